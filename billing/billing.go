@@ -0,0 +1,382 @@
+// Package billing settles the payments of subscriptions notified by the payment providers in the member service
+package billing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/gift"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/mirror-media/apigateway/promotion"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAmountMismatch is returned if the paid amount is not the amount of the subscription
+var ErrAmountMismatch = errors.New("paid amount doesn't match the subscription")
+
+// PaymentStatusAmountMismatch is the status of a payment recorded for a trade of which the amount doesn't match the subscription. The subscription isn't activated, and the trade has to be refunded or settled manually.
+const PaymentStatusAmountMismatch = "AMOUNT_MISMATCH"
+
+// claimTTL is how long a claim guards a trade being settled. The payment is recorded in the member service afterwards.
+const claimTTL = 10 * time.Minute
+
+// memberTypeRanks are the member types a paid subscription can upgrade. Other types, e.g., subscribe_group, are kept.
+var memberTypeRanks = map[model.MemberTypeType]int{
+	model.MemberTypeTypeNone:             0,
+	model.MemberTypeTypeMarketing:        0,
+	model.MemberTypeTypeSubscribeOneTime: 1,
+	model.MemberTypeTypeSubscribeMonthly: 2,
+	model.MemberTypeTypeSubscribeYearly:  3,
+}
+
+// memberTypes are the member types of paid subscriptions by the frequency
+var memberTypes = map[model.SubscriptionFrequencyType]model.MemberTypeType{
+	model.SubscriptionFrequencyTypeOneTime: model.MemberTypeTypeSubscribeOneTime,
+	model.SubscriptionFrequencyTypeMonthly: model.MemberTypeTypeSubscribeMonthly,
+	model.SubscriptionFrequencyTypeYearly:  model.MemberTypeTypeSubscribeYearly,
+}
+
+// Settlement is the result of settling a trade
+type Settlement struct {
+	SubscriptionID string
+	// FirebaseID is the member whose entitlement may be changed
+	FirebaseID string
+	// IsDuplicate is true if the trade has been settled or is being settled
+	IsDuplicate bool
+}
+
+// Settler records payments and activates subscriptions in the member service
+type Settler struct {
+	client     *graphql.Client
+	rdb        cache.Rediser
+	promotions *promotion.Redeemer
+}
+
+// NewSettler updates the member service with client, guards duplicate trades in rdb, bypassing its local cache, and gives back the promotions reserved by failed or invalidated subscriptions with promotions. promotions can be nil if they aren't reserved.
+func NewSettler(client *graphql.Client, rdb cache.Rediser, promotions *promotion.Redeemer) *Settler {
+	return &Settler{
+		client:     client,
+		rdb:        cache.Unwrap(rdb),
+		promotions: promotions,
+	}
+}
+
+// claimKey is the key of the claim of the trade, or of the order if the trade has no trade number
+func claimKey(trade payment.Trade) string {
+	if trade.TradeNumber == "" {
+		return fmt.Sprintf("%s.%s.%s.%s.%s", "apigateway", "billing", trade.Provider, "order", trade.OrderNumber)
+	}
+	return fmt.Sprintf("%s.%s.%s.%s", "apigateway", "billing", trade.Provider, trade.TradeNumber)
+}
+
+// paymentLists are the lists of the payments in the member service by the provider
+var paymentLists = map[string]string{
+	payment.NewebpayProviderName: "newebpayPayment",
+	payment.ECPayProviderName:    "ecpayPayment",
+}
+
+// subscription is the subscription of an order with the payments of a trade
+type subscription struct {
+	model.Subscription
+	Payments []struct {
+		ID          string `json:"id"`
+		TradeNumber string `json:"tradeNumber"`
+	} `json:"payments"`
+}
+
+// retrieveSubscription retrieves the subscription of the order with the payments of the trade. A trade without a trade number cannot be told from the others, so no payments are retrieved for it.
+func (s *Settler) retrieveSubscription(ctx context.Context, list, orderNumber, tradeNo string) (*subscription, error) {
+	variables, payments := "$orderNumber: String!", ""
+	if tradeNo != "" {
+		variables += ", $tradeNo: String!"
+		payments = `
+    payments: ` + list + `(where: {tradeNumber: $tradeNo}) {
+      id
+    }`
+	}
+	gql := `query (` + variables + `) {
+  subscription(where: {orderNumber: $orderNumber}) {
+    id
+    status
+    amount
+    frequency
+    paymentMethod
+    isGift
+    giftCode
+    promoteId
+    trialEndDatetime
+    periodFirstDatetime
+    member {
+      id
+      firebaseId
+      type
+    }` + payments + `
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("orderNumber", orderNumber)
+	if tradeNo != "" {
+		req.Var("tradeNo", tradeNo)
+	}
+
+	var resp struct {
+		Subscription *subscription `json:"subscription"`
+	}
+	if err := s.client.Run(ctx, req, &resp); err != nil {
+		return nil, errors.Wrapf(err, "retrieving subscription of order(%s) encountered error", orderNumber)
+	} else if resp.Subscription == nil {
+		return nil, fmt.Errorf("subscription of order(%s) is not found", orderNumber)
+	} else if resp.Subscription.Member == nil || resp.Subscription.Frequency == nil {
+		return nil, fmt.Errorf("subscription(%s) has no member or frequency", resp.Subscription.ID)
+	}
+	return resp.Subscription, nil
+}
+
+// isTrial tells if the trade only authorises the card for the trial of the subscription
+func (sub *subscription) isTrial() bool {
+	return sub.TrialEndDatetime != nil && *sub.TrialEndDatetime != ""
+}
+
+// expectedAmount is the amount of the subscription set by the price of the merchandise at purchase, or the amount to authorise the card of a trial
+func (sub *subscription) expectedAmount() int {
+	if sub.isTrial() {
+		return payment.TrialAuthorisationAmount
+	}
+	if sub.Amount == nil {
+		return 0
+	}
+	return int(math.Round(*sub.Amount))
+}
+
+// activation returns the fields to update the subscription paid at paidAt
+func (sub *subscription) activation(paidAt time.Time) map[string]interface{} {
+	paid := paidAt.Format(time.RFC3339)
+	data := map[string]interface{}{
+		"status":   model.SubscriptionStatusTypePaid,
+		"isActive": true,
+	}
+	if sub.PeriodFirstDatetime == nil || *sub.PeriodFirstDatetime == "" {
+		data["periodFirstDatetime"] = paid
+	}
+	isGift := sub.IsGift != nil && *sub.IsGift
+	if sub.isTrial() || isGift {
+		// the trial is charged when it ends, and the period of a gift starts when it's redeemed
+		return data
+	}
+	data["periodLastSuccessDatetime"] = paid
+	switch *sub.Frequency {
+	case model.SubscriptionFrequencyTypeMonthly:
+		data["periodNextPayDatetime"] = paidAt.AddDate(0, 1, 0).Format(time.RFC3339)
+	case model.SubscriptionFrequencyTypeYearly:
+		data["periodNextPayDatetime"] = paidAt.AddDate(1, 0, 0).Format(time.RFC3339)
+	}
+	return data
+}
+
+// upgradedMemberType returns the member type after the subscription is paid. isUpgraded is false if the type should be kept.
+func (sub *subscription) upgradedMemberType() (memberType model.MemberTypeType, isUpgraded bool) {
+	if sub.IsGift != nil && *sub.IsGift {
+		return "", false
+	}
+	memberType, ok := memberTypes[*sub.Frequency]
+	if !ok {
+		return "", false
+	}
+	current := model.MemberTypeTypeNone
+	if sub.Member.Type != nil {
+		current = *sub.Member.Type
+	}
+	rank, ok := memberTypeRanks[current]
+	if !ok || rank >= memberTypeRanks[memberType] {
+		return "", false
+	}
+	return memberType, true
+}
+
+func (s *Settler) updateSubscription(ctx context.Context, id string, data map[string]interface{}) error {
+	gql := `mutation ($id: ID!, $input: subscriptionPrivateUpdateInput) {
+  updatesubscription(id: $id, data: $input) {
+    id
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("id", id)
+	req.Var("input", data)
+	if err := s.client.Run(ctx, req, nil); err != nil {
+		return errors.Wrapf(err, "updating subscription(%s) encountered error", id)
+	}
+	return nil
+}
+
+func (s *Settler) updateMemberType(ctx context.Context, id string, memberType model.MemberTypeType) error {
+	gql := `mutation ($id: ID!, $input: memberUpdateInput) {
+  updatemember(id: $id, data: $input) {
+    id
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("id", id)
+	req.Var("input", map[string]interface{}{"type": memberType})
+	if err := s.client.Run(ctx, req, nil); err != nil {
+		return errors.Wrapf(err, "updating type of member(%s) encountered error", id)
+	}
+	return nil
+}
+
+func (s *Settler) createPayment(ctx context.Context, list string, sub *subscription, trade payment.Trade) (id string, err error) {
+	data := make(map[string]interface{}, len(trade.Record)+3)
+	for k, v := range trade.Record {
+		data[k] = v
+	}
+	data["subscription"] = map[string]interface{}{"connect": map[string]string{"id": sub.ID}}
+	data["frequency"] = sub.Frequency
+	if !trade.PaidAt.IsZero() {
+		data["paymentTime"] = trade.PaidAt.Format(time.RFC3339)
+	}
+
+	gql := fmt.Sprintf(`mutation ($input: %sCreateInput) {
+  create%s(data: $input) {
+    id
+  }
+}`, list, list)
+	req := graphql.NewRequest(gql)
+	req.Var("input", data)
+	var resp map[string]struct {
+		ID string `json:"id"`
+	}
+	if err = s.client.Run(ctx, req, &resp); err != nil {
+		return "", errors.Wrapf(err, "creating payment of trade(%s) encountered error", trade.TradeNumber)
+	}
+	return resp["create"+list].ID, nil
+}
+
+// recordMismatch records the payment of the trade with PaymentStatusAmountMismatch and the expected amount in the message
+func (s *Settler) recordMismatch(ctx context.Context, list string, sub *subscription, trade payment.Trade, expected int) error {
+	record := make(map[string]interface{}, len(trade.Record)+2)
+	for k, v := range trade.Record {
+		record[k] = v
+	}
+	record["status"] = PaymentStatusAmountMismatch
+	record["message"] = fmt.Sprintf("paid %d instead of %d", trade.Amount, expected)
+	trade.Record = record
+	_, err := s.createPayment(ctx, list, sub, trade)
+	return err
+}
+
+// Settle records the payment of the trade notified by the provider and activates the subscription of the order. The member type is upgraded by the frequency and a gift is given its gift code. The authorisation of a trial is cancelled with the provider after it's recorded. A failed payment fails a subscription being paid and gives back its promotion, and a payment of a mismatched amount is recorded with PaymentStatusAmountMismatch without activating the subscription. A trade is settled only once, and the payment is recorded last so a failed settlement can be retried.
+func (s *Settler) Settle(ctx context.Context, provider payment.Provider, trade payment.Trade) (settlement Settlement, err error) {
+	list, ok := paymentLists[trade.Provider]
+	if !ok {
+		return settlement, fmt.Errorf("payments of provider(%s) cannot be recorded", trade.Provider)
+	} else if provider.Name() != trade.Provider {
+		return settlement, fmt.Errorf("trade(%s) of provider(%s) cannot be settled with %s", trade.TradeNumber, trade.Provider, provider.Name())
+	}
+	key := claimKey(trade)
+	isClaimed, err := s.rdb.SetNX(ctx, key, trade.OrderNumber, claimTTL).Result()
+	if err != nil {
+		return settlement, errors.Wrapf(err, "cannot claim trade(%s)", trade.TradeNumber)
+	} else if !isClaimed {
+		settlement.IsDuplicate = true
+		return settlement, nil
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if releaseErr := s.rdb.Del(ctx, key).Err(); releaseErr != nil {
+			logrus.WithField("trade", trade.TradeNumber).Errorf("cannot release trade: %v", releaseErr)
+		}
+	}()
+
+	sub, err := s.retrieveSubscription(ctx, list, trade.OrderNumber, trade.TradeNumber)
+	if err != nil {
+		return settlement, err
+	}
+	settlement.SubscriptionID = sub.ID
+	if sub.Member.FirebaseID != nil {
+		settlement.FirebaseID = *sub.Member.FirebaseID
+	}
+	if sub.PaymentMethod != nil && sub.PaymentMethod.String() != trade.Provider {
+		return settlement, fmt.Errorf("subscription(%s) is paid by %s instead of %s", sub.ID, *sub.PaymentMethod, trade.Provider)
+	}
+	if len(sub.Payments) > 0 {
+		settlement.IsDuplicate = true
+		return settlement, nil
+	}
+
+	isFirstPayment := sub.Status == nil || *sub.Status == model.SubscriptionStatusTypePaying
+	if trade.IsSuccessful {
+		if expected := sub.expectedAmount(); trade.Amount != expected {
+			// the payment is recorded so the trade can be found and refunded, and the notifications again are duplicates
+			if err = s.recordMismatch(ctx, list, sub, trade, expected); err != nil {
+				return settlement, err
+			}
+			return settlement, errors.Wrapf(ErrAmountMismatch, "trade(%s) paid %d for subscription(%s) of %d", trade.TradeNumber, trade.Amount, sub.ID, expected)
+		}
+		activation := sub.activation(trade.PaidAt)
+		if sub.IsGift != nil && *sub.IsGift && (sub.GiftCode == nil || *sub.GiftCode == "") {
+			// the purchaser reads the code of the paid gift to pass it to the recipient
+			if activation["giftCode"], err = gift.NewCode(); err != nil {
+				return settlement, err
+			}
+		}
+		if err = s.updateSubscription(ctx, sub.ID, activation); err != nil {
+			return settlement, err
+		}
+		if memberType, isUpgraded := sub.upgradedMemberType(); isUpgraded {
+			if err = s.updateMemberType(ctx, sub.Member.ID, memberType); err != nil {
+				return settlement, err
+			}
+		}
+	} else if sub.Status != nil && *sub.Status == model.SubscriptionStatusTypePaying {
+		if err = s.updateSubscription(ctx, sub.ID, map[string]interface{}{"status": model.SubscriptionStatusTypeFail}); err != nil {
+			return settlement, err
+		}
+		// the subscription is failed only once, so its promotion is given back only once
+		s.releasePromotion(ctx, sub)
+	}
+
+	paymentID, err := s.createPayment(ctx, list, sub, trade)
+	if err != nil {
+		return settlement, err
+	}
+	if trade.IsSuccessful && isFirstPayment && sub.isTrial() {
+		s.cancelTrialAuthorisation(ctx, provider, list, paymentID, trade)
+	}
+	return settlement, nil
+}
+
+// cancelTrialAuthorisation gives back the amount authorised for the trial, which has issued the token to charge when the trial ends, and records it as a refund of the payment. It's only logged if it fails, because the payment has been recorded and the settlement won't be retried.
+func (s *Settler) cancelTrialAuthorisation(ctx context.Context, provider payment.Provider, list, paymentID string, trade payment.Trade) {
+	logger := logrus.WithField("trade", trade.TradeNumber)
+	refund, err := provider.Refund(ctx, trade.OrderNumber, trade.TradeNumber, trade.Amount, true)
+	if err != nil {
+		logger.Errorf("the authorisation of the trial has to be refunded manually: %v", err)
+		return
+	}
+	status := RefundStatusRefunded
+	if refund.IsCancelled {
+		status = RefundStatusCancelled
+	}
+	if err = s.updatePayment(ctx, list, paymentID, map[string]interface{}{
+		"refundAmount": refund.Amount,
+		"refundStatus": status,
+		"refundTime":   time.Now().Format(time.RFC3339),
+	}); err != nil {
+		logger.Errorf("the authorisation of the trial is %s, but the refund is not recorded: %v", status, err)
+	}
+}
+
+// releasePromotion gives back the redemption of the promotion reserved by the subscription, which fails or is invalidated
+func (s *Settler) releasePromotion(ctx context.Context, sub *subscription) {
+	if s.promotions == nil || sub.PromoteID == nil || sub.Member == nil || sub.Member.FirebaseID == nil {
+		return
+	}
+	s.promotions.Release(ctx, *sub.PromoteID, *sub.Member.FirebaseID)
+}
@@ -0,0 +1,279 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/pkg/errors"
+)
+
+// claimRediser implements SetNX, Del and the sets of cache.Rediser in memory. Expiration is ignored.
+type claimRediser struct {
+	cache.Rediser
+	values map[string]interface{}
+}
+
+func (c *claimRediser) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd {
+	if _, ok := c.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	c.values[key] = value
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *claimRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	var n int64
+	for _, key := range keys {
+		if _, ok := c.values[key]; ok {
+			delete(c.values, key)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *claimRediser) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	set, _ := c.values[key].(map[interface{}]bool)
+	if set == nil {
+		set = map[interface{}]bool{}
+		c.values[key] = set
+	}
+	var n int64
+	for _, member := range members {
+		if !set[member] {
+			set[member] = true
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *claimRediser) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *claimRediser) SCard(ctx context.Context, key string) *redis.IntCmd {
+	set, _ := c.values[key].(map[interface{}]bool)
+	return redis.NewIntResult(int64(len(set)), nil)
+}
+
+// memberService replies subscriptions by order numbers with the payments of paidTrades, and records the mutations. Trades of the created payments are paid.
+type memberService struct {
+	subscriptions map[string]string
+	paidTrades    map[string]bool
+	updates       map[string]map[string]interface{}
+	memberTypes   map[string]string
+	payments      map[string]map[string]interface{}
+	// listed is the JSON array of allSubscriptions, and trials is the one of the subscriptions of which the trials ended
+	listed string
+	trials string
+	// refundable are the payments by the IDs, and active are the JSON arrays of the other active subscriptions by the member IDs
+	refundable map[string]string
+	active     map[string]string
+}
+
+func (m *memberService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	input, _ := req.Variables["input"].(map[string]interface{})
+	switch {
+	case strings.Contains(req.Query, "updatesubscription"):
+		m.updates[req.Variables["id"].(string)] = input
+	case strings.Contains(req.Query, "updatemember"):
+		m.memberTypes[req.Variables["id"].(string)] = input["type"].(string)
+	case strings.Contains(req.Query, "createnewebpayPayment"), strings.Contains(req.Query, "createecpayPayment"):
+		// the ID of the created payment is its trade number
+		tradeNumber := input["tradeNumber"].(string)
+		m.payments[tradeNumber] = input
+		if m.paidTrades == nil {
+			m.paidTrades = map[string]bool{}
+		}
+		m.paidTrades[tradeNumber] = true
+		mutation := "createecpayPayment"
+		if strings.Contains(req.Query, "createnewebpayPayment") {
+			mutation = "createnewebpayPayment"
+		}
+		_, _ = w.Write([]byte(`{"data": {"` + mutation + `": {"id": "` + tradeNumber + `"}}}`))
+		return
+	case strings.Contains(req.Query, "updatenewebpayPayment"):
+		p, ok := m.payments[req.Variables["id"].(string)]
+		if !ok {
+			p = map[string]interface{}{}
+			m.payments[req.Variables["id"].(string)] = p
+		}
+		for k, v := range input {
+			p[k] = v
+		}
+	case req.Variables["paymentId"] != nil:
+		payment, ok := m.refundable[req.Variables["paymentId"].(string)]
+		if !ok {
+			payment = `null`
+		}
+		_, _ = w.Write([]byte(`{"data": {"payment": ` + payment + `}}`))
+		return
+	case req.Variables["memberId"] != nil:
+		active, ok := m.active[req.Variables["memberId"].(string)]
+		if !ok {
+			active = `[]`
+		}
+		_, _ = w.Write([]byte(`{"data": {"allSubscriptions": ` + active + `}}`))
+		return
+	case strings.Contains(req.Query, "trialEndDatetime_gte"):
+		_, _ = w.Write([]byte(`{"data": {"allSubscriptions": ` + m.trials + `}}`))
+		return
+	case strings.Contains(req.Query, "allSubscriptions"):
+		listed := m.listed
+		if req.Variables["skip"].(float64) > 0 {
+			listed = `[]`
+		}
+		_, _ = w.Write([]byte(`{"data": {"allSubscriptions": ` + listed + `}}`))
+		return
+	default:
+		subscription, ok := m.subscriptions[req.Variables["orderNumber"].(string)]
+		if !ok {
+			_, _ = w.Write([]byte(`{"data": {"subscription": null}}`))
+			return
+		}
+		payments := `[]`
+		if tradeNo, ok := req.Variables["tradeNo"].(string); ok && m.paidTrades[tradeNo] {
+			payments = `[{"id": "1"}]`
+		}
+		_, _ = w.Write([]byte(`{"data": {"subscription": ` + strings.Replace(subscription, `"payments"`, `"payments": `+payments, 1) + `}}`))
+		return
+	}
+	_, _ = w.Write([]byte(`{"data": {}}`))
+}
+
+func newebpayTrade(status, orderNumber, tradeNo string, amount int) payment.Trade {
+	trade, _ := payment.NewebpayNotification{
+		Status: status,
+		Result: payment.NewebpayTradeResult{
+			MerchantID:      "MS12345",
+			Amt:             amount,
+			TradeNo:         tradeNo,
+			MerchantOrderNo: orderNumber,
+			PaymentType:     "CREDIT",
+			PayTime:         "2021-12-01 08:00:00",
+		},
+	}.Trade()
+	return trade
+}
+
+func TestSettler_Settle(t *testing.T) {
+	ms := &memberService{
+		subscriptions: map[string]string{
+			"monthly":  `{"id": "1", "status": "paying", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "member": {"id": "m1", "firebaseId": "f1", "type": "none"}, "payments"}`,
+			"trial":    `{"id": "2", "status": "paying", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "trialEndDatetime": "2021-12-15T08:00:00+08:00", "member": {"id": "m2", "firebaseId": "f2", "type": "none"}, "payments"}`,
+			"gift":     `{"id": "3", "status": "paying", "amount": 1490, "frequency": "yearly", "paymentMethod": "newebpay", "isGift": true, "member": {"id": "m3", "firebaseId": "f3", "type": "none"}, "payments"}`,
+			"one_time": `{"id": "4", "status": "paying", "amount": 5, "frequency": "one_time", "member": {"id": "m4", "firebaseId": "f4", "type": "subscribe_yearly"}, "payments"}`,
+			"failed":   `{"id": "5", "status": "paying", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "member": {"id": "m5", "firebaseId": "f5", "type": "none"}, "payments"}`,
+			"no_trade": `{"id": "8", "status": "paying", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "member": {"id": "m8", "firebaseId": "f8", "type": "none"}, "payments"}`,
+			"ecpay":    `{"id": "7", "status": "paying", "amount": 5, "frequency": "one_time", "paymentMethod": "ecpay", "member": {"id": "m7", "firebaseId": "f7", "type": "none"}, "payments"}`,
+			"recorded": `{"id": "6", "status": "paid", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "member": {"id": "m6", "firebaseId": "f6", "type": "subscribe_monthly"}, "payments"}`,
+		},
+		paidTrades:  map[string]bool{"T6": true},
+		updates:     map[string]map[string]interface{}{},
+		memberTypes: map[string]string{},
+		payments:    map[string]map[string]interface{}{},
+	}
+	server := httptest.NewServer(ms)
+	defer server.Close()
+	s := NewSettler(graphql.NewClient(server.URL), &claimRediser{values: map[string]interface{}{}}, nil)
+
+	tests := []struct {
+		name             string
+		trade            payment.Trade
+		provider         string // the provider settling the trade, default to the one of the trade
+		wantSubscription string
+		wantStatus       string
+		wantNextPay      string
+		wantMemberType   string
+		wantRecorded     bool
+		wantDuplicate    bool
+		wantErr          bool
+		wantMismatch     bool
+		wantRefundStatus string
+		wantGiftCode     bool
+		// wantPaymentStatus is checked if it's set
+		wantPaymentStatus string
+	}{
+		{name: "monthly", trade: newebpayTrade(payment.NewebpayStatusSuccess, "monthly", "T1", 199), wantSubscription: "1", wantStatus: "paid", wantNextPay: "2022-01-01T08:00:00+08:00", wantMemberType: "subscribe_monthly", wantRecorded: true},
+		{name: "duplicate notification", trade: newebpayTrade(payment.NewebpayStatusSuccess, "monthly", "T1", 199), wantDuplicate: true},
+		{name: "trial authorisation", trade: newebpayTrade(payment.NewebpayStatusSuccess, "trial", "T2", 1), wantSubscription: "2", wantStatus: "paid", wantMemberType: "subscribe_monthly", wantRecorded: true, wantRefundStatus: RefundStatusCancelled},
+		{name: "gift", trade: newebpayTrade(payment.NewebpayStatusSuccess, "gift", "T3", 1490), wantSubscription: "3", wantStatus: "paid", wantRecorded: true, wantGiftCode: true},
+		{name: "one time of a yearly member", trade: newebpayTrade(payment.NewebpayStatusSuccess, "one_time", "T4", 5), wantSubscription: "4", wantStatus: "paid", wantRecorded: true},
+		{name: "failed payment", trade: newebpayTrade("CRE00001", "failed", "T5", 199), wantSubscription: "5", wantStatus: "fail", wantRecorded: true},
+		{name: "recorded payment", trade: newebpayTrade(payment.NewebpayStatusSuccess, "recorded", "T6", 199), wantSubscription: "6", wantDuplicate: true},
+		{name: "ecpay", trade: payment.Trade{Provider: payment.ECPayProviderName, IsSuccessful: true, Status: "1", OrderNumber: "ecpay", TradeNumber: "E7", Amount: 5, PaidAt: time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC), Record: map[string]interface{}{"tradeNumber": "E7"}}, wantSubscription: "7", wantStatus: "paid", wantMemberType: "subscribe_one_time", wantRecorded: true},
+		{name: "paid by another provider", trade: newebpayTrade(payment.NewebpayStatusSuccess, "ecpay", "T8", 5), wantErr: true},
+		{name: "notified by another provider", trade: payment.Trade{Provider: payment.NewebpayProviderName, IsSuccessful: true, OrderNumber: "monthly", TradeNumber: "T9", Amount: 199}, provider: payment.ECPayProviderName, wantErr: true},
+		{name: "amount mismatch", trade: newebpayTrade(payment.NewebpayStatusSuccess, "failed", "T7", 1), wantErr: true, wantMismatch: true, wantRecorded: true, wantPaymentStatus: PaymentStatusAmountMismatch},
+		{name: "amount mismatch notified again", trade: newebpayTrade(payment.NewebpayStatusSuccess, "failed", "T7", 1), wantSubscription: "5", wantDuplicate: true},
+		{name: "failed without trade number", trade: newebpayTrade("CRE00001", "no_trade", "", 199), wantSubscription: "8", wantStatus: "fail", wantRecorded: true},
+		{name: "without trade number notified again", trade: newebpayTrade("CRE00001", "no_trade", "", 199), wantDuplicate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms.updates = map[string]map[string]interface{}{}
+			ms.memberTypes = map[string]string{}
+			ms.payments = map[string]map[string]interface{}{}
+
+			provider := refundProvider{name: tt.trade.Provider}
+			if tt.provider != "" {
+				provider.name = tt.provider
+			}
+			got, err := s.Settle(context.Background(), provider, tt.trade)
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrAmountMismatch) != tt.wantMismatch {
+				t.Fatalf("Settle() error = %v, wantErr %v, wantMismatch %v", err, tt.wantErr, tt.wantMismatch)
+			}
+			if got.IsDuplicate != tt.wantDuplicate || (tt.wantSubscription != "" && got.SubscriptionID != tt.wantSubscription) {
+				t.Errorf("Settle() = %+v, want subscription %s and duplicate %v", got, tt.wantSubscription, tt.wantDuplicate)
+			}
+
+			var gotStatus, gotNextPay, gotGiftCode string
+			if update, ok := ms.updates[tt.wantSubscription]; ok {
+				gotStatus, _ = update["status"].(string)
+				gotNextPay, _ = update["periodNextPayDatetime"].(string)
+				gotGiftCode, _ = update["giftCode"].(string)
+			}
+			if gotStatus != tt.wantStatus {
+				t.Errorf("status of subscription = %q, want %q", gotStatus, tt.wantStatus)
+			}
+			if gotNextPay != tt.wantNextPay {
+				t.Errorf("periodNextPayDatetime of subscription = %q, want %q", gotNextPay, tt.wantNextPay)
+			}
+			if (gotGiftCode != "") != tt.wantGiftCode {
+				t.Errorf("giftCode of subscription = %q, want a code %v", gotGiftCode, tt.wantGiftCode)
+			}
+			var gotMemberType string
+			for _, memberType := range ms.memberTypes {
+				gotMemberType = memberType
+			}
+			if gotMemberType != tt.wantMemberType {
+				t.Errorf("type of member = %q, want %q", gotMemberType, tt.wantMemberType)
+			}
+			p, gotRecorded := ms.payments[tt.trade.TradeNumber]
+			if gotRecorded != tt.wantRecorded {
+				t.Errorf("payment recorded = %v, want %v", gotRecorded, tt.wantRecorded)
+			}
+			if gotPaymentStatus, _ := p["status"].(string); tt.wantPaymentStatus != "" && gotPaymentStatus != tt.wantPaymentStatus {
+				t.Errorf("status of payment = %q, want %q", gotPaymentStatus, tt.wantPaymentStatus)
+			}
+			if gotRefundStatus, _ := p["refundStatus"].(string); gotRefundStatus != tt.wantRefundStatus {
+				t.Errorf("refundStatus of payment = %q, want %q", gotRefundStatus, tt.wantRefundStatus)
+			}
+		})
+	}
+}
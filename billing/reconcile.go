@@ -0,0 +1,293 @@
+package billing
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/mirror-media/apigateway/trial"
+	"github.com/pkg/errors"
+)
+
+// Actions to fix a discrepancy
+const (
+	// ActionReport leaves the discrepancy to be checked manually
+	ActionReport = "report"
+	// ActionSettle settles the queried trade like a notification
+	ActionSettle = "settle"
+	// ActionInvalidate invalidates a subscription which can never be paid
+	ActionInvalidate = "invalidate"
+)
+
+// preparingOrderPrefix is the prefix of the order number of a subscription before the order number is assigned
+const preparingOrderPrefix = "preparing-order-"
+
+// reconcilePageSize is the number of subscriptions listed per request
+const reconcilePageSize = 100
+
+// Discrepancy is a subscription of which the state doesn't match the trade of the provider
+type Discrepancy struct {
+	SubscriptionID string
+	FirebaseID     string
+	OrderNumber    string
+	Status         string // The status of the subscription
+	TradeNumber    string
+	TradeStatus    string // The status of the trade replied by the provider
+	Amount         int    // The amount of the trade
+	Issue          string
+	Action         string
+	IsFixed        bool
+	Error          string
+}
+
+// Reconciler compares the subscriptions paid by a provider with the trades queried from it
+type Reconciler struct {
+	client   *graphql.Client
+	settler  *Settler
+	provider payment.Provider
+}
+
+// NewReconciler lists the subscriptions with client and fixes them with settler
+func NewReconciler(client *graphql.Client, settler *Settler, provider payment.Provider) *Reconciler {
+	return &Reconciler{
+		client:   client,
+		settler:  settler,
+		provider: provider,
+	}
+}
+
+func (r *Reconciler) listSubscriptions(ctx context.Context, from, to time.Time, skip int) ([]*subscription, error) {
+	list, ok := paymentLists[r.provider.Name()]
+	if !ok {
+		return nil, fmt.Errorf("payments of provider(%s) cannot be listed", r.provider.Name())
+	}
+	gql := `query ($paymentMethod: subscriptionPaymentMethodType, $from: String, $to: String, $first: Int, $skip: Int!) {
+  allSubscriptions(where: {paymentMethod: $paymentMethod, createdAt_gte: $from, createdAt_lt: $to}, orderBy: [{createdAt: asc}], first: $first, skip: $skip) {
+    id
+    orderNumber
+    status
+    amount
+    frequency
+    paymentMethod
+    isGift
+    promoteId
+    trialEndDatetime
+    member {
+      firebaseId
+    }
+    payments: ` + list + ` {
+      id
+      tradeNumber
+    }
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("paymentMethod", r.provider.Name())
+	req.Var("from", from.Format(time.RFC3339))
+	req.Var("to", to.Format(time.RFC3339))
+	req.Var("first", reconcilePageSize)
+	req.Var("skip", skip)
+
+	var resp struct {
+		Subscriptions []*subscription `json:"allSubscriptions"`
+	}
+	if err := r.client.Run(ctx, req, &resp); err != nil {
+		return nil, errors.Wrapf(err, "listing subscriptions created from %s encountered error", from.Format(time.RFC3339))
+	}
+	return resp.Subscriptions, nil
+}
+
+// isRecorded tells if the payment of the trade is recorded in the subscription
+func (sub *subscription) isRecorded(tradeNumber string) bool {
+	for _, p := range sub.Payments {
+		if tradeNumber != "" && p.TradeNumber == tradeNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// check compares the subscription with its trade. It returns the trade to settle, and nil if the subscription is consistent.
+func (r *Reconciler) check(ctx context.Context, sub *subscription) (*Discrepancy, payment.Trade) {
+	var trade payment.Trade
+	d := &Discrepancy{
+		SubscriptionID: sub.ID,
+		Action:         ActionReport,
+	}
+	if sub.OrderNumber != nil {
+		d.OrderNumber = *sub.OrderNumber
+	}
+	if sub.Status != nil {
+		d.Status = sub.Status.String()
+	}
+	if sub.Member != nil && sub.Member.FirebaseID != nil {
+		d.FirebaseID = *sub.Member.FirebaseID
+	}
+	isPaying := d.Status == model.SubscriptionStatusTypePaying.String()
+	isPaid := d.Status == model.SubscriptionStatusTypePaid.String()
+
+	if d.OrderNumber == "" || strings.HasPrefix(d.OrderNumber, preparingOrderPrefix) {
+		if !isPaying {
+			return nil, trade
+		}
+		d.Issue = "order number is never assigned, so the subscription cannot be paid"
+		d.Action = ActionInvalidate
+		return d, trade
+	}
+
+	trade, err := r.provider.QueryTrade(ctx, d.OrderNumber, sub.expectedAmount())
+	if err != nil {
+		if !isPaying && !isPaid {
+			return nil, trade
+		}
+		d.Issue = "trade cannot be queried"
+		d.Error = err.Error()
+		return d, trade
+	}
+	d.TradeNumber = trade.TradeNumber
+	d.TradeStatus = trade.Status
+	d.Amount = trade.Amount
+	isRecorded := sub.isRecorded(trade.TradeNumber)
+
+	switch {
+	case trade.IsSuccessful && !isRecorded:
+		d.Issue = "trade is paid but not recorded"
+		d.Action = ActionSettle
+	case trade.IsSuccessful && isPaying:
+		d.Issue = "trade is paid and recorded, but the subscription is still paying"
+	case trade.IsPending && isPaid:
+		d.Issue = "subscription is paid, but the trade is not paid yet"
+	case !trade.IsSuccessful && !trade.IsPending && isPaying && !isRecorded && trade.TradeNumber != "":
+		d.Issue = "trade failed, but the subscription is still paying"
+		d.Action = ActionSettle
+	case !trade.IsSuccessful && !trade.IsPending && isPaid:
+		d.Issue = "subscription is paid, but the trade is not"
+	default:
+		return nil, trade
+	}
+	return d, trade
+}
+
+// fix settles the trade or invalidates the subscription by the action of the discrepancy. An invalidated subscription gives back its promotion.
+func (r *Reconciler) fix(ctx context.Context, sub *subscription, d *Discrepancy, trade payment.Trade) error {
+	switch d.Action {
+	case ActionSettle:
+		settlement, err := r.settler.Settle(ctx, r.provider, trade)
+		if err != nil {
+			return err
+		}
+		d.IsFixed = !settlement.IsDuplicate
+	case ActionInvalidate:
+		if err := r.settler.updateSubscription(ctx, d.SubscriptionID, map[string]interface{}{"status": model.SubscriptionStatusTypeInvalid}); err != nil {
+			return err
+		}
+		r.settler.releasePromotion(ctx, sub)
+		d.IsFixed = true
+	}
+	return nil
+}
+
+// Reconcile walks the subscriptions created in [from, to) and reports the discrepancies with the trades. They are fixed unless isDryRun is true. A failed fix is reported in the discrepancy instead of stopping the walk.
+func (r *Reconciler) Reconcile(ctx context.Context, from, to time.Time, isDryRun bool) ([]*Discrepancy, error) {
+	var discrepancies []*Discrepancy
+	for skip := 0; ; skip += reconcilePageSize {
+		subscriptions, err := r.listSubscriptions(ctx, from, to, skip)
+		if err != nil {
+			return discrepancies, err
+		}
+		for _, sub := range subscriptions {
+			d, trade := r.check(ctx, sub)
+			if d == nil {
+				continue
+			}
+			if !isDryRun {
+				if err := r.fix(ctx, sub, d, trade); err != nil {
+					d.Error = err.Error()
+				}
+			}
+			discrepancies = append(discrepancies, d)
+		}
+		if len(subscriptions) < reconcilePageSize {
+			return discrepancies, nil
+		}
+	}
+}
+
+func (r *Reconciler) listTrials(ctx context.Context, from, to time.Time, skip int) ([]*model.Subscription, error) {
+	gql := `query ($paymentMethod: subscriptionPaymentMethodType, $from: String, $to: String, $first: Int, $skip: Int!) {
+  allSubscriptions(where: {paymentMethod: $paymentMethod, trialEndDatetime_gte: $from, trialEndDatetime_lt: $to}, orderBy: [{trialEndDatetime: asc}, {id: asc}], first: $first, skip: $skip) {
+    id
+    status
+    trialEndDatetime
+    periodLastSuccessDatetime
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("paymentMethod", r.provider.Name())
+	req.Var("from", from.Format(time.RFC3339))
+	req.Var("to", to.Format(time.RFC3339))
+	req.Var("first", reconcilePageSize)
+	req.Var("skip", skip)
+
+	var resp struct {
+		Subscriptions []*model.Subscription `json:"allSubscriptions"`
+	}
+	if err := r.client.Run(ctx, req, &resp); err != nil {
+		return nil, errors.Wrapf(err, "listing trials ended from %s encountered error", from.Format(time.RFC3339))
+	}
+	return resp.Subscriptions, nil
+}
+
+// RecordTrials walks the subscriptions of which the trials ended in [from, to) and records them as converted or expired to trials. to should leave time for the first charge to be recorded after the trial ends. Recording a trial again doesn't count it twice.
+func (r *Reconciler) RecordTrials(ctx context.Context, trials *trial.Tracker, from, to time.Time) (recorded int, err error) {
+	for skip := 0; ; skip += reconcilePageSize {
+		subscriptions, err := r.listTrials(ctx, from, to, skip)
+		if err != nil {
+			return recorded, err
+		}
+		for _, sub := range subscriptions {
+			state, isTrial, err := trial.StateOf(sub, to)
+			if err != nil {
+				return recorded, err
+			} else if !isTrial || (state != trial.StateConverted && state != trial.StateExpired) {
+				continue
+			}
+			if err = trials.Record(ctx, sub, state); err != nil {
+				return recorded, err
+			}
+			recorded++
+		}
+		if len(subscriptions) < reconcilePageSize {
+			return recorded, nil
+		}
+	}
+}
+
+// WriteReport writes the discrepancies as CSV with a header
+func WriteReport(w io.Writer, discrepancies []*Discrepancy) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"subscription_id", "order_number", "status", "trade_number", "trade_status", "amount", "issue", "action", "fixed", "error"})
+	for _, d := range discrepancies {
+		_ = writer.Write([]string{
+			d.SubscriptionID,
+			d.OrderNumber,
+			d.Status,
+			d.TradeNumber,
+			d.TradeStatus,
+			strconv.Itoa(d.Amount),
+			d.Issue,
+			d.Action,
+			strconv.FormatBool(d.IsFixed),
+			d.Error,
+		})
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "writing report encountered error")
+}
@@ -0,0 +1,179 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/mirror-media/apigateway/trial"
+	"github.com/pkg/errors"
+)
+
+// tradeProvider replies the trades by the order numbers
+type tradeProvider struct {
+	payment.Provider
+	trades map[string]payment.Trade
+}
+
+func (p tradeProvider) Name() string {
+	return payment.NewebpayProviderName
+}
+
+func (p tradeProvider) QueryTrade(ctx context.Context, orderNumber string, amount int) (payment.Trade, error) {
+	trade, ok := p.trades[orderNumber]
+	if !ok {
+		return trade, errors.New("trade is not found")
+	}
+	return trade, nil
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	paidAt := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	provider := tradeProvider{trades: map[string]payment.Trade{
+		"M1": {Provider: payment.NewebpayProviderName, IsSuccessful: true, Status: payment.NewebpayStatusSuccess, OrderNumber: "M1", TradeNumber: "T1", Amount: 199, PaidAt: paidAt, Record: map[string]interface{}{"tradeNumber": "T1"}},
+		"M2": {Provider: payment.NewebpayProviderName, IsSuccessful: true, Status: payment.NewebpayStatusSuccess, OrderNumber: "M2", TradeNumber: "T2", Amount: 199, PaidAt: paidAt},
+		"M3": {Provider: payment.NewebpayProviderName, IsPending: true, Status: payment.NewebpayTradeStatusUnpaid, OrderNumber: "M3", Amount: 199},
+		"M4": {Provider: payment.NewebpayProviderName, Status: payment.NewebpayTradeStatusRefunded, OrderNumber: "M4", TradeNumber: "T4", Amount: 199},
+		"M6": {Provider: payment.NewebpayProviderName, Status: payment.NewebpayTradeStatusFailed, OrderNumber: "M6", TradeNumber: "T6", Amount: 199, Record: map[string]interface{}{"tradeNumber": "T6"}},
+	}}
+	ms := &memberService{
+		subscriptions: map[string]string{
+			"M1": `{"id": "1", "status": "paying", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "member": {"id": "m1", "firebaseId": "f1", "type": "none"}, "payments"}`,
+			"M6": `{"id": "6", "status": "paying", "amount": 199, "frequency": "monthly", "paymentMethod": "newebpay", "member": {"id": "m6", "firebaseId": "f6", "type": "none"}, "payments"}`,
+		},
+		listed: `[
+  {"id": "0", "orderNumber": "preparing-order-c6k", "status": "paying", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f0"}, "payments": []},
+  {"id": "1", "orderNumber": "M1", "status": "paying", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f1"}, "payments": []},
+  {"id": "2", "orderNumber": "M2", "status": "paid", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f2"}, "payments": [{"id": "2", "tradeNumber": "T2"}]},
+  {"id": "3", "orderNumber": "M3", "status": "paying", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f3"}, "payments": []},
+  {"id": "4", "orderNumber": "M4", "status": "paid", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f4"}, "payments": [{"id": "4", "tradeNumber": "T4"}]},
+  {"id": "5", "orderNumber": "M5", "status": "paying", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f5"}, "payments": []},
+  {"id": "6", "orderNumber": "M6", "status": "paying", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f6"}, "payments": []},
+  {"id": "7", "orderNumber": "M7", "status": "fail", "amount": 199, "frequency": "monthly", "member": {"firebaseId": "f7"}, "payments": []}
+]`,
+	}
+	server := httptest.NewServer(ms)
+	defer server.Close()
+	client := graphql.NewClient(server.URL)
+	r := NewReconciler(client, NewSettler(client, &claimRediser{values: map[string]interface{}{}}, nil), provider)
+
+	wantActions := map[string]string{
+		"0": ActionInvalidate,
+		"1": ActionSettle,
+		"4": ActionReport,
+		"5": ActionReport,
+		"6": ActionSettle,
+	}
+	wantStatuses := map[string]string{
+		"0": "invalid",
+		"1": "paid",
+		"6": "fail",
+	}
+	tests := []struct {
+		name     string
+		isDryRun bool
+	}{
+		{name: "dry run", isDryRun: true},
+		{name: "fix", isDryRun: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms.updates = map[string]map[string]interface{}{}
+			ms.memberTypes = map[string]string{}
+			ms.payments = map[string]map[string]interface{}{}
+
+			got, err := r.Reconcile(context.Background(), paidAt.AddDate(0, 0, -3), paidAt, tt.isDryRun)
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if len(got) != len(wantActions) {
+				t.Fatalf("Reconcile() = %d discrepancies, want %d", len(got), len(wantActions))
+			}
+			for _, d := range got {
+				if d.Action != wantActions[d.SubscriptionID] {
+					t.Errorf("action of subscription(%s) = %s, want %s", d.SubscriptionID, d.Action, wantActions[d.SubscriptionID])
+				}
+				wantFixed := !tt.isDryRun && d.Action != ActionReport
+				if d.IsFixed != wantFixed || (d.Action != ActionReport && d.Error != "") {
+					t.Errorf("subscription(%s) is fixed %v with error %q, want fixed %v", d.SubscriptionID, d.IsFixed, d.Error, wantFixed)
+				}
+			}
+			for id, wantStatus := range wantStatuses {
+				var gotStatus string
+				if update, ok := ms.updates[id]; ok {
+					gotStatus = update["status"].(string)
+				}
+				if tt.isDryRun {
+					wantStatus = ""
+				}
+				if gotStatus != wantStatus {
+					t.Errorf("status of subscription(%s) = %q, want %q", id, gotStatus, wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteReport(&b, []*Discrepancy{
+		{SubscriptionID: "1", OrderNumber: "M1", Status: "paying", TradeNumber: "T1", TradeStatus: "SUCCESS", Amount: 199, Issue: "trade is paid but not recorded", Action: ActionSettle, IsFixed: true},
+		{SubscriptionID: "5", OrderNumber: "M5", Status: "paying", Issue: "trade cannot be queried", Action: ActionReport, Error: "trade, \"M5\", is not found"},
+	})
+	if err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"subscription_id,order_number,status,trade_number,trade_status,amount,issue,action,fixed,error",
+		"1,M1,paying,T1,SUCCESS,199,trade is paid but not recorded,settle,true,",
+		`5,M5,paying,,,0,trade cannot be queried,report,false,"trade, ""M5"", is not found"`,
+		"",
+	}, "\n")
+	if got := b.String(); got != want {
+		t.Errorf("WriteReport() = %v, want %v", got, want)
+	}
+}
+
+func TestReconciler_RecordTrials(t *testing.T) {
+	ms := &memberService{
+		trials: `[
+  {"id": "1", "status": "paid", "trialEndDatetime": "2021-11-30T08:00:00+08:00", "periodLastSuccessDatetime": "2021-11-30T08:05:00+08:00"},
+  {"id": "2", "status": "paid", "trialEndDatetime": "2021-11-30T09:00:00+08:00"},
+  {"id": "3", "status": "fail", "trialEndDatetime": "2021-11-30T20:00:00+08:00"},
+  {"id": "4", "status": "paying", "trialEndDatetime": "2021-11-30T20:00:00+08:00"}
+]`,
+	}
+	server := httptest.NewServer(ms)
+	defer server.Close()
+	client := graphql.NewClient(server.URL)
+	rdb := &claimRediser{values: map[string]interface{}{}}
+	r := NewReconciler(client, NewSettler(client, rdb, nil), tradeProvider{})
+	trials, err := trial.NewTracker(rdb)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	to := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	// recording the trials again doesn't count them twice
+	for i := 0; i < 2; i++ {
+		got, err := r.RecordTrials(context.Background(), trials, to.AddDate(0, 0, -3), to)
+		if err != nil {
+			t.Fatalf("RecordTrials() error = %v", err)
+		}
+		if got != 3 {
+			t.Errorf("RecordTrials() = %d, want 3", got)
+		}
+	}
+	stats, err := trials.Stats(context.Background(), to.AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats[string(trial.StateConverted)] != 1 || stats[string(trial.StateExpired)] != 2 {
+		t.Errorf("Stats() = %v, want 1 converted and 2 expired", stats)
+	}
+}
@@ -0,0 +1,251 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidRefundAmount is returned if the amount to refund is not positive or exceeds the amount not refunded yet
+var ErrInvalidRefundAmount = errors.New("refund amount is invalid")
+
+// Refund statuses recorded in the payment
+const (
+	RefundStatusCancelled = "cancelled"
+	RefundStatusRefunded  = "refunded"
+	// RefundStatusPending is recorded before the provider is requested, and it's kept if the outcome of the refund is unknown so the payment can't be refunded again before it's checked with the provider
+	RefundStatusPending = "pending"
+)
+
+// Refund is the result of refunding a payment
+type Refund struct {
+	payment.Refund
+	PaymentID      string
+	SubscriptionID string
+	// FirebaseID is the member whose entitlement may be changed
+	FirebaseID string
+	// RefundedAmount is the total amount refunded of the payment, including this refund
+	RefundedAmount int
+}
+
+// refundablePayment is a payment recorded by Settle with the amount refunded so far
+type refundablePayment struct {
+	ID           string        `json:"id"`
+	Amount       *int          `json:"amount"`
+	OrderNumber  *string       `json:"orderNumber"`
+	TradeNumber  *string       `json:"tradeNumber"`
+	RefundAmount *int          `json:"refundAmount"`
+	RefundStatus *string       `json:"refundStatus"`
+	Subscription *subscription `json:"subscription"`
+}
+
+func refundClaimKey(provider, paymentID string) string {
+	return fmt.Sprintf("%s.%s.%s.%s.%s", "apigateway", "billing", "refund", provider, paymentID)
+}
+
+func (s *Settler) retrievePayment(ctx context.Context, list, id string) (*refundablePayment, error) {
+	gql := `query ($paymentId: ID!) {
+  payment: ` + list + `(where: {id: $paymentId}) {
+    id
+    amount
+    orderNumber
+    tradeNumber
+    refundAmount
+    refundStatus
+    subscription {
+      id
+      frequency
+      isGift
+      member {
+        id
+        firebaseId
+        type
+      }
+    }
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("paymentId", id)
+
+	var resp struct {
+		Payment *refundablePayment `json:"payment"`
+	}
+	if err := s.client.Run(ctx, req, &resp); err != nil {
+		return nil, errors.Wrapf(err, "retrieving payment(%s) encountered error", id)
+	}
+	p := resp.Payment
+	if p == nil {
+		return nil, fmt.Errorf("payment(%s) is not found", id)
+	} else if p.Amount == nil || p.OrderNumber == nil || p.TradeNumber == nil || *p.TradeNumber == "" {
+		return nil, fmt.Errorf("payment(%s) has no amount, order number or trade number", id)
+	} else if p.Subscription == nil || p.Subscription.Member == nil || p.Subscription.Frequency == nil {
+		return nil, fmt.Errorf("payment(%s) has no subscription with a member and a frequency", id)
+	}
+	return p, nil
+}
+
+func (s *Settler) updatePayment(ctx context.Context, list, id string, data map[string]interface{}) error {
+	gql := fmt.Sprintf(`mutation ($id: ID!, $input: %sUpdateInput) {
+  update%s(id: $id, data: $input) {
+    id
+  }
+}`, list, list)
+	req := graphql.NewRequest(gql)
+	req.Var("id", id)
+	req.Var("input", data)
+	if err := s.client.Run(ctx, req, nil); err != nil {
+		return errors.Wrapf(err, "updating payment(%s) encountered error", id)
+	}
+	return nil
+}
+
+// downgradedMemberType returns the member type after the subscription is deactivated, which is the highest type of the other active subscriptions. isDowngraded is false if the type isn't granted by the subscription and should be kept.
+func (s *Settler) downgradedMemberType(ctx context.Context, sub *subscription) (memberType model.MemberTypeType, isDowngraded bool, err error) {
+	if sub.IsGift != nil && *sub.IsGift {
+		return "", false, nil
+	}
+	current := model.MemberTypeTypeNone
+	if sub.Member.Type != nil {
+		current = *sub.Member.Type
+	}
+	if granted, ok := memberTypes[*sub.Frequency]; !ok || granted != current {
+		return "", false, nil
+	}
+
+	gql := `query ($memberId: ID!, $id: ID!) {
+  allSubscriptions(where: {member: {id: $memberId}, isActive: true, id_not: $id}) {
+    frequency
+    isGift
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("memberId", sub.Member.ID)
+	req.Var("id", sub.ID)
+	var resp struct {
+		Subscriptions []model.Subscription `json:"allSubscriptions"`
+	}
+	if err = s.client.Run(ctx, req, &resp); err != nil {
+		return "", false, errors.Wrapf(err, "listing active subscriptions of member(%s) encountered error", sub.Member.ID)
+	}
+
+	memberType = model.MemberTypeTypeNone
+	for _, other := range resp.Subscriptions {
+		if other.Frequency == nil || (other.IsGift != nil && *other.IsGift) {
+			continue
+		}
+		if t, ok := memberTypes[*other.Frequency]; ok && memberTypeRanks[t] > memberTypeRanks[memberType] {
+			memberType = t
+		}
+	}
+	return memberType, memberTypeRanks[memberType] < memberTypeRanks[current], nil
+}
+
+// Refund refunds the amount of the payment with the provider, or the amount not refunded yet if amount is nil. The refund is recorded in the payment as pending before the provider is requested, so the payment can't be refunded twice even if the outcome isn't recorded. The pending refund is taken back only if the provider rejects it. After the refund, the subscription is deactivated with reason as the refund note, and the member type granted by the subscription is downgraded to the one of the other active subscriptions.
+func (s *Settler) Refund(ctx context.Context, provider payment.Provider, paymentID string, amount *int, reason string) (refund Refund, err error) {
+	list, ok := paymentLists[provider.Name()]
+	if !ok {
+		return refund, fmt.Errorf("payments of provider(%s) cannot be refunded", provider.Name())
+	}
+
+	// the claim guards concurrent refunds of the payment until the pending refund is recorded, so the refunded amount is read inside it
+	key := refundClaimKey(provider.Name(), paymentID)
+	isClaimed, err := s.rdb.SetNX(ctx, key, paymentID, claimTTL).Result()
+	if err != nil {
+		return refund, errors.Wrapf(err, "cannot claim payment(%s)", paymentID)
+	} else if !isClaimed {
+		return refund, fmt.Errorf("payment(%s) is being refunded", paymentID)
+	}
+	defer func() {
+		if releaseErr := s.rdb.Del(ctx, key).Err(); releaseErr != nil {
+			logrus.WithField("payment", paymentID).Errorf("cannot release payment: %v", releaseErr)
+		}
+	}()
+
+	p, err := s.retrievePayment(ctx, list, paymentID)
+	if err != nil {
+		return refund, err
+	}
+	if p.RefundStatus != nil && *p.RefundStatus == RefundStatusPending {
+		return refund, fmt.Errorf("payment(%s) has a pending refund to be checked with the provider", p.ID)
+	}
+	sub := p.Subscription
+	refund.PaymentID = p.ID
+	refund.SubscriptionID = sub.ID
+	if sub.Member.FirebaseID != nil {
+		refund.FirebaseID = *sub.Member.FirebaseID
+	}
+
+	var refunded int
+	if p.RefundAmount != nil {
+		refunded = *p.RefundAmount
+	}
+	remaining := *p.Amount - refunded
+	toRefund := remaining
+	if amount != nil {
+		toRefund = *amount
+	}
+	if toRefund <= 0 || toRefund > remaining {
+		return refund, errors.Wrapf(ErrInvalidRefundAmount, "payment(%s) of %d cannot be refunded %d after %d is refunded", p.ID, *p.Amount, toRefund, refunded)
+	}
+	refund.RefundedAmount = refunded + toRefund
+
+	if err = s.updatePayment(ctx, list, p.ID, map[string]interface{}{
+		"refundAmount": refund.RefundedAmount,
+		"refundStatus": RefundStatusPending,
+		"refundTime":   time.Now().Format(time.RFC3339),
+	}); err != nil {
+		return refund, err
+	}
+
+	logger := logrus.WithField("payment", p.ID)
+	refund.Refund, err = provider.Refund(ctx, *p.OrderNumber, *p.TradeNumber, toRefund, refunded == 0 && toRefund == *p.Amount)
+	if err != nil && payment.IsRejected(err) {
+		if restoreErr := s.updatePayment(ctx, list, p.ID, map[string]interface{}{
+			"refundAmount": p.RefundAmount,
+			"refundStatus": p.RefundStatus,
+		}); restoreErr != nil {
+			logger.Errorf("the refund is rejected, but it's still pending in the payment: %v", restoreErr)
+		}
+		return refund, err
+	} else if err != nil {
+		logger.Errorf("the refund of %d is pending until it's checked with the provider: %v", toRefund, err)
+		return refund, err
+	}
+
+	status := RefundStatusRefunded
+	if refund.IsCancelled {
+		status = RefundStatusCancelled
+	}
+	if err = s.updatePayment(ctx, list, p.ID, map[string]interface{}{
+		"refundStatus": status,
+	}); err != nil {
+		logger.Errorf("the payment is %s %d, but the refund is still pending: %v", status, toRefund, err)
+		return refund, err
+	}
+
+	deactivation := map[string]interface{}{
+		"isActive":   false,
+		"isCanceled": true,
+	}
+	if reason != "" {
+		deactivation["refundNote"] = reason
+	}
+	if err = s.updateSubscription(ctx, sub.ID, deactivation); err != nil {
+		return refund, err
+	}
+	memberType, isDowngraded, err := s.downgradedMemberType(ctx, sub)
+	if err != nil {
+		return refund, err
+	} else if isDowngraded {
+		if err = s.updateMemberType(ctx, sub.Member.ID, memberType); err != nil {
+			return refund, err
+		}
+	}
+	return refund, nil
+}
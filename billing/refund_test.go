@@ -0,0 +1,137 @@
+package billing
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/pkg/errors"
+)
+
+// refundProvider cancels the authorisation of a full refund unless the trade is captured. The refund of the trade "rejected" is rejected, and the one of the trade "failed" fails with an unknown outcome. It's NewebPay unless name is set.
+type refundProvider struct {
+	payment.Provider
+	name string
+}
+
+func (p refundProvider) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+	return payment.NewebpayProviderName
+}
+
+func (p refundProvider) Refund(ctx context.Context, orderNumber, tradeNumber string, amount int, isFull bool) (payment.Refund, error) {
+	switch tradeNumber {
+	case "failed":
+		return payment.Refund{}, errors.New("refund failed")
+	case "rejected":
+		return payment.Refund{}, &payment.NewebpayAPIError{API: "Close", Status: "TRA10035", Message: "rejected"}
+	}
+	return payment.Refund{
+		Provider:    p.Name(),
+		OrderNumber: orderNumber,
+		TradeNumber: tradeNumber,
+		Amount:      amount,
+		IsCancelled: isFull && tradeNumber != "captured",
+	}, nil
+}
+
+func TestSettler_Refund(t *testing.T) {
+	ms := &memberService{
+		refundable: map[string]string{
+			"p1": `{"id": "p1", "amount": 199, "orderNumber": "M1", "tradeNumber": "T1", "subscription": {"id": "1", "frequency": "monthly", "member": {"id": "m1", "firebaseId": "f1", "type": "subscribe_monthly"}}}`,
+			"p2": `{"id": "p2", "amount": 1490, "orderNumber": "M2", "tradeNumber": "captured", "subscription": {"id": "2", "frequency": "yearly", "member": {"id": "m2", "firebaseId": "f2", "type": "subscribe_yearly"}}}`,
+			"p3": `{"id": "p3", "amount": 1490, "orderNumber": "M3", "tradeNumber": "captured", "refundAmount": 1000, "subscription": {"id": "3", "frequency": "yearly", "isGift": true, "member": {"id": "m3", "firebaseId": "f3", "type": "none"}}}`,
+			"p4": `{"id": "p4", "amount": 5, "orderNumber": "M4", "tradeNumber": "T4", "subscription": {"id": "4", "frequency": "one_time", "member": {"id": "m4", "firebaseId": "f4", "type": "subscribe_yearly"}}}`,
+			"p5": `{"id": "p5", "amount": 199, "orderNumber": "M5", "tradeNumber": "failed", "subscription": {"id": "5", "frequency": "monthly", "member": {"id": "m5", "firebaseId": "f5", "type": "subscribe_monthly"}}}`,
+			"p8": `{"id": "p8", "amount": 199, "orderNumber": "M8", "tradeNumber": "rejected", "refundAmount": 50, "refundStatus": "refunded", "subscription": {"id": "8", "frequency": "monthly", "member": {"id": "m8", "firebaseId": "f8", "type": "subscribe_monthly"}}}`,
+			"p9": `{"id": "p9", "amount": 199, "orderNumber": "M9", "tradeNumber": "T9", "refundAmount": 199, "refundStatus": "pending", "subscription": {"id": "9", "frequency": "monthly", "member": {"id": "m9", "firebaseId": "f9", "type": "subscribe_monthly"}}}`,
+			"p7": `{"id": "p7", "amount": 199, "orderNumber": "M7", "tradeNumber": "T7", "subscription": {"id": "7", "frequency": "monthly", "member": {"id": "m7", "firebaseId": "f7", "type": "subscribe_monthly"}}}`,
+		},
+		active: map[string]string{
+			"m2": `[{"frequency": "monthly"}, {"frequency": "yearly", "isGift": true}]`,
+		},
+	}
+	server := httptest.NewServer(ms)
+	defer server.Close()
+	// p7 is being refunded by another request
+	s := NewSettler(graphql.NewClient(server.URL), &claimRediser{values: map[string]interface{}{refundClaimKey(payment.NewebpayProviderName, "p7"): "p7"}}, nil)
+
+	amount := func(a int) *int { return &a }
+	tests := []struct {
+		name               string
+		paymentID          string
+		amount             *int
+		wantIsCancelled    bool
+		wantRefundedAmount int
+		wantRefundStatus   string
+		wantMemberType     string
+		wantErr            bool
+		wantInvalidAmount  bool
+		// wantRecord is the payment recorded by a failed refund
+		wantRecord map[string]interface{}
+	}{
+		{name: "full refund before capture", paymentID: "p1", wantIsCancelled: true, wantRefundedAmount: 199, wantRefundStatus: RefundStatusCancelled, wantMemberType: "none"},
+		{name: "partial refund", paymentID: "p2", amount: amount(100), wantRefundedAmount: 100, wantRefundStatus: RefundStatusRefunded, wantMemberType: "subscribe_monthly"},
+		{name: "rest of a gift", paymentID: "p3", wantRefundedAmount: 1490, wantRefundStatus: RefundStatusRefunded},
+		{name: "type granted by another subscription", paymentID: "p4", wantIsCancelled: true, wantRefundedAmount: 5, wantRefundStatus: RefundStatusCancelled},
+		{name: "exceeding amount", paymentID: "p3", amount: amount(500), wantErr: true, wantInvalidAmount: true},
+		{name: "zero amount", paymentID: "p1", amount: amount(0), wantErr: true, wantInvalidAmount: true},
+		{name: "refund failed", paymentID: "p5", wantErr: true, wantRecord: map[string]interface{}{"refundAmount": float64(199), "refundStatus": RefundStatusPending}},
+		{name: "refund rejected", paymentID: "p8", wantErr: true, wantRecord: map[string]interface{}{"refundAmount": float64(50), "refundStatus": RefundStatusRefunded}},
+		{name: "pending refund", paymentID: "p9", wantErr: true},
+		{name: "not found", paymentID: "p6", wantErr: true},
+		{name: "being refunded", paymentID: "p7", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms.updates = map[string]map[string]interface{}{}
+			ms.memberTypes = map[string]string{}
+			ms.payments = map[string]map[string]interface{}{}
+
+			got, err := s.Refund(context.Background(), refundProvider{}, tt.paymentID, tt.amount, "requested by the member")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Settler.Refund() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrInvalidRefundAmount) != tt.wantInvalidAmount {
+				t.Errorf("Settler.Refund() error = %v, want invalid amount %v", err, tt.wantInvalidAmount)
+			}
+			if tt.wantErr {
+				if len(ms.updates) > 0 {
+					t.Errorf("Settler.Refund() updated %v", ms.updates)
+				}
+				record := ms.payments[tt.paymentID]
+				if tt.wantRecord == nil && record != nil {
+					t.Errorf("payment is recorded as %v", record)
+				}
+				for k, want := range tt.wantRecord {
+					if record[k] != want {
+						t.Errorf("payment is recorded with %s = %v, want %v", k, record[k], want)
+					}
+				}
+				return
+			}
+			if got.IsCancelled != tt.wantIsCancelled || got.RefundedAmount != tt.wantRefundedAmount {
+				t.Errorf("Settler.Refund() = %+v, want cancelled %v and refunded %d", got, tt.wantIsCancelled, tt.wantRefundedAmount)
+			}
+			record := ms.payments[tt.paymentID]
+			if record["refundAmount"] != float64(tt.wantRefundedAmount) || record["refundStatus"] != tt.wantRefundStatus || record["refundTime"] == nil {
+				t.Errorf("payment is recorded as %v, want refunded %d and %s", record, tt.wantRefundedAmount, tt.wantRefundStatus)
+			}
+			update := ms.updates[got.SubscriptionID]
+			if update["isActive"] != false || update["isCanceled"] != true || update["refundNote"] != "requested by the member" {
+				t.Errorf("subscription is updated with %v", update)
+			}
+			var gotMemberType string
+			for _, memberType := range ms.memberTypes {
+				gotMemberType = memberType
+			}
+			if gotMemberType != tt.wantMemberType {
+				t.Errorf("member type = %q, want %q", gotMemberType, tt.wantMemberType)
+			}
+		})
+	}
+}
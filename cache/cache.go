@@ -2,9 +2,15 @@ package cache
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type Rediser interface {
@@ -13,5 +19,110 @@ type Rediser interface {
 	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd
 
 	Get(ctx context.Context, key string) *redis.StringCmd
+	Incr(ctx context.Context, key string) *redis.IntCmd
+	Decr(ctx context.Context, key string) *redis.IntCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Expire(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd
+	ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd
+	TTL(ctx context.Context, key string) *redis.DurationCmd
+
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SCard(ctx context.Context, key string) *redis.IntCmd
+	SIsMember(ctx context.Context, key string, member interface{}) *redis.BoolCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+}
+
+type scanner interface {
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+}
+
+// NewRediser creates the redis client of the type of the service
+func NewRediser(c config.RedisService) (Rediser, error) {
+	if len(c.Addresses) == 0 {
+		return nil, errors.New("there's no redis address provided")
+	}
+	addrs := make([]string, 0, len(c.Addresses))
+	for _, a := range c.Addresses {
+		addrs = append(addrs, fmt.Sprintf("%s:%d", a.Addr, a.Port))
+	}
+
+	switch c.Type {
+	case "cluster":
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: c.Password,
+		}), nil
+	case "single":
+		if len(addrs) > 1 {
+			logrus.Warnf("single type Redis accepts only the first address, but %d addresses are provided", len(addrs))
+		}
+		// Only the first address is used because it's a single instance
+		return redis.NewClient(&redis.Options{
+			Addr:     addrs[0],
+			Password: c.Password,
+		}), nil
+	case "sentinel":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			SentinelAddrs: addrs,
+			Password:      c.Password,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis type(%s)", c.Type)
+	}
+}
+
+// Keys returns the keys matching the glob pattern with SCAN. Every master node is scanned if rdb is a cluster client.
+func Keys(ctx context.Context, rdb Rediser, pattern string) ([]string, error) {
+	switch client := Unwrap(rdb).(type) {
+	case *redis.ClusterClient:
+		var mu sync.Mutex
+		keys := make([]string, 0)
+		err := client.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			nodeKeys, err := scan(ctx, node, pattern)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			keys = append(keys, nodeKeys...)
+			mu.Unlock()
+			return nil
+		})
+		return keys, err
+	case scanner:
+		return scan(ctx, client, pattern)
+	default:
+		return nil, fmt.Errorf("%T doesn't support scan", rdb)
+	}
+}
+
+func scan(ctx context.Context, s scanner, pattern string) ([]string, error) {
+	keys := make([]string, 0)
+	var cursor uint64
+	for {
+		page, next, err := s.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return nil, errors.Wrapf(err, "scanning keys of pattern(%s) encountered error", pattern)
+		}
+		keys = append(keys, page...)
+		if next == 0 {
+			return keys, nil
+		}
+		cursor = next
+	}
+}
+
+// EscapePattern escapes the special characters of glob-style patterns in s
+func EscapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLocalCacheTTL     = 5 // seconds
+	defaultInvalidateChannel = "apigateway.cache.invalidate"
+)
+
+type pubSuber interface {
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// invalidation is the message broadcast to the other replicas when keys are written or deleted
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+type localEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// fill is a value of a key being read from redis. It isn't cached if the key is invalidated during the read.
+type fill struct {
+	refs          int
+	isInvalidated bool
+}
+
+// LocalCache is an in-process LRU cache of the values of Get in front of a Rediser. Every write of a value through it evicts the key locally and broadcasts the eviction to the other replicas with redis pub/sub. Sets are never cached, so their writes are not broadcast.
+//
+// Counters and locks, which are never read through the cache, should use the Rediser returned by Unwrap to skip both the cache and the broadcast.
+type LocalCache struct {
+	Rediser
+	maxBytes int
+	ttl      time.Duration
+	channel  string
+	origin   string
+
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	fills   map[string]*fill
+
+	pubsub *redis.PubSub
+}
+
+// NewLocalCache wraps rdb with a LocalCache. rdb is returned as is if MaxBytes is not positive.
+func NewLocalCache(rdb Rediser, c config.LocalCache) Rediser {
+	if c.MaxBytes <= 0 {
+		return rdb
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultLocalCacheTTL
+	}
+	channel := c.Channel
+	if channel == "" {
+		channel = defaultInvalidateChannel
+	}
+	l := &LocalCache{
+		Rediser:  rdb,
+		maxBytes: c.MaxBytes,
+		ttl:      time.Duration(ttl) * time.Second,
+		channel:  channel,
+		origin:   xid.New().String(),
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		fills:    make(map[string]*fill),
+	}
+
+	if ps, ok := rdb.(pubSuber); ok {
+		l.pubsub = ps.Subscribe(context.Background(), channel)
+		go l.listen(l.pubsub.Channel())
+	} else {
+		logrus.Warnf("%T doesn't support pub/sub, local cache invalidation won't be broadcast", rdb)
+	}
+	return l
+}
+
+// Unwrap returns the underlying Rediser
+func (l *LocalCache) Unwrap() Rediser {
+	return l.Rediser
+}
+
+// Unwrap returns the Rediser under the local cache of rdb, or rdb itself if it has no local cache
+func Unwrap(rdb Rediser) Rediser {
+	if w, ok := rdb.(interface{ Unwrap() Rediser }); ok {
+		return w.Unwrap()
+	}
+	return rdb
+}
+
+// Close stops listening to the invalidation of the other replicas
+func (l *LocalCache) Close() error {
+	if l.pubsub == nil {
+		return nil
+	}
+	return l.pubsub.Close()
+}
+
+func (l *LocalCache) listen(ch <-chan *redis.Message) {
+	for msg := range ch {
+		var inv invalidation
+		if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+			logrus.Warnf("invalid cache invalidation message(%s): %v", msg.Payload, err)
+			continue
+		}
+		if inv.Origin == l.origin {
+			continue
+		}
+		l.evict(inv.Keys...)
+	}
+}
+
+func (l *LocalCache) Get(ctx context.Context, key string) *redis.StringCmd {
+	if value, ok := l.get(key); ok {
+		return redis.NewStringResult(value, nil)
+	}
+
+	f := l.startFill(key)
+	cmd := l.Rediser.Get(ctx, key)
+	value, err := cmd.Result()
+	l.endFill(key, f, value, err == nil)
+	return cmd
+}
+
+func (l *LocalCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.Set(ctx, key, value, ttl)
+}
+
+func (l *LocalCache) SetXX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.SetXX(ctx, key, value, ttl)
+}
+
+func (l *LocalCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.SetNX(ctx, key, value, ttl)
+}
+
+func (l *LocalCache) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	defer l.invalidate(ctx, keys...)
+	return l.Rediser.Del(ctx, keys...)
+}
+
+func (l *LocalCache) Expire(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.Expire(ctx, key, ttl)
+}
+
+func (l *LocalCache) ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.ExpireAt(ctx, key, tm)
+}
+
+func (l *LocalCache) Incr(ctx context.Context, key string) *redis.IntCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.Incr(ctx, key)
+}
+
+func (l *LocalCache) Decr(ctx context.Context, key string) *redis.IntCmd {
+	defer l.invalidate(ctx, key)
+	return l.Rediser.Decr(ctx, key)
+}
+
+// invalidate evicts the keys locally and broadcasts the eviction
+func (l *LocalCache) invalidate(ctx context.Context, keys ...string) {
+	l.evict(keys...)
+	if l.pubsub == nil {
+		return
+	}
+	payload, _ := json.Marshal(invalidation{Origin: l.origin, Keys: keys})
+	if err := l.Rediser.(pubSuber).Publish(ctx, l.channel, payload).Err(); err != nil {
+		logrus.Warnf("publishing the invalidation of keys(%v) encountered error: %v", keys, err)
+	}
+}
+
+func (l *LocalCache) get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := e.Value.(*localEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.remove(e)
+		return "", false
+	}
+	l.lru.MoveToFront(e)
+	return entry.value, true
+}
+
+// startFill registers a read of the key from redis, so its invalidation during the read can be told
+func (l *LocalCache) startFill(key string) *fill {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.fills[key]
+	if !ok {
+		f = &fill{}
+		l.fills[key] = f
+	}
+	f.refs++
+	return f
+}
+
+// endFill caches the value read by the fill if it's ok and the key isn't invalidated after the read starts, so a value read before an invalidation is never cached after it
+func (l *LocalCache) endFill(key string, f *fill, value string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f.refs--; f.refs == 0 && l.fills[key] == f {
+		delete(l.fills, key)
+	}
+	if !ok || f.isInvalidated {
+		return
+	}
+
+	size := len(key) + len(value)
+	if size > l.maxBytes {
+		return
+	}
+	if e, ok := l.entries[key]; ok {
+		l.remove(e)
+	}
+	l.entries[key] = l.lru.PushFront(&localEntry{key: key, value: value, expiresAt: time.Now().Add(l.ttl)})
+	l.size += size
+	for l.size > l.maxBytes {
+		l.remove(l.lru.Back())
+	}
+}
+
+// evict removes the keys and drops the values of them being read
+func (l *LocalCache) evict(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if f, ok := l.fills[key]; ok {
+			// the reads starting later get a new fill
+			f.isInvalidated = true
+			delete(l.fills, key)
+		}
+		if e, ok := l.entries[key]; ok {
+			l.remove(e)
+		}
+	}
+}
+
+func (l *LocalCache) remove(e *list.Element) {
+	entry := l.lru.Remove(e).(*localEntry)
+	delete(l.entries, entry.key)
+	l.size -= len(entry.key) + len(entry.value)
+}
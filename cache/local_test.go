@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/config"
+)
+
+// countingRediser implements Get, Set and Del of Rediser in memory and counts Get
+type countingRediser struct {
+	Rediser
+	kv   map[string]string
+	gets int
+}
+
+func (c *countingRediser) Get(ctx context.Context, key string) *redis.StringCmd {
+	c.gets++
+	v, ok := c.kv[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (c *countingRediser) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	c.kv[key] = value.(string)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *countingRediser) Incr(ctx context.Context, key string) *redis.IntCmd {
+	n, _ := strconv.Atoi(c.kv[key])
+	c.kv[key] = strconv.Itoa(n + 1)
+	return redis.NewIntResult(int64(n+1), nil)
+}
+
+func (c *countingRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, k := range keys {
+		delete(c.kv, k)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+func TestLocalCache_Get(t *testing.T) {
+	ctx := context.Background()
+	rdb := &countingRediser{kv: map[string]string{"a": "1234", "b": "5678", "c": "9012"}}
+	// each entry takes 5 bytes, so only two of them fit
+	l := NewLocalCache(rdb, config.LocalCache{MaxBytes: 10, TTL: 60}).(*LocalCache)
+
+	tests := []struct {
+		name     string
+		do       func()
+		key      string
+		want     string
+		wantGets int
+	}{
+		{name: "miss", key: "a", want: "1234", wantGets: 1},
+		{name: "hit", key: "a", want: "1234", wantGets: 1},
+		{name: "set invalidates", do: func() { l.Set(ctx, "a", "4321", 0) }, key: "a", want: "4321", wantGets: 2},
+		{name: "incr invalidates", do: func() { l.Incr(ctx, "a") }, key: "a", want: "4322", wantGets: 3},
+		{name: "fill b", key: "b", want: "5678", wantGets: 4},
+		{name: "c evicts the least recently used a", key: "c", want: "9012", wantGets: 5},
+		{name: "b is still cached", key: "b", want: "5678", wantGets: 5},
+		{name: "a is evicted", key: "a", want: "4322", wantGets: 6},
+		{
+			name: "invalidation of another replica",
+			do: func() {
+				rdb.kv["a"] = "0000"
+				payload, _ := json.Marshal(invalidation{Origin: "another", Keys: []string{"a"}})
+				ch := make(chan *redis.Message, 1)
+				ch <- &redis.Message{Payload: string(payload)}
+				close(ch)
+				l.listen(ch)
+			},
+			key: "a", want: "0000", wantGets: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.do != nil {
+				tt.do()
+			}
+			if got := l.Get(ctx, tt.key).Val(); got != tt.want {
+				t.Errorf("LocalCache.Get() = %v, want %v", got, tt.want)
+			}
+			if rdb.gets != tt.wantGets {
+				t.Errorf("redis Get is called %d times, want %d", rdb.gets, tt.wantGets)
+			}
+		})
+	}
+}
+
+func TestLocalCache_endFill(t *testing.T) {
+	l := NewLocalCache(&countingRediser{kv: map[string]string{}}, config.LocalCache{MaxBytes: 10}).(*LocalCache)
+
+	a, b := l.startFill("a"), l.startFill("b")
+	// a is invalidated while the values are being read from redis
+	l.evict("a")
+	// the read of a after the invalidation isn't affected by it
+	fresh := l.startFill("a")
+	l.endFill("a", a, "1", true)
+	l.endFill("b", b, "2", true)
+
+	if _, ok := l.get("a"); ok {
+		t.Errorf("value read before the invalidation is cached")
+	}
+	if _, ok := l.get("b"); !ok {
+		t.Errorf("value of another key is not cached")
+	}
+	l.endFill("a", fresh, "3", true)
+	if v, ok := l.get("a"); !ok || v != "3" {
+		t.Errorf("value read after the invalidation is %q, cached %v", v, ok)
+	}
+	if len(l.fills) != 0 {
+		t.Errorf("fills %v are left", l.fills)
+	}
+}
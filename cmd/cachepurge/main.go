@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"time"
+
+	formatter "github.com/bcgodev/logrus-formatter-gke"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/server"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	logrus.SetFormatter(&formatter.GKELogFormatter{})
+	logrus.SetReportCaller(true)
+}
+
+// values collects the values of a repeated flag
+type values []string
+
+func (v *values) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *values) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+func main() {
+	var req server.PurgeRequest
+	flag.Var((*values)(&req.URIs), "uri", "request URI to purge, e.g. /api/v0/posts?max_results=10. It can be repeated")
+	flag.Var((*values)(&req.Prefixes), "prefix", "prefix of request URIs to purge. It can be repeated")
+	flag.Var((*values)(&req.Tags), "tag", "post ID of which the responses are purged. It can be repeated")
+	flag.Parse()
+
+	if len(req.URIs) == 0 && len(req.Prefixes) == 0 && len(req.Tags) == 0 {
+		flag.Usage()
+		logrus.Fatal("nothing to purge")
+	}
+
+	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
+	// It shares the config of apigateway
+	v.SetConfigName("config")
+	v.AddConfigPath("./configs")
+	err := v.ReadInConfig()
+	if err != nil {
+		logrus.Fatalf("fatal error config file: %s", err)
+	}
+
+	var cfg config.Conf
+	err = v.Unmarshal(&cfg)
+	if err != nil {
+		logrus.Fatalf("unable to decode into struct, %v", err)
+	}
+
+	rdb, err := cache.NewRediser(cfg.RedisService)
+	if err != nil {
+		logrus.Fatalf("unable to create redis client: %v", err)
+	}
+
+	proxyCache, err := server.NewProxyCache(rdb, cfg.RedisService.Cache)
+	if err != nil {
+		logrus.Fatalf("unable to create proxy cache: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	purged, err := proxyCache.Purge(ctx, req)
+	if err != nil {
+		logrus.Fatalf("purging cache encountered error after %d keys are purged: %v", purged, err)
+	}
+	logrus.Infof("%d keys are purged", purged)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	formatter "github.com/bcgodev/logrus-formatter-gke"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/httpclient"
+	"github.com/machinebox/graphql"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mirror-media/apigateway/billing"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/mirror-media/apigateway/promotion"
+	"github.com/mirror-media/apigateway/server"
+	"github.com/mirror-media/apigateway/trial"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	logrus.SetFormatter(&formatter.GKELogFormatter{})
+	logrus.SetReportCaller(true)
+}
+
+// main exits with 1 if reconciling is incomplete, after the report of the discrepancies found so far is written
+func main() {
+	if !run() {
+		os.Exit(1)
+	}
+}
+
+// run reconciles the subscriptions and writes the report. isComplete is false if reconciling or recording trials stopped at an error.
+func run() (isComplete bool) {
+	isComplete = true
+	provider := flag.String("provider", payment.NewebpayProviderName, "payment provider of the subscriptions to reconcile")
+	since := flag.Duration("since", 72*time.Hour, "reconcile the subscriptions created within the duration")
+	grace := flag.Duration("grace", time.Hour, "skip the subscriptions created within the duration, of which the notifications may be still on the way")
+	isDryRun := flag.Bool("dry-run", false, "report the discrepancies without fixing them")
+	report := flag.String("report", "-", "path of the CSV report, or - for stdout")
+	flag.Parse()
+
+	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
+	// It shares the config of membermutation, which owns the payment providers
+	v.SetConfigName("membermutationConfig")
+	v.AddConfigPath("./configs")
+	err := v.ReadInConfig()
+	if err != nil {
+		logrus.Fatalf("fatal error config file: %s", err)
+	}
+
+	var cfg config.Conf
+	err = v.Unmarshal(&cfg)
+	if err != nil {
+		logrus.Fatalf("unable to decode into struct, %v", err)
+	}
+
+	p, err := server.NewPaymentProviders(cfg).Get(*provider)
+	if err != nil {
+		logrus.Fatalf("unable to get the provider: %v", err)
+	}
+	rdb, err := cache.NewRediser(cfg.RedisService)
+	if err != nil {
+		logrus.Fatalf("unable to create redis client: %v", err)
+	}
+	client := graphql.NewClient(cfg.ServiceEndpoints.UserGraphQL, graphql.WithHTTPClient(httpclient.DefaultNetHttpClient))
+	reconciler := billing.NewReconciler(client, billing.NewSettler(client, rdb, promotion.NewRedeemer(rdb, client, cfg.Promotion)), p)
+
+	var w io.Writer = os.Stdout
+	if *report != "-" {
+		f, err := os.Create(*report)
+		if err != nil {
+			logrus.Fatalf("unable to create the report: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	now := time.Now()
+	discrepancies, err := reconciler.Reconcile(ctx, now.Add(-*since), now.Add(-*grace), *isDryRun)
+	if err != nil {
+		logrus.Errorf("reconciling encountered error after %d discrepancies are found: %v", len(discrepancies), err)
+		isComplete = false
+	}
+
+	// the trials ended in the same window are counted as converted or expired, after the grace for their first charges
+	if cfg.MemberSchema.HasTrialEndDatetime {
+		trials, err := trial.NewTracker(rdb)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		recorded, err := reconciler.RecordTrials(ctx, trials, now.Add(-*since), now.Add(-*grace))
+		if err != nil {
+			logrus.Errorf("recording trials encountered error after %d trials are recorded: %v", recorded, err)
+			isComplete = false
+		}
+	}
+
+	// the fixed members are refetched as the notification handler does
+	entitlements := entitlement.NewCache(rdb, cfg.EntitlementCache)
+	var fixed int
+	for _, d := range discrepancies {
+		if !d.IsFixed {
+			continue
+		}
+		fixed++
+		if d.FirebaseID == "" {
+			continue
+		}
+		if err := entitlements.Invalidate(ctx, d.FirebaseID); err != nil {
+			logrus.Warnf("invalidating the entitlements of member(%s) encountered error: %v", d.FirebaseID, err)
+		}
+	}
+
+	if err := billing.WriteReport(w, discrepancies); err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("%d discrepancies are found and %d are fixed", len(discrepancies), fixed)
+	return isComplete
+}
@@ -11,14 +11,120 @@ type RedisAddress struct {
 	Port int
 }
 
+// CacheRule is the cache policy of the requests of which the path starts with PathPrefix, e.g. /api/v0/getposts. The rule with the longest matched prefix wins.
+type CacheRule struct {
+	PathPrefix           string
+	TTL                  int      // seconds, 0 disables the cache
+	StaleWhileRevalidate int      // seconds
+	StaleIfError         int      // seconds
+	Methods              []string // default to GET
+	StatusCodes          []int    // default to 200
+	IgnoredQueryParams   []string // query parameters left out of the cache key
+	SortQueryParams      bool     // sort the query parameters in the cache key
+	BypassHeader         string   // requests with the header neither read nor write the cache
+}
+
+// RedisCache has the default cache policy. It applies to GET requests and 200 responses only.
 type RedisCache struct {
-	TTL int
+	TTL                  int // seconds
+	StaleWhileRevalidate int // seconds an expired entry is still served while it's refreshed in the background
+	StaleIfError         int // seconds an entry is kept after the stale-while-revalidate window to be served while the circuit of the upstream is open
+	LockTTL              int // seconds, default to 10
+	LockWait             int // milliseconds to wait for the replica refreshing the entry, default to 3000
+	Rules                []CacheRule
+	Compression          string // encoding of the stored responses, gzip, br or identity, default to gzip
+}
+
+// EntitlementCache caches the entitlements of members from the member service. It's disabled if TTL is not positive.
+type EntitlementCache struct {
+	TTL         int // seconds an entitlement is reused before it's refetched
+	FallbackTTL int // seconds the last known entitlement is kept for when the member service is down, default to 7 days
+}
+
+// UpstreamPolicy is how the gateway calls an upstream. Zero fields fall back to the defaults.
+type UpstreamPolicy struct {
+	DialTimeout      int  // milliseconds, default to 3000
+	ResponseTimeout  int  // milliseconds to wait for the response headers, default to 10000
+	MaxRetries       *int // retries of idempotent requests when the upstream is unavailable, default to 0. It's a pointer so 0 can override a fallback.
+	RetryBackoff     int  // milliseconds, multiplied by the number of the retry, default to 100
+	BreakerThreshold int  // consecutive failures to open the circuit, default to 5. Negative disables the breaker.
+	BreakerCooldown  int  // seconds the circuit stays open before a trial request, default to 30
+}
+
+// UpstreamRoute proxies the v0 requests of which the path, without "/api/v0", matches PathPrefix or PathRegex to Upstream. The matched prefix is replaced by Rewrite, or Rewrite is expanded with the submatches of PathRegex, e.g., "/$1".
+type UpstreamRoute struct {
+	PathPrefix  string
+	PathRegex   string
+	Upstream    string         // default to V0RESTfulSvrTargetURL
+	Rewrite     string         // the path is unchanged if it's empty
+	SkipPaywall bool           // posts are not truncated by the paywall if it's true
+	Policy      UpstreamPolicy // zero fields fall back to UpstreamPolicy
+}
+
+// DegradedMode is how readers are treated when their entitlements cannot be fetched from the member service. The last known entitlement in EntitlementCache is always used if there is one, but there's none if EntitlementCache is disabled, and then every member is treated by FailOpen.
+type DegradedMode struct {
+	FailOpen bool // readers with a valid token are treated as premium members if there's no last known entitlement
+}
+
+// RateLimit limits the requests of a member, or a client IP without a member, in a sliding window. It's disabled if Requests is not positive.
+type RateLimit struct {
+	Requests int
+	Window   int // seconds, default to 60
+}
+
+// RateLimits are the limits of the route groups
+type RateLimits struct {
+	V0 RateLimit // the restful proxy
+	V1 RateLimit // tokenState
+	V2 RateLimit // GraphQL
+}
+
+// Institution is a licensee of which readers in the IP ranges or signed in with the SSO providers are granted premium access
+type Institution struct {
+	ID           string
+	Name         string
+	CIDRs        []string // CIDRs or single IPs
+	SSOProviders []string // Firebase sign-in providers of the SSO of the institution, which are SAML or OIDC providers like saml.library
+}
+
+// Institutions is the registry of the institutions, which grant premium access to the readers from their IP ranges or signed in with their SSO. X-Forwarded-For is honoured only for the hops in TrustedProxies.
+type Institutions struct {
+	Registry       []Institution
+	TrustedProxies []string // CIDRs or single IPs of the load balancers in front of the gateway
+}
+
+// GroupSubscription configures the seats of group subscriptions by group ID. A seat count of 0 means unlimited.
+type GroupSubscription struct {
+	DefaultSeats int
+	Seats        map[string]int
+}
+
+// MemberSchema tells which fields added for the entitlements the member service has migrated. The fields not migrated yet are left out of the entitlement query, so the gateway can be deployed before the member service.
+type MemberSchema struct {
+	HasTrialEndDatetime    bool // subscription has trialEndDatetime, without which expired trials keep the privilege of their member types
+	HasRedeemedGift        bool // member has redeemedGift, without which redeemed gifts grant no privilege
+	HasGroupJoinedDatetime bool // member has groupJoinedDatetime, without which members take the seats of their groups in the order of their IDs
+}
+
+// Promotion configures the limits of promotion codes, which are kept here instead of the promotions in the member service. Codes are case insensitive. A limit of 0 means unlimited.
+type Promotion struct {
+	DefaultUsageLimit int
+	UsageLimits       map[string]int // by code
+	PerMemberLimit    int
+}
+
+// LocalCache is the in-process cache in front of redis. It's disabled if MaxBytes is not positive.
+type LocalCache struct {
+	MaxBytes int    // the total size of keys and values
+	TTL      int    // seconds, default to 5
+	Channel  string // the pub/sub channel of invalidation, default to apigateway.cache.invalidate
 }
 
 // RedisService represents a object of a redis service. If the type is sentinel, the first address is always treated as the master.
 type RedisService struct {
 	Addresses []RedisAddress // 1. ip:port, 2. dns:port
 	Cache     RedisCache
+	Local     LocalCache
 	Password  string
 	Type      string // 1. single, 2. sentinel, 3. cluster
 }
@@ -27,9 +133,12 @@ type NewebPayStore struct {
 	CallbackHost        string
 	CallbackProtocol    string
 	ClientBackPath      string
+	Endpoint            string // https://core.newebpay.com, or https://ccore.newebpay.com for testing
 	ID                  string
-	IsAbleToModifyEmail int8 // Use 1
-	LoginType           int8 // Use 0
+	HashKey             string // The 32-byte key to encrypt TradeInfo
+	HashIV              string // The 16-byte IV to encrypt TradeInfo
+	IsAbleToModifyEmail int8   // Use 1
+	LoginType           int8   // Use 0
 	NotifyProtocol      string
 	NotifyHost          string
 	NotifyPath          string
@@ -39,12 +148,80 @@ type NewebPayStore struct {
 	Version             string // Use 1.6
 }
 
+// ECPayStore is the ECPay merchant paying one-time subscriptions and gifts. It's disabled if ID is empty.
+type ECPayStore struct {
+	ID               string
+	HashKey          string
+	HashIV           string
+	Endpoint         string // https://payment.ecpay.com.tw, or https://payment-stage.ecpay.com.tw for testing
+	CallbackProtocol string
+	CallbackHost     string
+	ClientBackPath   string
+	ReturnPath       string
+	NotifyProtocol   string
+	NotifyHost       string
+	NotifyPath       string
+}
+
 type FeatureToggles struct {
 	Bucket string
 	Object string
 	Type   string
 }
 
+// ContentGatingMatch lists the conditions of a policy. Every non-empty condition has to be met for the policy to match.
+type ContentGatingMatch struct {
+	Categories           []string // names of categories, any of them
+	IsMemberOnlyCategory *bool    // whether the post has a category with isMemberOnly
+	Tags                 []string // names of tags, any of them
+	Writers              []string // names of writers, any of them
+	Flags                []string // boolean fields of a post which have to be true, e.g. isAdvertised
+	PublishedAfter       string   // RFC3339
+	PublishedBefore      string   // RFC3339
+	MinWordCount         int
+}
+
+// ContentGatingTruncation describes how a post is truncated
+type ContentGatingTruncation struct {
+	Strategy   string // 1. none, 2. blocks, 3. words, 4. percentage
+	Blocks     int    // number of apiData blocks to keep for blocks
+	Words      int    // word budget for words
+	Percentage int    // percentage of the word count to keep for percentage
+}
+
+type ContentGatingPolicy struct {
+	Name       string
+	Match      ContentGatingMatch
+	Truncation ContentGatingTruncation
+}
+
+// ContentGating represents the paywall policies. Policies are evaluated in order and the first matched one wins. Posts matching no policy are free.
+type ContentGating struct {
+	Policies []ContentGatingPolicy
+}
+
+// Metering lets readers open a number of premium posts for free every calendar month. A limit of 0 disables the metering for that kind of reader.
+type Metering struct {
+	MemberLimit    int
+	AnonymousLimit int
+	// AnonymousIPLimit is the quota shared by all anonymous readers behind a client IP. 0 means 10 times AnonymousLimit.
+	AnonymousIPLimit  int
+	AnonymousIDHeader string // header carrying the signed id of an anonymous reader for clients without cookies
+	Secret            string // signs the ids issued to anonymous readers. It's required if AnonymousLimit is set.
+}
+
+// ShareLink configures the signed links members use to share premium posts. Share links are disabled if Secret is empty.
+type ShareLink struct {
+	Secret       string
+	TTL          int // seconds
+	MonthlyQuota int // 0 means unlimited
+}
+
+// Admin configures the endpoints for administrators, e.g. the CMS webhook. They are disabled if Token is empty.
+type Admin struct {
+	Token string
+}
+
 type Conf struct {
 	Address                     string
 	FirebaseCredentialFilePath  string
@@ -53,10 +230,24 @@ type Conf struct {
 	RedisService                RedisService
 	ServiceEndpoints            ServiceEndpoints
 	NewebPayStore               NewebPayStore
+	ECPayStore                  ECPayStore
 	TokenSecretName             string
 	V0RESTfulSvrTargetURL       string
+	UpstreamRoutes              []UpstreamRoute
+	UpstreamPolicy              UpstreamPolicy
 	FeatureToggles              FeatureToggles
 	PrivilegedEmailDomains      map[string]bool
+	ContentGating               ContentGating
+	Metering                    Metering
+	ShareLink                   ShareLink
+	Admin                       Admin
+	EntitlementCache            EntitlementCache
+	DegradedMode                DegradedMode
+	RateLimits                  RateLimits
+	Institutions                Institutions
+	GroupSubscription           GroupSubscription
+	MemberSchema                MemberSchema
+	Promotion                   Promotion
 }
 
 func (c *Conf) Valid() bool {
@@ -0,0 +1,138 @@
+// Package entitlement caches what a member is entitled to read
+package entitlement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultFallbackTTL = 7 * 24 * 60 * 60 // seconds
+
+// Entitlement is what a member is entitled to read
+type Entitlement struct {
+	HasPremiumPrivilege bool     `json:"hasPremiumPrivilege"`
+	SubscribedPostIDs   []string `json:"subscribedPostIds"`
+}
+
+// lastKnown is the cached entitlement, which is kept for fallback until ExpiresAt
+type lastKnown struct {
+	Entitlement
+	ExpiresAt int64 `json:"expiresAt"` // unix time
+}
+
+// Fetcher fetches the entitlement of the member from the member service
+type Fetcher func(ctx context.Context, firebaseID string) (Entitlement, error)
+
+// Cache caches entitlements by firebase ID. An entitlement is refetched after TTL, but the last known one is kept for FallbackTTL in case the member service is down. The expiration of the last known one is stored with it, so it isn't used after FallbackTTL even if the key outlives it. A nil Cache always fetches.
+type Cache struct {
+	rdb         cache.Rediser
+	ttl         time.Duration
+	fallbackTTL time.Duration
+	now         func() time.Time
+}
+
+// NewCache returns nil if TTL is not positive
+func NewCache(rdb cache.Rediser, c config.EntitlementCache) *Cache {
+	if c.TTL <= 0 {
+		return nil
+	}
+	fallbackTTL := c.FallbackTTL
+	if fallbackTTL <= 0 {
+		fallbackTTL = defaultFallbackTTL
+	}
+	return &Cache{
+		rdb:         rdb,
+		ttl:         time.Duration(c.TTL) * time.Second,
+		fallbackTTL: time.Duration(fallbackTTL) * time.Second,
+		now:         time.Now,
+	}
+}
+
+func key(firebaseID string) string {
+	return fmt.Sprintf("%s.%s.%s", "apigateway", "entitlement", firebaseID)
+}
+
+// freshKey exists until the cached entitlement has to be refetched
+func freshKey(firebaseID string) string {
+	return key(firebaseID) + ".fresh"
+}
+
+// Get returns the cached entitlement if it's fresh, or fetches it. isFallback is true if fetching failed and the last known entitlement is returned instead.
+func (c *Cache) Get(ctx context.Context, firebaseID string, fetch Fetcher) (e Entitlement, isFallback bool, err error) {
+	if c == nil {
+		e, err = fetch(ctx, firebaseID)
+		return e, false, err
+	}
+
+	logger := logrus.WithField("firebaseId", firebaseID)
+	cached, hasCached := c.load(ctx, logger, firebaseID)
+	if hasCached {
+		if err := c.rdb.Get(ctx, freshKey(firebaseID)).Err(); err == nil {
+			return cached, false, nil
+		} else if err != redis.Nil {
+			logger.Warnf("checking the freshness of the entitlement encountered error: %v", err)
+		}
+	}
+
+	e, err = fetch(ctx, firebaseID)
+	if err != nil {
+		if hasCached {
+			logger.Warnf("fetching entitlement encountered error and the last known one is used: %v", err)
+			return cached, true, nil
+		}
+		return Entitlement{}, false, err
+	}
+
+	c.store(ctx, logger, firebaseID, e)
+	return e, false, nil
+}
+
+// Invalidate makes the entitlements of the members to be refetched. The last known ones are still kept for fallback.
+func (c *Cache) Invalidate(ctx context.Context, firebaseIDs ...string) error {
+	if c == nil || len(firebaseIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(firebaseIDs))
+	for _, id := range firebaseIDs {
+		keys = append(keys, freshKey(id))
+	}
+	return errors.Wrapf(c.rdb.Del(ctx, keys...).Err(), "invalidating entitlements of %v encountered error", firebaseIDs)
+}
+
+func (c *Cache) load(ctx context.Context, logger *logrus.Entry, firebaseID string) (e Entitlement, ok bool) {
+	b, err := c.rdb.Get(ctx, key(firebaseID)).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Warnf("loading the cached entitlement encountered error: %v", err)
+		}
+		return Entitlement{}, false
+	}
+	var cached lastKnown
+	if err = json.Unmarshal(b, &cached); err != nil {
+		logger.Warnf("decoding the cached entitlement(%s) encountered error: %v", b, err)
+		return Entitlement{}, false
+	}
+	if !c.now().Before(time.Unix(cached.ExpiresAt, 0)) {
+		return Entitlement{}, false
+	}
+	return cached.Entitlement, true
+}
+
+func (c *Cache) store(ctx context.Context, logger *logrus.Entry, firebaseID string, e Entitlement) {
+	b, _ := json.Marshal(lastKnown{Entitlement: e, ExpiresAt: c.now().Add(c.fallbackTTL).Unix()})
+	if err := c.rdb.Set(ctx, key(firebaseID), b, c.fallbackTTL).Err(); err != nil {
+		logger.Warnf("caching the entitlement encountered error: %v", err)
+		return
+	}
+	if err := c.rdb.Set(ctx, freshKey(firebaseID), "1", c.ttl).Err(); err != nil {
+		logger.Warnf("marking the cached entitlement fresh encountered error: %v", err)
+	}
+}
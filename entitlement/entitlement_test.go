@@ -0,0 +1,117 @@
+package entitlement
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+)
+
+// memoryRediser implements Get, Set and Del of cache.Rediser in memory. Expiration is ignored.
+type memoryRediser struct {
+	cache.Rediser
+	kv map[string]string
+}
+
+func (m *memoryRediser) Get(ctx context.Context, key string) *redis.StringCmd {
+	v, ok := m.kv[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (m *memoryRediser) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	switch v := value.(type) {
+	case []byte:
+		m.kv[key] = string(v)
+	case string:
+		m.kv[key] = v
+	}
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (m *memoryRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, k := range keys {
+		delete(m.kv, k)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+func TestCache_Get(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(&memoryRediser{kv: map[string]string{}}, config.EntitlementCache{TTL: 60})
+
+	var calls int
+	var fetchErr error
+	premium := Entitlement{HasPremiumPrivilege: true, SubscribedPostIDs: []string{}}
+	fetch := func(ctx context.Context, firebaseID string) (Entitlement, error) {
+		calls++
+		if fetchErr != nil {
+			return Entitlement{}, fetchErr
+		}
+		return premium, nil
+	}
+
+	tests := []struct {
+		name           string
+		firebaseID     string
+		do             func()
+		want           Entitlement
+		wantIsFallback bool
+		wantErr        bool
+		wantCalls      int
+	}{
+		{name: "miss", firebaseID: "a", want: premium, wantCalls: 1},
+		{name: "hit", firebaseID: "a", want: premium, wantCalls: 1},
+		{name: "invalidated", firebaseID: "a", do: func() { c.Invalidate(ctx, "a") }, want: premium, wantCalls: 2},
+		{
+			name:       "member service is down",
+			firebaseID: "a",
+			do: func() {
+				c.Invalidate(ctx, "a")
+				fetchErr = errors.New("down")
+			},
+			want:           premium,
+			wantIsFallback: true,
+			wantCalls:      3,
+		},
+		{name: "member service is down without the last known entitlement", firebaseID: "b", wantErr: true, wantCalls: 4},
+		{
+			name:       "last known entitlement has expired",
+			firebaseID: "a",
+			do: func() {
+				now := time.Now().Add(7*24*time.Hour + time.Second)
+				c.now = func() time.Time { return now }
+			},
+			wantErr:   true,
+			wantCalls: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.do != nil {
+				tt.do()
+			}
+			got, isFallback, err := c.Get(ctx, tt.firebaseID, fetch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Cache.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cache.Get() = %+v, want %+v", got, tt.want)
+			}
+			if isFallback != tt.wantIsFallback {
+				t.Errorf("Cache.Get() isFallback = %v, want %v", isFallback, tt.wantIsFallback)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fetch is called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package entitlement
+
+import (
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/pkg/errors"
+)
+
+// isGiftEntitled tells if any of the redeemed gifts is in its period. A gift without the end of its period grants nothing.
+func (p *Privileges) isGiftEntitled(gifts []*model.Subscription) (bool, error) {
+	for _, g := range gifts {
+		if g == nil || g.PeriodEndDatetime == nil || *g.PeriodEndDatetime == "" {
+			continue
+		}
+		state, err := p.windowState(g.PeriodFirstDatetime, g.PeriodEndDatetime)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid period of gift(%s)", g.ID)
+		} else if state == StateActive {
+			return true, nil
+		}
+	}
+	return false, nil
+}
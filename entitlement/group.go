@@ -0,0 +1,67 @@
+package entitlement
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/pkg/errors"
+)
+
+// Seats returns the seats of the group. 0 means unlimited.
+func (p *Privileges) Seats(groupID string) int {
+	if seats, ok := p.seats[groupID]; ok {
+		return seats
+	}
+	return p.defaultSeats
+}
+
+// GroupState tells the state of the group now
+func (p *Privileges) GroupState(group model.Group) (State, error) {
+	state, err := p.windowState(group.StartDate, group.EndDate)
+	if err != nil {
+		return "", errors.Wrapf(err, "group(%s)", group.ID)
+	}
+	return state, nil
+}
+
+// isGroupEntitled tells if the group grants the premium privilege to the member. A group grants the privilege in its validity window, and only to as many members as its seats in the order they joined, or in the order of their IDs if the member service doesn't have the time members joined. Only if the group has more members than its seats, the members holding the seats are queried from the member service.
+func (p *Privileges) isGroupEntitled(ctx context.Context, memberID string, group *model.Group) (bool, error) {
+	if group == nil {
+		return false, nil
+	}
+	state, err := p.GroupState(*group)
+	if err != nil || state != StateActive {
+		return false, err
+	}
+	seats := p.Seats(group.ID)
+	if seats <= 0 || (group.MemberCount != nil && *group.MemberCount <= seats) {
+		return true, nil
+	}
+
+	// members take the seats in the order they joined the group if the member service has the time, and the ID breaks ties
+	req := graphql.NewRequest(`query ($id: ID!, $seats: Int!) {
+  group(where: {id: $id}) {
+    member(orderBy: ` + p.seatOrder + `, first: $seats) {
+      id
+    }
+  }
+}`)
+	req.Var("id", group.ID)
+	req.Var("seats", seats)
+	var resp struct {
+		Group *model.Group `json:"group"`
+	}
+	if err = p.client.Run(ctx, req, &resp); err != nil {
+		return false, errors.Wrapf(err, "querying the seats of group(%s) encountered error", group.ID)
+	} else if resp.Group == nil {
+		return false, fmt.Errorf("group(%s) is not found", group.ID)
+	}
+	for _, m := range resp.Group.Member {
+		if m != nil && m.ID == memberID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,14 @@
+package entitlement
+
+import (
+	"github.com/mirror-media/apigateway/graph/member/model"
+)
+
+// isMarketingEntitled tells if the marketing membership is active and in its validity window
+func (p *Privileges) isMarketingEntitled(membership *model.MarketingMembership) (bool, error) {
+	if membership == nil || membership.Status == nil || *membership.Status != model.MarketingMembershipStatusTypeActive {
+		return false, nil
+	}
+	state, err := p.windowState(membership.StartDate, membership.EndDate)
+	return state == StateActive, err
+}
@@ -0,0 +1,184 @@
+package entitlement
+
+import (
+	"context"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/mirror-media/apigateway/trial"
+	"github.com/pkg/errors"
+)
+
+// State is the state of a group or a marketing membership by its validity window
+type State string
+
+const (
+	StatePending State = "pending"
+	StateActive  State = "active"
+	StateExpired State = "expired"
+)
+
+// memberFields are the fields of a member required by HasPremiumPrivilege in every member service
+const memberFields = `id
+    type
+    state
+    group { id startDate endDate memberCount }
+    marketingMembership { status startDate endDate }`
+
+// trialFields are the fields of the trials of a member, which require trialEndDatetime of subscription
+const trialFields = `
+    recurringSubscription: subscription(where: {frequency_in: [monthly, yearly], status_not: paying}, orderBy: [{createdAt: desc}], first: 1) { id status trialEndDatetime periodLastSuccessDatetime }`
+
+// giftFields are the fields of the gifts redeemed by a member, which require redeemedGift of member
+const giftFields = `
+    redeemedGift(where: {status: paid}) { id periodFirstDatetime periodEndDatetime }`
+
+// seatOrder is the order in which members take the seats of a group, and the ID breaks ties. joinedSeatOrder requires groupJoinedDatetime of member.
+const (
+	seatOrder       = `[{id: asc}]`
+	joinedSeatOrder = `[{groupJoinedDatetime: asc}, {id: asc}]`
+)
+
+// Member is a member queried with the MemberFields of Privileges
+type Member struct {
+	model.Member
+	// RecurringSubscription has the latest recurring subscription which has been paid or failed
+	RecurringSubscription []*model.Subscription `json:"recurringSubscription"`
+}
+
+// Privileges decides the premium privilege of members by their types, groups, marketing memberships and gifts
+type Privileges struct {
+	client       *graphql.Client
+	memberFields string
+	seatOrder    string
+	seats        map[string]int
+	defaultSeats int
+	location     *time.Location
+	now          func() time.Time
+}
+
+// NewPrivileges queries the ranks of members with client when a group is over its seats. The trials and the gifts of members are only queried if schema has their fields, and so is the order members joined their groups.
+func NewPrivileges(client *graphql.Client, c config.GroupSubscription, schema config.MemberSchema) (*Privileges, error) {
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load the location for privileges")
+	}
+	memberFields := memberFields
+	if schema.HasTrialEndDatetime {
+		memberFields += trialFields
+	}
+	if schema.HasRedeemedGift {
+		memberFields += giftFields
+	}
+	order := seatOrder
+	if schema.HasGroupJoinedDatetime {
+		order = joinedSeatOrder
+	}
+	return &Privileges{
+		client:       client,
+		memberFields: memberFields,
+		seatOrder:    order,
+		seats:        c.Seats,
+		defaultSeats: c.DefaultSeats,
+		location:     tz,
+		now:          time.Now,
+	}, nil
+}
+
+// MemberFields are the fields of a member required by HasPremiumPrivilege
+func (p *Privileges) MemberFields() string {
+	return p.memberFields
+}
+
+// parseDate parses a date time, or a date in Taipei. A date ends at the end of the day if isEnd is true.
+func (p *Privileges) parseDate(s string, isEnd bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, p.location)
+	if err != nil {
+		return t, err
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// windowState tells the state now by the start and end dates. Missing dates mean the window is unbounded on that side.
+func (p *Privileges) windowState(startDate, endDate *string) (State, error) {
+	now := p.now()
+	if startDate != nil && *startDate != "" {
+		start, err := p.parseDate(*startDate, false)
+		if err != nil {
+			return "", errors.Wrap(err, "invalid startDate")
+		}
+		if now.Before(start) {
+			return StatePending, nil
+		}
+	}
+	if endDate != nil && *endDate != "" {
+		end, err := p.parseDate(*endDate, true)
+		if err != nil {
+			return "", errors.Wrap(err, "invalid endDate")
+		}
+		if now.After(end) {
+			return StateExpired, nil
+		}
+	}
+	return StateActive, nil
+}
+
+// HasPremiumPrivilege tells if the member queried with MemberFields has premium privilege. The type of the member may be stale, so a marketing member is checked against the marketing membership, and a valid marketing membership grants the privilege whatever the type is, and so does a redeemed gift in its period. Likewise, a trialing member has the privilege, but a recurring member whose trial has expired doesn't.
+func (p *Privileges) HasPremiumPrivilege(ctx context.Context, member *Member) (bool, error) {
+	if member == nil || member.State == nil || *member.State != model.MemberStateTypeActive || member.Type == nil {
+		return false, nil
+	}
+
+	isTrialExpired := false
+	for _, s := range member.RecurringSubscription {
+		state, isTrial, err := trial.StateOf(s, p.now())
+		if err != nil {
+			return false, err
+		} else if !isTrial {
+			continue
+		}
+		switch state {
+		case trial.StateTrialing:
+			return true, nil
+		case trial.StateExpired:
+			isTrialExpired = true
+		}
+	}
+
+	switch *member.Type {
+	case model.MemberTypeTypeNone, model.MemberTypeTypeSubscribeOneTime, model.MemberTypeTypeMarketing:
+	case model.MemberTypeTypeSubscribeGroup:
+		// the privilege of a group member depends on the window and the seats of the group
+		isGroupEntitled, err := p.isGroupEntitled(ctx, member.ID, member.Group)
+		if err != nil || isGroupEntitled {
+			return isGroupEntitled, err
+		}
+	case model.MemberTypeTypeSubscribeMonthly, model.MemberTypeTypeSubscribeYearly:
+		if !isTrialExpired {
+			return true, nil
+		}
+	default:
+		return true, nil
+	}
+
+	isMarketingEntitled, err := p.isMarketingEntitled(member.MarketingMembership)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking marketing membership of member(%s) encountered error", member.ID)
+	} else if isMarketingEntitled {
+		return true, nil
+	}
+
+	isGiftEntitled, err := p.isGiftEntitled(member.RedeemedGift)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking gifts of member(%s) encountered error", member.ID)
+	}
+	return isGiftEntitled, nil
+}
@@ -0,0 +1,207 @@
+package entitlement
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/graph/member/model"
+)
+
+func newTestPrivileges(t *testing.T, client *graphql.Client) *Privileges {
+	p, err := NewPrivileges(client, config.GroupSubscription{
+		DefaultSeats: 2,
+		Seats:        map[string]int{"unlimited": 0},
+	}, config.MemberSchema{HasTrialEndDatetime: true, HasRedeemedGift: true, HasGroupJoinedDatetime: true})
+	if err != nil {
+		t.Fatalf("NewPrivileges() error = %v", err)
+	}
+	p.now = func() time.Time { return time.Date(2021, 11, 30, 17, 0, 0, 0, time.UTC) } // 2021-12-01 01:00 in Taipei
+	return p
+}
+
+func stringPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func TestNewPrivileges_MemberFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		schema       config.MemberSchema
+		wantTrialEnd bool
+		wantRedeemed bool
+	}{
+		{name: "not migrated"},
+		{name: "trials", schema: config.MemberSchema{HasTrialEndDatetime: true}, wantTrialEnd: true},
+		{name: "trials and gifts", schema: config.MemberSchema{HasTrialEndDatetime: true, HasRedeemedGift: true}, wantTrialEnd: true, wantRedeemed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPrivileges(nil, config.GroupSubscription{}, tt.schema)
+			if err != nil {
+				t.Fatalf("NewPrivileges() error = %v", err)
+			}
+			got := p.MemberFields()
+			if strings.Contains(got, "trialEndDatetime") != tt.wantTrialEnd || strings.Contains(got, "redeemedGift") != tt.wantRedeemed {
+				t.Errorf("MemberFields() = %s, want trialEndDatetime %v and redeemedGift %v", got, tt.wantTrialEnd, tt.wantRedeemed)
+			}
+		})
+	}
+}
+
+func TestPrivileges_isGroupEntitled_seatOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		schema     config.MemberSchema
+		wantJoined bool
+	}{
+		{name: "not migrated"},
+		{name: "joined time", schema: config.MemberSchema{HasGroupJoinedDatetime: true}, wantJoined: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Query string `json:"query"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				query = req.Query
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"group": model.Group{ID: "1", Member: []*model.Member{{ID: "1"}}}}})
+			}))
+			defer server.Close()
+			p, err := NewPrivileges(graphql.NewClient(server.URL), config.GroupSubscription{DefaultSeats: 1}, tt.schema)
+			if err != nil {
+				t.Fatalf("NewPrivileges() error = %v", err)
+			}
+
+			got, err := p.isGroupEntitled(context.Background(), "1", &model.Group{ID: "1", MemberCount: intPtr(2)})
+			if err != nil || !got {
+				t.Fatalf("isGroupEntitled() = %v, %v, want true", got, err)
+			}
+			if strings.Contains(query, "groupJoinedDatetime") != tt.wantJoined {
+				t.Errorf("seats are queried by %s, want groupJoinedDatetime %v", query, tt.wantJoined)
+			}
+		})
+	}
+}
+
+func TestPrivileges_GroupState(t *testing.T) {
+	p := newTestPrivileges(t, nil)
+
+	tests := []struct {
+		name    string
+		group   model.Group
+		want    State
+		wantErr bool
+	}{
+		{name: "unbounded", group: model.Group{ID: "1"}, want: StateActive},
+		{name: "in the window", group: model.Group{ID: "1", StartDate: stringPtr("2021-11-01"), EndDate: stringPtr("2021-12-31")}, want: StateActive},
+		{name: "starts today in Taipei", group: model.Group{ID: "1", StartDate: stringPtr("2021-12-01")}, want: StateActive},
+		{name: "not started", group: model.Group{ID: "1", StartDate: stringPtr("2021-12-01T02:00:00+08:00")}, want: StatePending},
+		{name: "ended yesterday in Taipei", group: model.Group{ID: "1", EndDate: stringPtr("2021-11-30")}, want: StateExpired},
+		{name: "invalid date", group: model.Group{ID: "1", EndDate: stringPtr("next year")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.GroupState(tt.group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GroupState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GroupState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivileges_HasPremiumPrivilege(t *testing.T) {
+	// members of the group joined in the order of 3, 1 and 2, which differs from the order of their IDs
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables struct {
+				Seats int `json:"seats"`
+			} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		members := []*model.Member{{ID: "3"}, {ID: "1"}, {ID: "2"}}
+		if req.Variables.Seats < len(members) {
+			members = members[:req.Variables.Seats]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"group": model.Group{ID: "1", Member: members}}})
+	}))
+	defer server.Close()
+	p := newTestPrivileges(t, graphql.NewClient(server.URL))
+
+	member := func(id string, memberType model.MemberTypeType, group *model.Group, marketing *model.MarketingMembership) *Member {
+		state := model.MemberStateTypeActive
+		return &Member{Member: model.Member{ID: id, Type: &memberType, State: &state, Group: group, MarketingMembership: marketing}}
+	}
+	// withTrial gives the member a recurring subscription with a trial ending at trialEnd
+	withTrial := func(m *Member, status model.SubscriptionStatusType, trialEnd string, lastSuccess *string) *Member {
+		m.RecurringSubscription = []*model.Subscription{{ID: "1", Status: &status, TrialEndDatetime: &trialEnd, PeriodLastSuccessDatetime: lastSuccess}}
+		return m
+	}
+	marketing := func(status model.MarketingMembershipStatusType, endDate string) *model.MarketingMembership {
+		return &model.MarketingMembership{Status: &status, StartDate: stringPtr("2021-11-01"), EndDate: &endDate}
+	}
+	// withGift gives the member a redeemed gift in the period
+	withGift := func(m *Member, start, end string) *Member {
+		m.RedeemedGift = []*model.Subscription{{ID: "1", PeriodFirstDatetime: &start, PeriodEndDatetime: &end}}
+		return m
+	}
+	inactive := model.MemberStateTypeInactive
+	yearly := model.MemberTypeTypeSubscribeYearly
+
+	tests := []struct {
+		name    string
+		member  *Member
+		want    bool
+		wantErr bool
+	}{
+		{name: "no member"},
+		{name: "inactive member", member: &Member{Member: model.Member{ID: "1", Type: &yearly, State: &inactive}}},
+		{name: "yearly", member: member("1", model.MemberTypeTypeSubscribeYearly, nil, nil), want: true},
+		{name: "one time", member: member("1", model.MemberTypeTypeSubscribeOneTime, nil, nil)},
+		{name: "group without group", member: member("1", model.MemberTypeTypeSubscribeGroup, nil, nil)},
+		{name: "group with a vacant seat", member: member("1", model.MemberTypeTypeSubscribeGroup, &model.Group{ID: "1", MemberCount: intPtr(2)}, nil), want: true},
+		{name: "expired group", member: member("1", model.MemberTypeTypeSubscribeGroup, &model.Group{ID: "1", EndDate: stringPtr("2021-11-30"), MemberCount: intPtr(1)}, nil)},
+		{name: "seated in an overfilled group", member: member("3", model.MemberTypeTypeSubscribeGroup, &model.Group{ID: "1", MemberCount: intPtr(3)}, nil), want: true},
+		{name: "beyond the seats", member: member("2", model.MemberTypeTypeSubscribeGroup, &model.Group{ID: "1", MemberCount: intPtr(3)}, nil)},
+		{name: "unlimited seats", member: member("3", model.MemberTypeTypeSubscribeGroup, &model.Group{ID: "unlimited", MemberCount: intPtr(3)}, nil), want: true},
+		{name: "marketing", member: member("1", model.MemberTypeTypeMarketing, nil, marketing(model.MarketingMembershipStatusTypeActive, "2021-12-31")), want: true},
+		{name: "expired marketing of a stale type", member: member("1", model.MemberTypeTypeMarketing, nil, marketing(model.MarketingMembershipStatusTypeActive, "2021-11-30"))},
+		{name: "inactive marketing", member: member("1", model.MemberTypeTypeMarketing, nil, marketing(model.MarketingMembershipStatusTypeInactive, "2021-12-31"))},
+		{name: "marketing without membership", member: member("1", model.MemberTypeTypeMarketing, nil, nil)},
+		{name: "marketing of a stale type", member: member("1", model.MemberTypeTypeNone, nil, marketing(model.MarketingMembershipStatusTypeActive, "2021-12-31")), want: true},
+		{name: "marketing with invalid dates", member: member("1", model.MemberTypeTypeMarketing, nil, marketing(model.MarketingMembershipStatusTypeActive, "soon")), wantErr: true},
+		{name: "yearly with invalid marketing dates", member: member("1", model.MemberTypeTypeSubscribeYearly, nil, marketing(model.MarketingMembershipStatusTypeActive, "soon")), want: true},
+		{name: "trialing of a stale type", member: withTrial(member("1", model.MemberTypeTypeNone, nil, nil), model.SubscriptionStatusTypePaid, "2021-12-14T00:00:00+08:00", nil), want: true},
+		{name: "trial with failed authorisation", member: withTrial(member("1", model.MemberTypeTypeSubscribeMonthly, nil, nil), model.SubscriptionStatusTypeFail, "2021-12-14T00:00:00+08:00", nil)},
+		{name: "converted trial", member: withTrial(member("1", model.MemberTypeTypeSubscribeMonthly, nil, nil), model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00+08:00", stringPtr("2021-11-30T00:10:00+08:00")), want: true},
+		{name: "expired trial", member: withTrial(member("1", model.MemberTypeTypeSubscribeMonthly, nil, nil), model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00+08:00", nil)},
+		{name: "expired trial with marketing", member: withTrial(member("1", model.MemberTypeTypeSubscribeMonthly, nil, marketing(model.MarketingMembershipStatusTypeActive, "2021-12-31")), model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00+08:00", nil), want: true},
+		{name: "redeemed gift", member: withGift(member("1", model.MemberTypeTypeNone, nil, nil), "2021-11-01T00:00:00+08:00", "2022-11-01T00:00:00+08:00"), want: true},
+		{name: "ended gift", member: withGift(member("1", model.MemberTypeTypeNone, nil, nil), "2020-11-01T00:00:00+08:00", "2021-11-01T00:00:00+08:00")},
+		{name: "gift without period", member: withGift(member("1", model.MemberTypeTypeNone, nil, nil), "", "")},
+		{name: "gift with invalid period", member: withGift(member("1", model.MemberTypeTypeNone, nil, nil), "", "next year"), wantErr: true},
+		{name: "trial with invalid end", member: withTrial(member("1", model.MemberTypeTypeSubscribeMonthly, nil, nil), model.SubscriptionStatusTypePaid, "soon", nil), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.HasPremiumPrivilege(context.Background(), tt.member)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HasPremiumPrivilege() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HasPremiumPrivilege() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
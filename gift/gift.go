@@ -0,0 +1,97 @@
+// Package gift generates the codes of gift subscriptions and guards their redemption
+package gift
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/pkg/errors"
+)
+
+// alphabet is Crockford's base32 without the letters easily mistaken for digits
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// codeLength is the number of characters of a code without the dashes. 16 characters of base32 are 80 bits.
+const codeLength = 16
+
+// claimTTL is how long a claim guards a redemption in progress. The redeemed gift is marked in the member service afterwards.
+const claimTTL = 10 * time.Minute
+
+// NewCode generates a random code like ABCD-EFGH-JKMN-PQRS
+func NewCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < codeLength; i++ {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.Wrap(err, "cannot generate a gift code")
+		}
+		b.WriteByte(alphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// NormalizeCode formats the code typed by a reader. Cases, spaces and dashes are ignored, and letters mistaken for digits are read as the digits.
+func NormalizeCode(code string) (string, error) {
+	replacer := strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1")
+	code = replacer.Replace(strings.ToUpper(code))
+	if len(code) != codeLength {
+		return "", fmt.Errorf("gift code should have %d characters", codeLength)
+	}
+
+	var b strings.Builder
+	for i, c := range code {
+		if !strings.ContainsRune(alphabet, c) {
+			return "", fmt.Errorf("gift code has an invalid character(%c)", c)
+		}
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), nil
+}
+
+// Period returns the end of the privilege of a gift redeemed at start
+func Period(start time.Time) (end time.Time) {
+	return start.AddDate(1, 0, 0)
+}
+
+// Claims prevents a gift code from being redeemed by two members at the same time
+type Claims struct {
+	rdb cache.Rediser
+}
+
+// NewClaims keeps the claims in rdb, bypassing its local cache
+func NewClaims(rdb cache.Rediser) *Claims {
+	return &Claims{rdb: cache.Unwrap(rdb)}
+}
+
+func claimKey(code string) string {
+	return fmt.Sprintf("%s.%s.%s", "apigateway", "gift", code)
+}
+
+// Claim reserves the code for the member. isClaimed is false if the code is being redeemed by someone else.
+func (c *Claims) Claim(ctx context.Context, code, firebaseID string) (isClaimed bool, err error) {
+	isClaimed, err = c.rdb.SetNX(ctx, claimKey(code), firebaseID, claimTTL).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot claim gift(%s)", code)
+	}
+	return isClaimed, nil
+}
+
+// Release gives back the code, e.g., when the redemption fails
+func (c *Claims) Release(ctx context.Context, code string) error {
+	if err := c.rdb.Del(ctx, claimKey(code)).Err(); err != nil {
+		return errors.Wrapf(err, "cannot release gift(%s)", code)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package gift
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+)
+
+// claimRediser implements SetNX and Del of cache.Rediser in memory. Expiration is ignored.
+type claimRediser struct {
+	cache.Rediser
+	values map[string]interface{}
+}
+
+func (c *claimRediser) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd {
+	if _, ok := c.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	c.values[key] = value
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *claimRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	var n int64
+	for _, key := range keys {
+		if _, ok := c.values[key]; ok {
+			delete(c.values, key)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func TestNewCode(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{4}(-[0-9A-HJKMNP-TV-Z]{4}){3}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := NewCode()
+		if err != nil {
+			t.Fatalf("NewCode() error = %v", err)
+		}
+		if !format.MatchString(code) {
+			t.Errorf("NewCode() = %s, which is not formatted", code)
+		}
+		if normalized, err := NormalizeCode(code); err != nil || normalized != code {
+			t.Errorf("NormalizeCode(%s) = %s, %v", code, normalized, err)
+		}
+		if seen[code] {
+			t.Errorf("NewCode() = %s, which is generated again", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{name: "formatted", code: "ABCD-EFGH-JKMN-PQRS", want: "ABCD-EFGH-JKMN-PQRS"},
+		{name: "lower case without dashes", code: "abcdefghjkmnpqrs", want: "ABCD-EFGH-JKMN-PQRS"},
+		{name: "spaces", code: " ABCD EFGH JKMN PQRS ", want: "ABCD-EFGH-JKMN-PQRS"},
+		{name: "mistaken letters", code: "OOOO-IIII-LLLL-0000", want: "0000-1111-1111-0000"},
+		{name: "too short", code: "ABCD-EFGH", wantErr: true},
+		{name: "invalid character", code: "ABCD-EFGH-JKMN-PQRU", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeCode(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaims_Claim(t *testing.T) {
+	c := NewClaims(&claimRediser{values: map[string]interface{}{}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		code       string
+		firebaseID string
+		do         func()
+		want       bool
+	}{
+		{name: "first", code: "ABCD-EFGH-JKMN-PQRS", firebaseID: "a", want: true},
+		{name: "being redeemed", code: "ABCD-EFGH-JKMN-PQRS", firebaseID: "b"},
+		{name: "another code", code: "0000-0000-0000-0000", firebaseID: "b", want: true},
+		{
+			name:       "released",
+			code:       "ABCD-EFGH-JKMN-PQRS",
+			firebaseID: "b",
+			do: func() {
+				_ = c.Release(ctx, "ABCD-EFGH-JKMN-PQRS")
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.do != nil {
+				tt.do()
+			}
+			got, err := c.Claim(ctx, tt.code, tt.firebaseID)
+			if err != nil {
+				t.Fatalf("Claim() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Claim() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
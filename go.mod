@@ -9,6 +9,7 @@ require (
 	firebase.google.com/go/v4 v4.6.0
 	github.com/99designs/gqlgen v0.14.0
 	github.com/Masterminds/goutils v1.1.1 // indirect
+	github.com/andybalholm/brotli v1.0.4
 	github.com/bcgodev/logrus-formatter-gke v1.0.0
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/eclipse/paho.mqtt.golang v1.3.5 // indirect
@@ -56,6 +57,7 @@ require (
 	go.uber.org/zap v1.19.1 // indirect
 	golang.org/x/crypto v0.0.0-20210915214749-c084706c2272 // indirect
 	golang.org/x/net v0.0.0-20210924151903-3ad01bbaa167 // indirect
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
 	golang.org/x/sys v0.0.0-20210927052749-1cf2251ac284 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324 // indirect
@@ -305,6 +305,18 @@ type AppStorePaymentWhereUniqueInput struct {
 	TransactionID *string `json:"transactionId"`
 }
 
+type CheckoutForm struct {
+	PaymentMethod SubscriptionPaymentMethodType `json:"paymentMethod"`
+	// The URL to POST the fields to
+	Action string               `json:"action"`
+	Fields []*CheckoutFormField `json:"fields"`
+}
+
+type CheckoutFormField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 type GooglePlayPayment struct {
 	ID                  string                         `json:"id"`
 	Subscription        *Subscription                  `json:"subscription"`
@@ -445,6 +457,15 @@ type Group struct {
 	UpdatedAt      *string   `json:"updatedAt"`
 }
 
+type GroupMembership struct {
+	GroupID  string `json:"groupId"`
+	MemberID string `json:"memberId"`
+	// The number of members in the group after the mutation
+	MemberCount int `json:"memberCount"`
+	// The number of seats of the group. It's null if the seats are unlimited.
+	Seats *int `json:"seats"`
+}
+
 type GroupOrderByInput struct {
 	ID             *OrderDirection `json:"id"`
 	Company        *OrderDirection `json:"company"`
@@ -459,6 +480,12 @@ type GroupOrderByInput struct {
 	UpdatedAt      *OrderDirection `json:"updatedAt"`
 }
 
+type GroupRelateToOneInput struct {
+	Connect       *GroupWhereUniqueInput `json:"connect"`
+	Disconnect    *GroupWhereUniqueInput `json:"disconnect"`
+	DisconnectAll *bool                  `json:"disconnectAll"`
+}
+
 type GroupWhereInput struct {
 	And                          []*GroupWhereInput `json:"AND"`
 	Or                           []*GroupWhereInput `json:"OR"`
@@ -1020,26 +1047,31 @@ type Member struct {
 	Email               *string              `json:"email"`
 	MarketingMembership *MarketingMembership `json:"marketingMembership"`
 	Group               *Group               `json:"group"`
-	Type                *MemberTypeType      `json:"type"`
-	State               *MemberStateType     `json:"state"`
-	Tos                 *bool                `json:"tos"`
-	DateJoined          *string              `json:"dateJoined"`
-	FirstName           *string              `json:"firstName"`
-	LastName            *string              `json:"lastName"`
-	Name                *string              `json:"name"`
-	Gender              *MemberGenderType    `json:"gender"`
-	Phone               *string              `json:"phone"`
-	Birthday            *string              `json:"birthday"`
-	Address             *string              `json:"address"`
-	Nickname            *string              `json:"nickname"`
-	ProfileImage        *string              `json:"profileImage"`
-	City                *string              `json:"city"`
-	Country             *string              `json:"country"`
-	District            *string              `json:"district"`
-	Subscription        []*Subscription      `json:"subscription"`
-	SubscriptionCount   *int                 `json:"subscriptionCount"`
-	CreatedAt           *string              `json:"createdAt"`
-	UpdatedAt           *string              `json:"updatedAt"`
+	// When the member joined the group. Members take the seats of the group in this order.
+	GroupJoinedDatetime *string           `json:"groupJoinedDatetime"`
+	Type                *MemberTypeType   `json:"type"`
+	State               *MemberStateType  `json:"state"`
+	Tos                 *bool             `json:"tos"`
+	DateJoined          *string           `json:"dateJoined"`
+	FirstName           *string           `json:"firstName"`
+	LastName            *string           `json:"lastName"`
+	Name                *string           `json:"name"`
+	Gender              *MemberGenderType `json:"gender"`
+	Phone               *string           `json:"phone"`
+	Birthday            *string           `json:"birthday"`
+	Address             *string           `json:"address"`
+	Nickname            *string           `json:"nickname"`
+	ProfileImage        *string           `json:"profileImage"`
+	City                *string           `json:"city"`
+	Country             *string           `json:"country"`
+	District            *string           `json:"district"`
+	Subscription        []*Subscription   `json:"subscription"`
+	SubscriptionCount   *int              `json:"subscriptionCount"`
+	// The gift subscriptions redeemed by the member
+	RedeemedGift      []*Subscription `json:"redeemedGift"`
+	RedeemedGiftCount *int            `json:"redeemedGiftCount"`
+	CreatedAt         *string         `json:"createdAt"`
+	UpdatedAt         *string         `json:"updatedAt"`
 }
 
 // Nested fields are removed
@@ -1068,27 +1100,28 @@ type MemberInfo struct {
 }
 
 type MemberOrderByInput struct {
-	ID           *OrderDirection `json:"id"`
-	FirebaseID   *OrderDirection `json:"firebaseId"`
-	Email        *OrderDirection `json:"email"`
-	Type         *OrderDirection `json:"type"`
-	State        *OrderDirection `json:"state"`
-	Tos          *OrderDirection `json:"tos"`
-	DateJoined   *OrderDirection `json:"dateJoined"`
-	FirstName    *OrderDirection `json:"firstName"`
-	LastName     *OrderDirection `json:"lastName"`
-	Name         *OrderDirection `json:"name"`
-	Gender       *OrderDirection `json:"gender"`
-	Phone        *OrderDirection `json:"phone"`
-	Birthday     *OrderDirection `json:"birthday"`
-	Address      *OrderDirection `json:"address"`
-	Nickname     *OrderDirection `json:"nickname"`
-	ProfileImage *OrderDirection `json:"profileImage"`
-	City         *OrderDirection `json:"city"`
-	Country      *OrderDirection `json:"country"`
-	District     *OrderDirection `json:"district"`
-	CreatedAt    *OrderDirection `json:"createdAt"`
-	UpdatedAt    *OrderDirection `json:"updatedAt"`
+	ID                  *OrderDirection `json:"id"`
+	FirebaseID          *OrderDirection `json:"firebaseId"`
+	Email               *OrderDirection `json:"email"`
+	Type                *OrderDirection `json:"type"`
+	State               *OrderDirection `json:"state"`
+	Tos                 *OrderDirection `json:"tos"`
+	DateJoined          *OrderDirection `json:"dateJoined"`
+	FirstName           *OrderDirection `json:"firstName"`
+	LastName            *OrderDirection `json:"lastName"`
+	Name                *OrderDirection `json:"name"`
+	Gender              *OrderDirection `json:"gender"`
+	Phone               *OrderDirection `json:"phone"`
+	Birthday            *OrderDirection `json:"birthday"`
+	Address             *OrderDirection `json:"address"`
+	Nickname            *OrderDirection `json:"nickname"`
+	ProfileImage        *OrderDirection `json:"profileImage"`
+	City                *OrderDirection `json:"city"`
+	Country             *OrderDirection `json:"country"`
+	District            *OrderDirection `json:"district"`
+	CreatedAt           *OrderDirection `json:"createdAt"`
+	UpdatedAt           *OrderDirection `json:"updatedAt"`
+	GroupJoinedDatetime *OrderDirection `json:"groupJoinedDatetime"`
 }
 
 type MemberPrivateCreateInput struct {
@@ -1120,25 +1153,28 @@ type MemberPrivateUpdateInput struct {
 	FirebaseID          *string                              `json:"firebaseId"`
 	Email               *string                              `json:"email"`
 	MarketingMembership *MarketingMembershipRelateToOneInput `json:"marketingMembership"`
-	Type                *MemberTypeType                      `json:"type"`
-	State               *MemberStateType                     `json:"state"`
-	Tos                 *bool                                `json:"tos"`
-	DateJoined          *string                              `json:"dateJoined"`
-	FirstName           *string                              `json:"firstName"`
-	LastName            *string                              `json:"lastName"`
-	Name                *string                              `json:"name"`
-	Gender              *MemberGenderType                    `json:"gender"`
-	Phone               *string                              `json:"phone"`
-	Birthday            *string                              `json:"birthday"`
-	Address             *string                              `json:"address"`
-	Nickname            *string                              `json:"nickname"`
-	ProfileImage        *string                              `json:"profileImage"`
-	City                *string                              `json:"city"`
-	Country             *string                              `json:"country"`
-	District            *string                              `json:"district"`
-	Subscription        *SubscriptionRelateToManyInput       `json:"subscription"`
-	CreatedAt           *string                              `json:"createdAt"`
-	UpdatedAt           *string                              `json:"updatedAt"`
+	Group               *GroupRelateToOneInput               `json:"group"`
+	// When the member joined the group. It's only written if MemberSchema::HasGroupJoinedDatetime is true.
+	GroupJoinedDatetime *string                        `json:"groupJoinedDatetime"`
+	Type                *MemberTypeType                `json:"type"`
+	State               *MemberStateType               `json:"state"`
+	Tos                 *bool                          `json:"tos"`
+	DateJoined          *string                        `json:"dateJoined"`
+	FirstName           *string                        `json:"firstName"`
+	LastName            *string                        `json:"lastName"`
+	Name                *string                        `json:"name"`
+	Gender              *MemberGenderType              `json:"gender"`
+	Phone               *string                        `json:"phone"`
+	Birthday            *string                        `json:"birthday"`
+	Address             *string                        `json:"address"`
+	Nickname            *string                        `json:"nickname"`
+	ProfileImage        *string                        `json:"profileImage"`
+	City                *string                        `json:"city"`
+	Country             *string                        `json:"country"`
+	District            *string                        `json:"district"`
+	Subscription        *SubscriptionRelateToManyInput `json:"subscription"`
+	CreatedAt           *string                        `json:"createdAt"`
+	UpdatedAt           *string                        `json:"updatedAt"`
 }
 
 type MemberRelateToOneInput struct {
@@ -1157,16 +1193,20 @@ type MemberWhereUniqueInput struct {
 }
 
 type Merchandise struct {
-	ID        string                   `json:"id"`
-	Name      *string                  `json:"name"`
-	Code      *string                  `json:"code"`
-	Price     *float64                 `json:"price"`
-	Currency  *MerchandiseCurrencyType `json:"currency"`
-	State     *MerchandiseStateType    `json:"state"`
-	Desc      *string                  `json:"desc"`
-	Comment   *string                  `json:"comment"`
-	CreatedAt *string                  `json:"createdAt"`
-	UpdatedAt *string                  `json:"updatedAt"`
+	ID    string   `json:"id"`
+	Name  *string  `json:"name"`
+	Code  *string  `json:"code"`
+	Price *float64 `json:"price"`
+	// The days of the free trial of a recurring plan, which are 7, 14 or 30. There's no trial if it's null or 0.
+	TrialDays *int `json:"trialDays"`
+	// The payment provider of the merchandise. The default provider is used if it's null.
+	PaymentMethod *SubscriptionPaymentMethodType `json:"paymentMethod"`
+	Currency      *MerchandiseCurrencyType       `json:"currency"`
+	State         *MerchandiseStateType          `json:"state"`
+	Desc          *string                        `json:"desc"`
+	Comment       *string                        `json:"comment"`
+	CreatedAt     *string                        `json:"createdAt"`
+	UpdatedAt     *string                        `json:"updatedAt"`
 }
 
 type MerchandiseCreateInput struct {
@@ -1338,6 +1378,14 @@ type MerchandiseWhereUniqueInput struct {
 	Code *string `json:"code"`
 }
 
+type NewebpayForm struct {
+	MerchantID string `json:"merchantId"`
+	// The AES-256-CBC encrypted TradeInfo
+	TradeInfo string `json:"tradeInfo"`
+	TradeSha  string `json:"tradeSha"`
+	Version   string `json:"version"`
+}
+
 type NewebpayPayment struct {
 	ID               string                        `json:"id"`
 	Subscription     *Subscription                 `json:"subscription"`
@@ -1359,6 +1407,9 @@ type NewebpayPayment struct {
 	CardInfoFirstSix *string                       `json:"cardInfoFirstSix"`
 	CardInfoExp      *string                       `json:"cardInfoExp"`
 	Frequency        *NewebpayPaymentFrequencyType `json:"frequency"`
+	RefundAmount     *int                          `json:"refundAmount"`
+	RefundStatus     *string                       `json:"refundStatus"`
+	RefundTime       *string                       `json:"refundTime"`
 	CreatedAt        *string                       `json:"createdAt"`
 	UpdatedAt        *string                       `json:"updatedAt"`
 }
@@ -1569,6 +1620,9 @@ type NewebpayPaymentUpdateInput struct {
 	CardInfoFirstSix *string                       `json:"cardInfoFirstSix"`
 	CardInfoExp      *string                       `json:"cardInfoExp"`
 	Frequency        *NewebpayPaymentFrequencyType `json:"frequency"`
+	RefundAmount     *int                          `json:"refundAmount"`
+	RefundStatus     *string                       `json:"refundStatus"`
+	RefundTime       *string                       `json:"refundTime"`
 	CreatedAt        *string                       `json:"createdAt"`
 	UpdatedAt        *string                       `json:"updatedAt"`
 }
@@ -1876,6 +1930,18 @@ type NewebpayPaymentWhereUniqueInput struct {
 	OrderNumber *string `json:"orderNumber"`
 }
 
+type PaymentRefund struct {
+	PaymentID      string                        `json:"paymentId"`
+	SubscriptionID string                        `json:"subscriptionId"`
+	PaymentMethod  SubscriptionPaymentMethodType `json:"paymentMethod"`
+	// The amount refunded by this mutation
+	Amount int `json:"amount"`
+	// The total amount refunded of the payment
+	RefundedAmount int `json:"refundedAmount"`
+	// It's true if the authorisation is cancelled before it's captured instead of being refunded
+	IsCancelled bool `json:"isCancelled"`
+}
+
 type Promotion struct {
 	ID        string              `json:"id"`
 	Code      *string             `json:"code"`
@@ -2007,6 +2073,15 @@ type PromotionWhereUniqueInput struct {
 	ID *string `json:"id"`
 }
 
+type ShareLink struct {
+	PostID string `json:"postId"`
+	// It should be sent as the **shareToken** query parameter or the **X-Share-Token** header to /api/v0
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+	// The number of posts the member can still share in this month. It's null if there is no quota.
+	RemainingQuota *int `json:"remainingQuota"`
+}
+
 type Subscription struct {
 	ID                                  string                            `json:"id"`
 	Member                              *Member                           `json:"member"`
@@ -2034,6 +2109,12 @@ type Subscription struct {
 	PeriodCreateDatetime                *string                           `json:"periodCreateDatetime"`
 	PeriodFirstDatetime                 *string                           `json:"periodFirstDatetime"`
 	PeriodEndDatetime                   *string                           `json:"periodEndDatetime"`
+	TrialEndDatetime                    *string                           `json:"trialEndDatetime"`
+	IsGift                              *bool                             `json:"isGift"`
+	GiftCode                            *string                           `json:"giftCode"`
+	GiftRecipientEmail                  *string                           `json:"giftRecipientEmail"`
+	GiftRedeemer                        *Member                           `json:"giftRedeemer"`
+	GiftRedeemedDatetime                *string                           `json:"giftRedeemedDatetime"`
 	ChangePlanDatetime                  *string                           `json:"changePlanDatetime"`
 	GooglePlayStatus                    *SubscriptionGooglePlayStatusType `json:"googlePlayStatus"`
 	GooglePlayPurchaseToken             *string                           `json:"googlePlayPurchaseToken"`
@@ -2090,6 +2171,12 @@ type SubscriptionCreateInput struct {
 	PeriodCreateDatetime                *string                              `json:"periodCreateDatetime"`
 	PeriodFirstDatetime                 *string                              `json:"periodFirstDatetime"`
 	PeriodEndDatetime                   *string                              `json:"periodEndDatetime"`
+	TrialEndDatetime                    *string                              `json:"trialEndDatetime"`
+	IsGift                              *bool                                `json:"isGift"`
+	GiftCode                            *string                              `json:"giftCode"`
+	GiftRecipientEmail                  *string                              `json:"giftRecipientEmail"`
+	GiftRedeemer                        *MemberRelateToOneInput              `json:"giftRedeemer"`
+	GiftRedeemedDatetime                *string                              `json:"giftRedeemedDatetime"`
 	ChangePlanDatetime                  *string                              `json:"changePlanDatetime"`
 	GooglePlayStatus                    *SubscriptionGooglePlayStatusType    `json:"googlePlayStatus"`
 	GooglePlayPurchaseToken             *string                              `json:"googlePlayPurchaseToken"`
@@ -2117,6 +2204,14 @@ type SubscriptionCreateInput struct {
 type SubscriptionCreation struct {
 	Subscription    *SubscriptionInfo `json:"subscription"`
 	NewebpayPayload *string           `json:"newebpayPayload"`
+	// The fields of the form to POST to NewebPay. It's null if the subscription is paid by another provider.
+	NewebpayForm *NewebpayForm `json:"newebpayForm"`
+	// The form to POST to the payment provider of the subscription
+	CheckoutForm *CheckoutForm `json:"checkoutForm"`
+}
+
+type SubscriptionGiftCreateInfo struct {
+	ReturnToPath string `json:"returnToPath"`
 }
 
 type SubscriptionHistory struct {
@@ -2506,6 +2601,11 @@ type SubscriptionInfo struct {
 	PeriodCreateDatetime      *string                        `json:"periodCreateDatetime"`
 	PeriodFirstDatetime       *string                        `json:"periodFirstDatetime"`
 	PeriodEndDatetime         *string                        `json:"periodEndDatetime"`
+	TrialEndDatetime          *string                        `json:"trialEndDatetime"`
+	IsGift                    *bool                          `json:"isGift"`
+	GiftCode                  *string                        `json:"giftCode"`
+	GiftRecipientEmail        *string                        `json:"giftRecipientEmail"`
+	GiftRedeemedDatetime      *string                        `json:"giftRedeemedDatetime"`
 	ChangePlanDatetime        *string                        `json:"changePlanDatetime"`
 	Note                      *string                        `json:"note"`
 	PromoteID                 *int                           `json:"promoteId"`
@@ -2525,6 +2625,8 @@ type SubscriptionOneTimeCreateInfo struct {
 	PostSlug     string `json:"postSlug"`
 	PostTitle    string `json:"postTitle"`
 	ReturnToPath string `json:"returnToPath"`
+	// It's the code of an active promotion. The discount of the promotion is taken off the amount.
+	PromotionCode *string `json:"promotionCode"`
 }
 
 type SubscriptionOrderByInput struct {
@@ -2592,6 +2694,12 @@ type SubscriptionPrivateUpdateInput struct {
 	PeriodCreateDatetime                *string                              `json:"periodCreateDatetime"`
 	PeriodFirstDatetime                 *string                              `json:"periodFirstDatetime"`
 	PeriodEndDatetime                   *string                              `json:"periodEndDatetime"`
+	TrialEndDatetime                    *string                              `json:"trialEndDatetime"`
+	IsGift                              *bool                                `json:"isGift"`
+	GiftCode                            *string                              `json:"giftCode"`
+	GiftRecipientEmail                  *string                              `json:"giftRecipientEmail"`
+	GiftRedeemer                        *MemberRelateToOneInput              `json:"giftRedeemer"`
+	GiftRedeemedDatetime                *string                              `json:"giftRedeemedDatetime"`
 	ChangePlanDatetime                  *string                              `json:"changePlanDatetime"`
 	GooglePlayStatus                    *SubscriptionGooglePlayStatusType    `json:"googlePlayStatus"`
 	GooglePlayPurchaseToken             *string                              `json:"googlePlayPurchaseToken"`
@@ -2618,6 +2726,10 @@ type SubscriptionPrivateUpdateInput struct {
 
 type SubscriptionRecurringCreateInfo struct {
 	ReturnToPath string `json:"returnToPath"`
+	// It starts the free trial of the plan. A member can only have one free trial.
+	Trial *bool `json:"trial"`
+	// It's the code of an active promotion. The discount of the promotion is taken off the amount.
+	PromotionCode *string `json:"promotionCode"`
 }
 
 type SubscriptionRelateToManyInput struct {
@@ -2651,6 +2763,8 @@ type SubscriptionWhereInput struct {
 	IDNotIn                                  []string                            `json:"id_not_in"`
 	Member                                   *MemberWhereInput                   `json:"member"`
 	MemberIsNull                             *bool                               `json:"member_is_null"`
+	GiftRedeemer                             *MemberWhereInput                   `json:"giftRedeemer"`
+	GiftRedeemerIsNull                       *bool                               `json:"giftRedeemer_is_null"`
 	OrderNumber                              *string                             `json:"orderNumber"`
 	OrderNumberNot                           *string                             `json:"orderNumber_not"`
 	OrderNumberContains                      *string                             `json:"orderNumber_contains"`
@@ -2756,6 +2870,9 @@ type SubscriptionWhereInput struct {
 	IsActiveNot                              *bool                               `json:"isActive_not"`
 	IsCanceled                               *bool                               `json:"isCanceled"`
 	IsCanceledNot                            *bool                               `json:"isCanceled_not"`
+	IsGift                                   *bool                               `json:"isGift"`
+	IsGiftNot                                *bool                               `json:"isGift_not"`
+	GiftCode                                 *string                             `json:"giftCode"`
 	Frequency                                *SubscriptionFrequencyType          `json:"frequency"`
 	FrequencyNot                             *SubscriptionFrequencyType          `json:"frequency_not"`
 	FrequencyIn                              []*SubscriptionFrequencyType        `json:"frequency_in"`
@@ -5225,19 +5342,21 @@ type SubscriptionPaymentMethodType string
 
 const (
 	SubscriptionPaymentMethodTypeNewebpay   SubscriptionPaymentMethodType = "newebpay"
+	SubscriptionPaymentMethodTypeEcpay      SubscriptionPaymentMethodType = "ecpay"
 	SubscriptionPaymentMethodTypeAppStore   SubscriptionPaymentMethodType = "app_store"
 	SubscriptionPaymentMethodTypeGooglePlay SubscriptionPaymentMethodType = "google_play"
 )
 
 var AllSubscriptionPaymentMethodType = []SubscriptionPaymentMethodType{
 	SubscriptionPaymentMethodTypeNewebpay,
+	SubscriptionPaymentMethodTypeEcpay,
 	SubscriptionPaymentMethodTypeAppStore,
 	SubscriptionPaymentMethodTypeGooglePlay,
 }
 
 func (e SubscriptionPaymentMethodType) IsValid() bool {
 	switch e {
-	case SubscriptionPaymentMethodTypeNewebpay, SubscriptionPaymentMethodTypeAppStore, SubscriptionPaymentMethodTypeGooglePlay:
+	case SubscriptionPaymentMethodTypeNewebpay, SubscriptionPaymentMethodTypeEcpay, SubscriptionPaymentMethodTypeAppStore, SubscriptionPaymentMethodTypeGooglePlay:
 		return true
 	}
 	return false
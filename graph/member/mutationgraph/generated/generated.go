@@ -42,9 +42,15 @@ type DirectiveRoot struct {
 
 type ComplexityRoot struct {
 	Mutation struct {
+		CreateGiftSubscription      func(childComplexity int, data map[string]interface{}, info model.SubscriptionGiftCreateInfo) int
+		CreateShareLink             func(childComplexity int, postID string) int
 		CreateSubscriptionRecurring func(childComplexity int, data map[string]interface{}, info model.SubscriptionRecurringCreateInfo) int
 		Createmember                func(childComplexity int, data map[string]interface{}) int
 		CreatesSubscriptionOneTime  func(childComplexity int, data map[string]interface{}, info model.SubscriptionOneTimeCreateInfo) int
+		InviteGroupMember           func(childComplexity int, groupID string, email string) int
+		RedeemGiftSubscription      func(childComplexity int, giftCode string) int
+		RefundPayment               func(childComplexity int, id string, amount *int, reason *string, paymentMethod *model.SubscriptionPaymentMethodType) int
+		RemoveGroupMember           func(childComplexity int, groupID string, memberID string) int
 		Updatemember                func(childComplexity int, id string, data map[string]interface{}) int
 		Updatesubscription          func(childComplexity int, id string, data map[string]interface{}) int
 		UpsertAppSubscription       func(childComplexity int, info model.SubscriptionAppUpsertInfo) int
@@ -68,6 +74,17 @@ type ComplexityRoot struct {
 		UpdatedAt             func(childComplexity int) int
 	}
 
+	CheckoutForm struct {
+		Action        func(childComplexity int) int
+		Fields        func(childComplexity int) int
+		PaymentMethod func(childComplexity int) int
+	}
+
+	CheckoutFormField struct {
+		Name  func(childComplexity int) int
+		Value func(childComplexity int) int
+	}
+
 	GooglePlayPayment struct {
 		Amount              func(childComplexity int) int
 		CreatedAt           func(childComplexity int) int
@@ -95,6 +112,13 @@ type ComplexityRoot struct {
 		UpdatedAt      func(childComplexity int) int
 	}
 
+	GroupMembership struct {
+		GroupID     func(childComplexity int) int
+		MemberCount func(childComplexity int) int
+		MemberID    func(childComplexity int) int
+		Seats       func(childComplexity int) int
+	}
+
 	Invoice struct {
 		Amount          func(childComplexity int) int
 		BuyerName       func(childComplexity int) int
@@ -138,6 +162,7 @@ type ComplexityRoot struct {
 		FirstName           func(childComplexity int) int
 		Gender              func(childComplexity int) int
 		Group               func(childComplexity int) int
+		GroupJoinedDatetime func(childComplexity int) int
 		ID                  func(childComplexity int) int
 		LastName            func(childComplexity int) int
 		MarketingMembership func(childComplexity int) int
@@ -145,6 +170,8 @@ type ComplexityRoot struct {
 		Nickname            func(childComplexity int) int
 		Phone               func(childComplexity int) int
 		ProfileImage        func(childComplexity int) int
+		RedeemedGift        func(childComplexity int, where model.SubscriptionWhereInput, search *string, orderBy []*model.SubscriptionOrderByInput, first *int, skip int) int
+		RedeemedGiftCount   func(childComplexity int, where model.SubscriptionWhereInput) int
 		State               func(childComplexity int) int
 		Subscription        func(childComplexity int, where model.SubscriptionWhereInput, search *string, orderBy []*model.SubscriptionOrderByInput, first *int, skip int) int
 		SubscriptionCount   func(childComplexity int, where model.SubscriptionWhereInput) int
@@ -178,16 +205,25 @@ type ComplexityRoot struct {
 	}
 
 	Merchandise struct {
-		Code      func(childComplexity int) int
-		Comment   func(childComplexity int) int
-		CreatedAt func(childComplexity int) int
-		Currency  func(childComplexity int) int
-		Desc      func(childComplexity int) int
-		ID        func(childComplexity int) int
-		Name      func(childComplexity int) int
-		Price     func(childComplexity int) int
-		State     func(childComplexity int) int
-		UpdatedAt func(childComplexity int) int
+		Code          func(childComplexity int) int
+		Comment       func(childComplexity int) int
+		CreatedAt     func(childComplexity int) int
+		Currency      func(childComplexity int) int
+		Desc          func(childComplexity int) int
+		ID            func(childComplexity int) int
+		Name          func(childComplexity int) int
+		PaymentMethod func(childComplexity int) int
+		Price         func(childComplexity int) int
+		State         func(childComplexity int) int
+		TrialDays     func(childComplexity int) int
+		UpdatedAt     func(childComplexity int) int
+	}
+
+	NewebpayForm struct {
+		MerchantID func(childComplexity int) int
+		TradeInfo  func(childComplexity int) int
+		TradeSha   func(childComplexity int) int
+		Version    func(childComplexity int) int
 	}
 
 	NewebpayPayment struct {
@@ -207,6 +243,9 @@ type ComplexityRoot struct {
 		OrderNumber      func(childComplexity int) int
 		PaymentMethod    func(childComplexity int) int
 		PaymentTime      func(childComplexity int) int
+		RefundAmount     func(childComplexity int) int
+		RefundStatus     func(childComplexity int) int
+		RefundTime       func(childComplexity int) int
 		RespondCode      func(childComplexity int) int
 		Status           func(childComplexity int) int
 		Subscription     func(childComplexity int) int
@@ -225,6 +264,15 @@ type ComplexityRoot struct {
 		UpdatedAt    func(childComplexity int) int
 	}
 
+	PaymentRefund struct {
+		Amount         func(childComplexity int) int
+		IsCancelled    func(childComplexity int) int
+		PaymentID      func(childComplexity int) int
+		PaymentMethod  func(childComplexity int) int
+		RefundedAmount func(childComplexity int) int
+		SubscriptionID func(childComplexity int) int
+	}
+
 	Promotion struct {
 		Code      func(childComplexity int) int
 		CreatedAt func(childComplexity int) int
@@ -237,6 +285,13 @@ type ComplexityRoot struct {
 		UpdatedAt func(childComplexity int) int
 	}
 
+	ShareLink struct {
+		ExpiresAt      func(childComplexity int) int
+		PostID         func(childComplexity int) int
+		RemainingQuota func(childComplexity int) int
+		Token          func(childComplexity int) int
+	}
+
 	Subscription struct {
 		AaplOriginalTransactionID           func(childComplexity int) int
 		Amount                              func(childComplexity int) int
@@ -255,6 +310,10 @@ type ComplexityRoot struct {
 		Desc                                func(childComplexity int) int
 		Email                               func(childComplexity int) int
 		Frequency                           func(childComplexity int) int
+		GiftCode                            func(childComplexity int) int
+		GiftRecipientEmail                  func(childComplexity int) int
+		GiftRedeemedDatetime                func(childComplexity int) int
+		GiftRedeemer                        func(childComplexity int) int
 		GooglePlayNotificationEventDatetime func(childComplexity int) int
 		GooglePlayPackageName               func(childComplexity int) int
 		GooglePlayPayment                   func(childComplexity int, where model.GooglePlayPaymentWhereInput, search *string, orderBy []*model.GooglePlayPaymentOrderByInput, first *int, skip int) int
@@ -264,6 +323,7 @@ type ComplexityRoot struct {
 		ID                                  func(childComplexity int) int
 		IsActive                            func(childComplexity int) int
 		IsCanceled                          func(childComplexity int) int
+		IsGift                              func(childComplexity int) int
 		LoveCode                            func(childComplexity int) int
 		Member                              func(childComplexity int) int
 		NewebpayPayment                     func(childComplexity int, where model.NewebpayPaymentWhereInput, search *string, orderBy []*model.NewebpayPaymentOrderByInput, first *int, skip int) int
@@ -286,10 +346,13 @@ type ComplexityRoot struct {
 		PromoteID                           func(childComplexity int) int
 		RefundNote                          func(childComplexity int) int
 		Status                              func(childComplexity int) int
+		TrialEndDatetime                    func(childComplexity int) int
 		UpdatedAt                           func(childComplexity int) int
 	}
 
 	SubscriptionCreation struct {
+		CheckoutForm    func(childComplexity int) int
+		NewebpayForm    func(childComplexity int) int
 		NewebpayPayload func(childComplexity int) int
 		Subscription    func(childComplexity int) int
 	}
@@ -335,9 +398,13 @@ type ComplexityRoot struct {
 		Desc                      func(childComplexity int) int
 		Email                     func(childComplexity int) int
 		Frequency                 func(childComplexity int) int
+		GiftCode                  func(childComplexity int) int
+		GiftRecipientEmail        func(childComplexity int) int
+		GiftRedeemedDatetime      func(childComplexity int) int
 		ID                        func(childComplexity int) int
 		IsActive                  func(childComplexity int) int
 		IsCanceled                func(childComplexity int) int
+		IsGift                    func(childComplexity int) int
 		NextFrequency             func(childComplexity int) int
 		Note                      func(childComplexity int) int
 		OneTimeEndDatetime        func(childComplexity int) int
@@ -352,6 +419,7 @@ type ComplexityRoot struct {
 		PostID                    func(childComplexity int) int
 		PromoteID                 func(childComplexity int) int
 		Status                    func(childComplexity int) int
+		TrialEndDatetime          func(childComplexity int) int
 		UpdatedAt                 func(childComplexity int) int
 	}
 
@@ -366,7 +434,13 @@ type MutationResolver interface {
 	UpsertAppSubscription(ctx context.Context, info model.SubscriptionAppUpsertInfo) (*model.SubscriptionUpsert, error)
 	CreateSubscriptionRecurring(ctx context.Context, data map[string]interface{}, info model.SubscriptionRecurringCreateInfo) (*model.SubscriptionCreation, error)
 	CreatesSubscriptionOneTime(ctx context.Context, data map[string]interface{}, info model.SubscriptionOneTimeCreateInfo) (*model.SubscriptionCreation, error)
+	CreateGiftSubscription(ctx context.Context, data map[string]interface{}, info model.SubscriptionGiftCreateInfo) (*model.SubscriptionCreation, error)
+	RedeemGiftSubscription(ctx context.Context, giftCode string) (*model.SubscriptionInfo, error)
 	Updatesubscription(ctx context.Context, id string, data map[string]interface{}) (*model.SubscriptionInfo, error)
+	CreateShareLink(ctx context.Context, postID string) (*model.ShareLink, error)
+	InviteGroupMember(ctx context.Context, groupID string, email string) (*model.GroupMembership, error)
+	RemoveGroupMember(ctx context.Context, groupID string, memberID string) (*model.GroupMembership, error)
+	RefundPayment(ctx context.Context, id string, amount *int, reason *string, paymentMethod *model.SubscriptionPaymentMethodType) (*model.PaymentRefund, error)
 }
 
 type executableSchema struct {
@@ -384,6 +458,30 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 	_ = ec
 	switch typeName + "." + field {
 
+	case "Mutation.createGiftSubscription":
+		if e.complexity.Mutation.CreateGiftSubscription == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_createGiftSubscription_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.CreateGiftSubscription(childComplexity, args["data"].(map[string]interface{}), args["info"].(model.SubscriptionGiftCreateInfo)), true
+
+	case "Mutation.createShareLink":
+		if e.complexity.Mutation.CreateShareLink == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_createShareLink_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.CreateShareLink(childComplexity, args["postId"].(string)), true
+
 	case "Mutation.createSubscriptionRecurring":
 		if e.complexity.Mutation.CreateSubscriptionRecurring == nil {
 			break
@@ -420,6 +518,54 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Mutation.CreatesSubscriptionOneTime(childComplexity, args["data"].(map[string]interface{}), args["info"].(model.SubscriptionOneTimeCreateInfo)), true
 
+	case "Mutation.inviteGroupMember":
+		if e.complexity.Mutation.InviteGroupMember == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_inviteGroupMember_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.InviteGroupMember(childComplexity, args["groupId"].(string), args["email"].(string)), true
+
+	case "Mutation.redeemGiftSubscription":
+		if e.complexity.Mutation.RedeemGiftSubscription == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_redeemGiftSubscription_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.RedeemGiftSubscription(childComplexity, args["giftCode"].(string)), true
+
+	case "Mutation.refundPayment":
+		if e.complexity.Mutation.RefundPayment == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_refundPayment_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.RefundPayment(childComplexity, args["id"].(string), args["amount"].(*int), args["reason"].(*string), args["paymentMethod"].(*model.SubscriptionPaymentMethodType)), true
+
+	case "Mutation.removeGroupMember":
+		if e.complexity.Mutation.RemoveGroupMember == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_removeGroupMember_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.RemoveGroupMember(childComplexity, args["groupId"].(string), args["memberId"].(string)), true
+
 	case "Mutation.updatemember":
 		if e.complexity.Mutation.Updatemember == nil {
 			break
@@ -540,6 +686,41 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.AppStorePayment.UpdatedAt(childComplexity), true
 
+	case "checkoutForm.action":
+		if e.complexity.CheckoutForm.Action == nil {
+			break
+		}
+
+		return e.complexity.CheckoutForm.Action(childComplexity), true
+
+	case "checkoutForm.fields":
+		if e.complexity.CheckoutForm.Fields == nil {
+			break
+		}
+
+		return e.complexity.CheckoutForm.Fields(childComplexity), true
+
+	case "checkoutForm.paymentMethod":
+		if e.complexity.CheckoutForm.PaymentMethod == nil {
+			break
+		}
+
+		return e.complexity.CheckoutForm.PaymentMethod(childComplexity), true
+
+	case "checkoutFormField.name":
+		if e.complexity.CheckoutFormField.Name == nil {
+			break
+		}
+
+		return e.complexity.CheckoutFormField.Name(childComplexity), true
+
+	case "checkoutFormField.value":
+		if e.complexity.CheckoutFormField.Value == nil {
+			break
+		}
+
+		return e.complexity.CheckoutFormField.Value(childComplexity), true
+
 	case "googlePlayPayment.amount":
 		if e.complexity.GooglePlayPayment.Amount == nil {
 			break
@@ -697,6 +878,34 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Group.UpdatedAt(childComplexity), true
 
+	case "groupMembership.groupId":
+		if e.complexity.GroupMembership.GroupID == nil {
+			break
+		}
+
+		return e.complexity.GroupMembership.GroupID(childComplexity), true
+
+	case "groupMembership.memberCount":
+		if e.complexity.GroupMembership.MemberCount == nil {
+			break
+		}
+
+		return e.complexity.GroupMembership.MemberCount(childComplexity), true
+
+	case "groupMembership.memberId":
+		if e.complexity.GroupMembership.MemberID == nil {
+			break
+		}
+
+		return e.complexity.GroupMembership.MemberID(childComplexity), true
+
+	case "groupMembership.seats":
+		if e.complexity.GroupMembership.Seats == nil {
+			break
+		}
+
+		return e.complexity.GroupMembership.Seats(childComplexity), true
+
 	case "invoice.amount":
 		if e.complexity.Invoice.Amount == nil {
 			break
@@ -949,6 +1158,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Member.Group(childComplexity), true
 
+	case "member.groupJoinedDatetime":
+		if e.complexity.Member.GroupJoinedDatetime == nil {
+			break
+		}
+
+		return e.complexity.Member.GroupJoinedDatetime(childComplexity), true
+
 	case "member.id":
 		if e.complexity.Member.ID == nil {
 			break
@@ -998,6 +1214,30 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Member.ProfileImage(childComplexity), true
 
+	case "member.redeemedGift":
+		if e.complexity.Member.RedeemedGift == nil {
+			break
+		}
+
+		args, err := ec.field_member_redeemedGift_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Member.RedeemedGift(childComplexity, args["where"].(model.SubscriptionWhereInput), args["search"].(*string), args["orderBy"].([]*model.SubscriptionOrderByInput), args["first"].(*int), args["skip"].(int)), true
+
+	case "member.redeemedGiftCount":
+		if e.complexity.Member.RedeemedGiftCount == nil {
+			break
+		}
+
+		args, err := ec.field_member_redeemedGiftCount_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Member.RedeemedGiftCount(childComplexity, args["where"].(model.SubscriptionWhereInput)), true
+
 	case "member.state":
 		if e.complexity.Member.State == nil {
 			break
@@ -1246,6 +1486,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Merchandise.Name(childComplexity), true
 
+	case "merchandise.paymentMethod":
+		if e.complexity.Merchandise.PaymentMethod == nil {
+			break
+		}
+
+		return e.complexity.Merchandise.PaymentMethod(childComplexity), true
+
 	case "merchandise.price":
 		if e.complexity.Merchandise.Price == nil {
 			break
@@ -1260,6 +1507,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Merchandise.State(childComplexity), true
 
+	case "merchandise.trialDays":
+		if e.complexity.Merchandise.TrialDays == nil {
+			break
+		}
+
+		return e.complexity.Merchandise.TrialDays(childComplexity), true
+
 	case "merchandise.updatedAt":
 		if e.complexity.Merchandise.UpdatedAt == nil {
 			break
@@ -1267,6 +1521,34 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Merchandise.UpdatedAt(childComplexity), true
 
+	case "newebpayForm.merchantId":
+		if e.complexity.NewebpayForm.MerchantID == nil {
+			break
+		}
+
+		return e.complexity.NewebpayForm.MerchantID(childComplexity), true
+
+	case "newebpayForm.tradeInfo":
+		if e.complexity.NewebpayForm.TradeInfo == nil {
+			break
+		}
+
+		return e.complexity.NewebpayForm.TradeInfo(childComplexity), true
+
+	case "newebpayForm.tradeSha":
+		if e.complexity.NewebpayForm.TradeSha == nil {
+			break
+		}
+
+		return e.complexity.NewebpayForm.TradeSha(childComplexity), true
+
+	case "newebpayForm.version":
+		if e.complexity.NewebpayForm.Version == nil {
+			break
+		}
+
+		return e.complexity.NewebpayForm.Version(childComplexity), true
+
 	case "newebpayPayment.amount":
 		if e.complexity.NewebpayPayment.Amount == nil {
 			break
@@ -1379,6 +1661,27 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.NewebpayPayment.PaymentTime(childComplexity), true
 
+	case "newebpayPayment.refundAmount":
+		if e.complexity.NewebpayPayment.RefundAmount == nil {
+			break
+		}
+
+		return e.complexity.NewebpayPayment.RefundAmount(childComplexity), true
+
+	case "newebpayPayment.refundStatus":
+		if e.complexity.NewebpayPayment.RefundStatus == nil {
+			break
+		}
+
+		return e.complexity.NewebpayPayment.RefundStatus(childComplexity), true
+
+	case "newebpayPayment.refundTime":
+		if e.complexity.NewebpayPayment.RefundTime == nil {
+			break
+		}
+
+		return e.complexity.NewebpayPayment.RefundTime(childComplexity), true
+
 	case "newebpayPayment.respondCode":
 		if e.complexity.NewebpayPayment.RespondCode == nil {
 			break
@@ -1470,6 +1773,48 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.NewebpayPaymentInfo.UpdatedAt(childComplexity), true
 
+	case "paymentRefund.amount":
+		if e.complexity.PaymentRefund.Amount == nil {
+			break
+		}
+
+		return e.complexity.PaymentRefund.Amount(childComplexity), true
+
+	case "paymentRefund.isCancelled":
+		if e.complexity.PaymentRefund.IsCancelled == nil {
+			break
+		}
+
+		return e.complexity.PaymentRefund.IsCancelled(childComplexity), true
+
+	case "paymentRefund.paymentId":
+		if e.complexity.PaymentRefund.PaymentID == nil {
+			break
+		}
+
+		return e.complexity.PaymentRefund.PaymentID(childComplexity), true
+
+	case "paymentRefund.paymentMethod":
+		if e.complexity.PaymentRefund.PaymentMethod == nil {
+			break
+		}
+
+		return e.complexity.PaymentRefund.PaymentMethod(childComplexity), true
+
+	case "paymentRefund.refundedAmount":
+		if e.complexity.PaymentRefund.RefundedAmount == nil {
+			break
+		}
+
+		return e.complexity.PaymentRefund.RefundedAmount(childComplexity), true
+
+	case "paymentRefund.subscriptionId":
+		if e.complexity.PaymentRefund.SubscriptionID == nil {
+			break
+		}
+
+		return e.complexity.PaymentRefund.SubscriptionID(childComplexity), true
+
 	case "promotion.code":
 		if e.complexity.Promotion.Code == nil {
 			break
@@ -1533,6 +1878,34 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Promotion.UpdatedAt(childComplexity), true
 
+	case "shareLink.expiresAt":
+		if e.complexity.ShareLink.ExpiresAt == nil {
+			break
+		}
+
+		return e.complexity.ShareLink.ExpiresAt(childComplexity), true
+
+	case "shareLink.postId":
+		if e.complexity.ShareLink.PostID == nil {
+			break
+		}
+
+		return e.complexity.ShareLink.PostID(childComplexity), true
+
+	case "shareLink.remainingQuota":
+		if e.complexity.ShareLink.RemainingQuota == nil {
+			break
+		}
+
+		return e.complexity.ShareLink.RemainingQuota(childComplexity), true
+
+	case "shareLink.token":
+		if e.complexity.ShareLink.Token == nil {
+			break
+		}
+
+		return e.complexity.ShareLink.Token(childComplexity), true
+
 	case "subscription.aaplOriginalTransactionId":
 		if e.complexity.Subscription.AaplOriginalTransactionID == nil {
 			break
@@ -1662,6 +2035,34 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Subscription.Frequency(childComplexity), true
 
+	case "subscription.giftCode":
+		if e.complexity.Subscription.GiftCode == nil {
+			break
+		}
+
+		return e.complexity.Subscription.GiftCode(childComplexity), true
+
+	case "subscription.giftRecipientEmail":
+		if e.complexity.Subscription.GiftRecipientEmail == nil {
+			break
+		}
+
+		return e.complexity.Subscription.GiftRecipientEmail(childComplexity), true
+
+	case "subscription.giftRedeemedDatetime":
+		if e.complexity.Subscription.GiftRedeemedDatetime == nil {
+			break
+		}
+
+		return e.complexity.Subscription.GiftRedeemedDatetime(childComplexity), true
+
+	case "subscription.giftRedeemer":
+		if e.complexity.Subscription.GiftRedeemer == nil {
+			break
+		}
+
+		return e.complexity.Subscription.GiftRedeemer(childComplexity), true
+
 	case "subscription.googlePlayNotificationEventDatetime":
 		if e.complexity.Subscription.GooglePlayNotificationEventDatetime == nil {
 			break
@@ -1735,6 +2136,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Subscription.IsCanceled(childComplexity), true
 
+	case "subscription.isGift":
+		if e.complexity.Subscription.IsGift == nil {
+			break
+		}
+
+		return e.complexity.Subscription.IsGift(childComplexity), true
+
 	case "subscription.loveCode":
 		if e.complexity.Subscription.LoveCode == nil {
 			break
@@ -1899,6 +2307,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Subscription.Status(childComplexity), true
 
+	case "subscription.trialEndDatetime":
+		if e.complexity.Subscription.TrialEndDatetime == nil {
+			break
+		}
+
+		return e.complexity.Subscription.TrialEndDatetime(childComplexity), true
+
 	case "subscription.updatedAt":
 		if e.complexity.Subscription.UpdatedAt == nil {
 			break
@@ -1906,6 +2321,20 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Subscription.UpdatedAt(childComplexity), true
 
+	case "subscriptionCreation.checkoutForm":
+		if e.complexity.SubscriptionCreation.CheckoutForm == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionCreation.CheckoutForm(childComplexity), true
+
+	case "subscriptionCreation.newebpayForm":
+		if e.complexity.SubscriptionCreation.NewebpayForm == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionCreation.NewebpayForm(childComplexity), true
+
 	case "subscriptionCreation.newebpayPayload":
 		if e.complexity.SubscriptionCreation.NewebpayPayload == nil {
 			break
@@ -2179,6 +2608,27 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.SubscriptionInfo.Frequency(childComplexity), true
 
+	case "subscriptionInfo.giftCode":
+		if e.complexity.SubscriptionInfo.GiftCode == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionInfo.GiftCode(childComplexity), true
+
+	case "subscriptionInfo.giftRecipientEmail":
+		if e.complexity.SubscriptionInfo.GiftRecipientEmail == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionInfo.GiftRecipientEmail(childComplexity), true
+
+	case "subscriptionInfo.giftRedeemedDatetime":
+		if e.complexity.SubscriptionInfo.GiftRedeemedDatetime == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionInfo.GiftRedeemedDatetime(childComplexity), true
+
 	case "subscriptionInfo.id":
 		if e.complexity.SubscriptionInfo.ID == nil {
 			break
@@ -2200,6 +2650,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.SubscriptionInfo.IsCanceled(childComplexity), true
 
+	case "subscriptionInfo.isGift":
+		if e.complexity.SubscriptionInfo.IsGift == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionInfo.IsGift(childComplexity), true
+
 	case "subscriptionInfo.nextFrequency":
 		if e.complexity.SubscriptionInfo.NextFrequency == nil {
 			break
@@ -2298,6 +2755,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.SubscriptionInfo.Status(childComplexity), true
 
+	case "subscriptionInfo.trialEndDatetime":
+		if e.complexity.SubscriptionInfo.TrialEndDatetime == nil {
+			break
+		}
+
+		return e.complexity.SubscriptionInfo.TrialEndDatetime(childComplexity), true
+
 	case "subscriptionInfo.updatedAt":
 		if e.complexity.SubscriptionInfo.UpdatedAt == nil {
 			break
@@ -2691,6 +3155,10 @@ type member {
   email: String
   marketingMembership: marketingMembership
   group: group
+  """
+  When the member joined the group. Members take the seats of the group in this order.
+  """
+  groupJoinedDatetime: String
   type: memberTypeType
   state: memberStateType
   tos: Boolean
@@ -2715,6 +3183,17 @@ type member {
     skip: Int! = 0
   ): [subscription!]
   subscriptionCount(where: subscriptionWhereInput! = {}): Int
+  """
+  The gift subscriptions redeemed by the member
+  """
+  redeemedGift(
+    where: subscriptionWhereInput! = {}
+    search: String
+    orderBy: [subscriptionOrderByInput!]! = []
+    first: Int
+    skip: Int! = 0
+  ): [subscription!]
+  redeemedGiftCount(where: subscriptionWhereInput! = {}): Int
   createdAt: String
   updatedAt: String
 }
@@ -2841,12 +3320,18 @@ input memberOrderByInput {
   district: OrderDirection
   createdAt: OrderDirection
   updatedAt: OrderDirection
+  groupJoinedDatetime: OrderDirection
 }
 
 input memberPrivateUpdateInput {
   firebaseId: String
   email: String
   marketingMembership: marketingMembershipRelateToOneInput
+  group: groupRelateToOneInput
+  """
+  When the member joined the group. It's only written if MemberSchema::HasGroupJoinedDatetime is true.
+  """
+  groupJoinedDatetime: String
   type: memberTypeType
   state: memberStateType
   tos: Boolean
@@ -2875,6 +3360,12 @@ input marketingMembershipRelateToOneInput {
   disconnectAll: Boolean
 }
 
+input groupRelateToOneInput {
+  connect: groupWhereUniqueInput
+  disconnect: groupWhereUniqueInput
+  disconnectAll: Boolean
+}
+
 input subscriptionRelateToManyInput {
   create: [subscriptionCreateInput]
   connect: [subscriptionWhereUniqueInput]
@@ -2921,6 +3412,14 @@ type merchandise {
   name: String
   code: String
   price: Float
+  """
+  The days of the free trial of a recurring plan, which are 7, 14 or 30. There's no trial if it's null or 0.
+  """
+  trialDays: Int
+  """
+  The payment provider of the merchandise. The default provider is used if it's null.
+  """
+  paymentMethod: subscriptionPaymentMethodType
   currency: merchandiseCurrencyType
   state: merchandiseStateType
   desc: String
@@ -3480,6 +3979,9 @@ type newebpayPayment {
   cardInfoFirstSix: String
   cardInfoExp: String
   frequency: newebpayPaymentFrequencyType
+  refundAmount: Int
+  refundStatus: String
+  refundTime: String
   createdAt: String
   updatedAt: String
 }
@@ -3879,6 +4381,9 @@ input newebpayPaymentUpdateInput {
   cardInfoFirstSix: String
   cardInfoExp: String
   frequency: newebpayPaymentFrequencyType
+  refundAmount: Int
+  refundStatus: String
+  refundTime: String
   createdAt: String
   updatedAt: String
 }
@@ -4507,6 +5012,12 @@ type subscription {
   periodCreateDatetime: String
   periodFirstDatetime: String
   periodEndDatetime: String
+  trialEndDatetime: String
+  isGift: Boolean
+  giftCode: String
+  giftRecipientEmail: String
+  giftRedeemer: member
+  giftRedeemedDatetime: String
   changePlanDatetime: String
   googlePlayStatus: subscriptionGooglePlayStatusType
   googlePlayPurchaseToken: String
@@ -4534,6 +5045,7 @@ type subscription {
 
 enum subscriptionPaymentMethodType {
   newebpay
+  ecpay
   app_store
   google_play
 }
@@ -4593,6 +5105,8 @@ input subscriptionWhereInput {
   id_not_in: [ID!]
   member: memberWhereInput
   member_is_null: Boolean
+  giftRedeemer: memberWhereInput
+  giftRedeemer_is_null: Boolean
   orderNumber: String
   orderNumber_not: String
   orderNumber_contains: String
@@ -4698,6 +5212,9 @@ input subscriptionWhereInput {
   isActive_not: Boolean
   isCanceled: Boolean
   isCanceled_not: Boolean
+  isGift: Boolean
+  isGift_not: Boolean
+  giftCode: String
   frequency: subscriptionFrequencyType
   frequency_not: subscriptionFrequencyType
   frequency_in: [subscriptionFrequencyType]
@@ -5171,6 +5688,12 @@ input subscriptionPrivateUpdateInput {
   periodCreateDatetime: String
   periodFirstDatetime: String
   periodEndDatetime: String
+  trialEndDatetime: String
+  isGift: Boolean
+  giftCode: String
+  giftRecipientEmail: String
+  giftRedeemer: memberRelateToOneInput
+  giftRedeemedDatetime: String
   changePlanDatetime: String
   googlePlayStatus: subscriptionGooglePlayStatusType
   googlePlayPurchaseToken: String
@@ -5251,6 +5774,12 @@ input subscriptionCreateInput {
   periodCreateDatetime: String
   periodFirstDatetime: String
   periodEndDatetime: String
+  trialEndDatetime: String
+  isGift: Boolean
+  giftCode: String
+  giftRecipientEmail: String
+  giftRedeemer: memberRelateToOneInput
+  giftRedeemedDatetime: String
   changePlanDatetime: String
   googlePlayStatus: subscriptionGooglePlayStatusType
   googlePlayPurchaseToken: String
@@ -6007,7 +6536,10 @@ enum createSubscriptionStatusType {
 }
 
 input subscriptionRecurringCreateInput {
-  paymentMethod: subscriptionPaymentMethodType!
+  """
+  The payment provider. It falls back to the provider of the merchandise, and then the default provider. ecpay only pays once.
+  """
+  paymentMethod: subscriptionPaymentMethodType
   status: createSubscriptionStatusType!
   email: String!
   """
@@ -6026,10 +6558,21 @@ input subscriptionRecurringCreateInput {
 
 input subscriptionRecurringCreateInfo {
   returnToPath: String!
+  """
+  It starts the free trial of the plan. A member can only have one free trial.
+  """
+  trial: Boolean
+  """
+  It's the code of an active promotion. The discount of the promotion is taken off the amount.
+  """
+  promotionCode: String
 }
 
 input subscriptionOneTimeCreateInput {
-  paymentMethod: subscriptionPaymentMethodType!
+  """
+  The payment provider. It falls back to the provider of the merchandise, and then the default provider. ecpay only pays once.
+  """
+  paymentMethod: subscriptionPaymentMethodType
   status: createSubscriptionStatusType!
   email: String!
   note: String
@@ -6047,6 +6590,34 @@ input subscriptionOneTimeCreateInfo {
   postSlug: String!
   postTitle: String!
   returnToPath: String!
+  """
+  It's the code of an active promotion. The discount of the promotion is taken off the amount.
+  """
+  promotionCode: String
+}
+
+input subscriptionGiftCreateInput {
+  """
+  The payment provider. It falls back to the provider of the merchandise, and then the default provider. ecpay only pays once.
+  """
+  paymentMethod: subscriptionPaymentMethodType
+  status: createSubscriptionStatusType!
+  email: String!
+  """
+  The recipient of the gift. It's only recorded, and the gift code isn't sent by the email. The purchaser reads the giftCode of the subscription once it's paid and passes it to the recipient.
+  """
+  giftRecipientEmail: String!
+  note: String
+  loveCode: Int
+  category: subscriptionCategoryType!
+  carrierType: String
+  carrierNum: String
+  buyerName: String
+  buyerUBN: String
+}
+
+input subscriptionGiftCreateInfo {
+  returnToPath: String!
 }
 
 input subscriptionAppUpsertInfo {
@@ -6089,6 +6660,11 @@ type subscriptionInfo {
   periodCreateDatetime: String
   periodFirstDatetime: String
   periodEndDatetime: String
+  trialEndDatetime: String
+  isGift: Boolean
+  giftCode: String
+  giftRecipientEmail: String
+  giftRedeemedDatetime: String
   changePlanDatetime: String
   note: String
   promoteId: Int
@@ -6106,12 +6682,88 @@ type subscriptionInfo {
 
 type subscriptionCreation {
   subscription: subscriptionInfo!
-  newebpayPayload: String
+  newebpayPayload: String @deprecated(reason: "It's the plain TradeInfo. POST newebpayForm instead.")
+  """
+  The fields of the form to POST to NewebPay. It's null if the subscription is paid by another provider.
+  """
+  newebpayForm: newebpayForm
+  """
+  The form to POST to the payment provider of the subscription
+  """
+  checkoutForm: checkoutForm
+}
+
+type checkoutForm {
+  paymentMethod: subscriptionPaymentMethodType!
+  """
+  The URL to POST the fields to
+  """
+  action: String!
+  fields: [checkoutFormField!]!
+}
+
+type checkoutFormField {
+  name: String!
+  value: String!
+}
+
+type newebpayForm {
+  merchantId: String!
+  """
+  The AES-256-CBC encrypted TradeInfo
+  """
+  tradeInfo: String!
+  tradeSha: String!
+  version: String!
+}
+
+type paymentRefund {
+  paymentId: ID!
+  subscriptionId: ID!
+  paymentMethod: subscriptionPaymentMethodType!
+  """
+  The amount refunded by this mutation
+  """
+  amount: Int!
+  """
+  The total amount refunded of the payment
+  """
+  refundedAmount: Int!
+  """
+  It's true if the authorisation is cancelled before it's captured instead of being refunded
+  """
+  isCancelled: Boolean!
 }
 
 type subscriptionUpsert {
   success: Boolean!
 }
+
+type shareLink {
+  postId: String!
+  """
+  It should be sent as the **shareToken** query parameter or the **X-Share-Token** header to /api/v0
+  """
+  token: String!
+  expiresAt: String!
+  """
+  The number of posts the member can still share in this month. It's null if there is no quota.
+  """
+  remainingQuota: Int
+}
+
+type groupMembership {
+  groupId: ID!
+  memberId: ID!
+  """
+  The number of members in the group after the mutation
+  """
+  memberCount: Int!
+  """
+  The number of seats of the group. It's null if the seats are unlimited.
+  """
+  seats: Int
+}
 `, BuiltIn: false},
 	{Name: "mutation.graphql", Input: `type Mutation {
   """
@@ -6154,6 +6806,19 @@ type subscriptionUpsert {
     info: subscriptionOneTimeCreateInfo!
   ): subscriptionCreation
   """
+  It creates a yearly subscription as a gift for the **giftRecipientEmail**, set a new order number, connect the subscription to the purchaser with the firebaseID, and the amount/currency coresponding to **yearly** in **merchandise**. The gift code is assigned when the payment is settled, and the purchaser reads the **giftCode** of the paid subscription to pass it to the recipient. No email is sent. The subscription doesn't renew, and it doesn't grant the purchaser premium privilege.
+
+  Nested query is not allowed in the mutation.
+  """
+  createGiftSubscription(
+    data: subscriptionGiftCreateInput!
+    info: subscriptionGiftCreateInfo!
+  ): subscriptionCreation
+  """
+  It redeems the paid gift subscription with the **giftCode** for the member with the same firebaseID in the **token**, whichever sign-in provider the member uses. The member has premium privilege for a year from the redemption. A gift can only be redeemed once.
+  """
+  redeemGiftSubscription(giftCode: String!): subscriptionInfo
+  """
   It checks if the existing subscription is connect to the member with the same firebaseID, and them it updates the subscription with subscriptionUpdateInput and the amount/currency coresponding to the nextFrequency in **merchandise**.
 
   It pracatically let users update the next frequency and cancel the subscription.
@@ -6161,6 +6826,31 @@ type subscriptionUpsert {
   Nested query is not allowed in the mutation.
   """
   updatesubscription(id: ID!, data: subscriptionUpdateInput!): subscriptionInfo
+
+  """
+  It mints a signed share token of a premium post for the member with the same **firebaseId** in the **token**. The member has to be able to read the post in full. Anyone with the token can read the post in full until it expires.
+
+  A member can share a limited number of posts every month. Sharing the same post again doesn't consume the quota.
+  """
+  createShareLink(postId: String!): shareLink
+
+  """
+  It connects the active member with the **email** to the group and makes the member a **subscribe_group** member. Only the admin of the group, whose verified email in the **token** is the **requesterEmail** of the group, can invite members.
+
+  The group has to be in its validity window and have a vacant seat. Members who already have premium privilege or belong to a group can't be invited.
+  """
+  inviteGroupMember(groupId: ID!, email: String!): groupMembership
+  """
+  It disconnects the member from the group and makes a **subscribe_group** member a **none** member. Only the admin of the group can remove members.
+  """
+  removeGroupMember(groupId: ID!, memberId: ID!): groupMembership
+
+  """
+  It refunds the **amount** of the payment, or the amount not refunded yet if it's omitted, deactivates its subscription and records the refund in the payment. The authorisation is cancelled instead if the whole payment is refunded before it's captured. The **reason** is kept as the refund note of the subscription.
+
+  Only administrators can refund payments with the admin token at /api/admin/graphql/member. The **paymentMethod** is the provider of the payment, which is **newebpay** by default.
+  """
+  refundPayment(id: ID!, amount: Int, reason: String, paymentMethod: subscriptionPaymentMethodType): paymentRefund
 }
 `, BuiltIn: false},
 }
@@ -6170,6 +6860,45 @@ var parsedSchema = gqlparser.MustLoadSchema(sources...)
 
 // region    ***************************** args.gotpl *****************************
 
+func (ec *executionContext) field_Mutation_createGiftSubscription_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 map[string]interface{}
+	if tmp, ok := rawArgs["data"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("data"))
+		arg0, err = ec.unmarshalNsubscriptionGiftCreateInput2map(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["data"] = arg0
+	var arg1 model.SubscriptionGiftCreateInfo
+	if tmp, ok := rawArgs["info"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("info"))
+		arg1, err = ec.unmarshalNsubscriptionGiftCreateInfo2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionGiftCreateInfo(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["info"] = arg1
+	return args, nil
+}
+
+func (ec *executionContext) field_Mutation_createShareLink_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 string
+	if tmp, ok := rawArgs["postId"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("postId"))
+		arg0, err = ec.unmarshalNString2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["postId"] = arg0
+	return args, nil
+}
+
 func (ec *executionContext) field_Mutation_createSubscriptionRecurring_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
 	var err error
 	args := map[string]interface{}{}
@@ -6233,6 +6962,111 @@ func (ec *executionContext) field_Mutation_createsSubscriptionOneTime_args(ctx c
 	return args, nil
 }
 
+func (ec *executionContext) field_Mutation_inviteGroupMember_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 string
+	if tmp, ok := rawArgs["groupId"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("groupId"))
+		arg0, err = ec.unmarshalNID2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["groupId"] = arg0
+	var arg1 string
+	if tmp, ok := rawArgs["email"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("email"))
+		arg1, err = ec.unmarshalNString2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["email"] = arg1
+	return args, nil
+}
+
+func (ec *executionContext) field_Mutation_redeemGiftSubscription_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 string
+	if tmp, ok := rawArgs["giftCode"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftCode"))
+		arg0, err = ec.unmarshalNString2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["giftCode"] = arg0
+	return args, nil
+}
+
+func (ec *executionContext) field_Mutation_refundPayment_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 string
+	if tmp, ok := rawArgs["id"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("id"))
+		arg0, err = ec.unmarshalNID2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["id"] = arg0
+	var arg1 *int
+	if tmp, ok := rawArgs["amount"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("amount"))
+		arg1, err = ec.unmarshalOInt2ᚖint(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["amount"] = arg1
+	var arg2 *string
+	if tmp, ok := rawArgs["reason"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("reason"))
+		arg2, err = ec.unmarshalOString2ᚖstring(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["reason"] = arg2
+	var arg3 *model.SubscriptionPaymentMethodType
+	if tmp, ok := rawArgs["paymentMethod"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("paymentMethod"))
+		arg3, err = ec.unmarshalOsubscriptionPaymentMethodType2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionPaymentMethodType(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["paymentMethod"] = arg3
+	return args, nil
+}
+
+func (ec *executionContext) field_Mutation_removeGroupMember_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 string
+	if tmp, ok := rawArgs["groupId"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("groupId"))
+		arg0, err = ec.unmarshalNID2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["groupId"] = arg0
+	var arg1 string
+	if tmp, ok := rawArgs["memberId"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("memberId"))
+		arg1, err = ec.unmarshalNID2string(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["memberId"] = arg1
+	return args, nil
+}
+
 func (ec *executionContext) field_Mutation_updatemember_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
 	var err error
 	args := map[string]interface{}{}
@@ -6407,6 +7241,72 @@ func (ec *executionContext) field_group_member_args(ctx context.Context, rawArgs
 	return args, nil
 }
 
+func (ec *executionContext) field_member_redeemedGiftCount_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 model.SubscriptionWhereInput
+	if tmp, ok := rawArgs["where"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("where"))
+		arg0, err = ec.unmarshalNsubscriptionWhereInput2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionWhereInput(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["where"] = arg0
+	return args, nil
+}
+
+func (ec *executionContext) field_member_redeemedGift_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	args := map[string]interface{}{}
+	var arg0 model.SubscriptionWhereInput
+	if tmp, ok := rawArgs["where"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("where"))
+		arg0, err = ec.unmarshalNsubscriptionWhereInput2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionWhereInput(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["where"] = arg0
+	var arg1 *string
+	if tmp, ok := rawArgs["search"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("search"))
+		arg1, err = ec.unmarshalOString2ᚖstring(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["search"] = arg1
+	var arg2 []*model.SubscriptionOrderByInput
+	if tmp, ok := rawArgs["orderBy"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("orderBy"))
+		arg2, err = ec.unmarshalNsubscriptionOrderByInput2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionOrderByInputᚄ(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["orderBy"] = arg2
+	var arg3 *int
+	if tmp, ok := rawArgs["first"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("first"))
+		arg3, err = ec.unmarshalOInt2ᚖint(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["first"] = arg3
+	var arg4 int
+	if tmp, ok := rawArgs["skip"]; ok {
+		ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("skip"))
+		arg4, err = ec.unmarshalNInt2int(ctx, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args["skip"] = arg4
+	return args, nil
+}
+
 func (ec *executionContext) field_member_subscriptionCount_args(ctx context.Context, rawArgs map[string]interface{}) (map[string]interface{}, error) {
 	var err error
 	args := map[string]interface{}{}
@@ -6874,6 +7774,84 @@ func (ec *executionContext) _Mutation_createsSubscriptionOneTime(ctx context.Con
 	return ec.marshalOsubscriptionCreation2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionCreation(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _Mutation_createGiftSubscription(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   true,
+		IsResolver: true,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_Mutation_createGiftSubscription_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Mutation().CreateGiftSubscription(rctx, args["data"].(map[string]interface{}), args["info"].(model.SubscriptionGiftCreateInfo))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.SubscriptionCreation)
+	fc.Result = res
+	return ec.marshalOsubscriptionCreation2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionCreation(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _Mutation_redeemGiftSubscription(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   true,
+		IsResolver: true,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_Mutation_redeemGiftSubscription_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Mutation().RedeemGiftSubscription(rctx, args["giftCode"].(string))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.SubscriptionInfo)
+	fc.Result = res
+	return ec.marshalOsubscriptionInfo2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionInfo(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _Mutation_updatesubscription(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -6913,6 +7891,162 @@ func (ec *executionContext) _Mutation_updatesubscription(ctx context.Context, fi
 	return ec.marshalOsubscriptionInfo2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionInfo(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _Mutation_createShareLink(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   true,
+		IsResolver: true,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_Mutation_createShareLink_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Mutation().CreateShareLink(rctx, args["postId"].(string))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.ShareLink)
+	fc.Result = res
+	return ec.marshalOshareLink2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐShareLink(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _Mutation_inviteGroupMember(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   true,
+		IsResolver: true,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_Mutation_inviteGroupMember_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Mutation().InviteGroupMember(rctx, args["groupId"].(string), args["email"].(string))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.GroupMembership)
+	fc.Result = res
+	return ec.marshalOgroupMembership2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupMembership(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _Mutation_removeGroupMember(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   true,
+		IsResolver: true,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_Mutation_removeGroupMember_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Mutation().RemoveGroupMember(rctx, args["groupId"].(string), args["memberId"].(string))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.GroupMembership)
+	fc.Result = res
+	return ec.marshalOgroupMembership2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupMembership(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _Mutation_refundPayment(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   true,
+		IsResolver: true,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_Mutation_refundPayment_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Mutation().RefundPayment(rctx, args["id"].(string), args["amount"].(*int), args["reason"].(*string), args["paymentMethod"].(*model.SubscriptionPaymentMethodType))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.PaymentRefund)
+	fc.Result = res
+	return ec.marshalOpaymentRefund2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐPaymentRefund(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _Query___type(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -8489,6 +9623,181 @@ func (ec *executionContext) _appStorePayment_updatedAt(ctx context.Context, fiel
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _checkoutForm_paymentMethod(ctx context.Context, field graphql.CollectedField, obj *model.CheckoutForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "checkoutForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.PaymentMethod, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(model.SubscriptionPaymentMethodType)
+	fc.Result = res
+	return ec.marshalNsubscriptionPaymentMethodType2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionPaymentMethodType(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _checkoutForm_action(ctx context.Context, field graphql.CollectedField, obj *model.CheckoutForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "checkoutForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Action, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _checkoutForm_fields(ctx context.Context, field graphql.CollectedField, obj *model.CheckoutForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "checkoutForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Fields, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.([]*model.CheckoutFormField)
+	fc.Result = res
+	return ec.marshalNcheckoutFormField2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCheckoutFormFieldᚄ(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _checkoutFormField_name(ctx context.Context, field graphql.CollectedField, obj *model.CheckoutFormField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "checkoutFormField",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Name, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _checkoutFormField_value(ctx context.Context, field graphql.CollectedField, obj *model.CheckoutFormField) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "checkoutFormField",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Value, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _googlePlayPayment_id(ctx context.Context, field graphql.CollectedField, obj *model.GooglePlayPayment) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -9181,6 +10490,143 @@ func (ec *executionContext) _group_updatedAt(ctx context.Context, field graphql.
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _groupMembership_groupId(ctx context.Context, field graphql.CollectedField, obj *model.GroupMembership) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "groupMembership",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GroupID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNID2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _groupMembership_memberId(ctx context.Context, field graphql.CollectedField, obj *model.GroupMembership) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "groupMembership",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.MemberID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNID2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _groupMembership_memberCount(ctx context.Context, field graphql.CollectedField, obj *model.GroupMembership) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "groupMembership",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.MemberCount, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _groupMembership_seats(ctx context.Context, field graphql.CollectedField, obj *model.GroupMembership) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "groupMembership",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Seats, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*int)
+	fc.Result = res
+	return ec.marshalOInt2ᚖint(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _invoice_id(ctx context.Context, field graphql.CollectedField, obj *model.Invoice) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -10118,6 +11564,38 @@ func (ec *executionContext) _member_group(ctx context.Context, field graphql.Col
 	return ec.marshalOgroup2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroup(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _member_groupJoinedDatetime(ctx context.Context, field graphql.CollectedField, obj *model.Member) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "member",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GroupJoinedDatetime, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _member_type(ctx context.Context, field graphql.CollectedField, obj *model.Member) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -10708,6 +12186,84 @@ func (ec *executionContext) _member_subscriptionCount(ctx context.Context, field
 	return ec.marshalOInt2ᚖint(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _member_redeemedGift(ctx context.Context, field graphql.CollectedField, obj *model.Member) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "member",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_member_redeemedGift_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RedeemedGift, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.([]*model.Subscription)
+	fc.Result = res
+	return ec.marshalOsubscription2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionᚄ(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _member_redeemedGiftCount(ctx context.Context, field graphql.CollectedField, obj *model.Member) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "member",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	rawArgs := field.ArgumentMap(ec.Variables)
+	args, err := ec.field_member_redeemedGiftCount_args(ctx, rawArgs)
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	fc.Args = args
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RedeemedGiftCount, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*int)
+	fc.Result = res
+	return ec.marshalOInt2ᚖint(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _member_createdAt(ctx context.Context, field graphql.CollectedField, obj *model.Member) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -11578,6 +13134,70 @@ func (ec *executionContext) _merchandise_price(ctx context.Context, field graphq
 	return ec.marshalOFloat2ᚖfloat64(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _merchandise_trialDays(ctx context.Context, field graphql.CollectedField, obj *model.Merchandise) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "merchandise",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TrialDays, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*int)
+	fc.Result = res
+	return ec.marshalOInt2ᚖint(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _merchandise_paymentMethod(ctx context.Context, field graphql.CollectedField, obj *model.Merchandise) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "merchandise",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.PaymentMethod, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.SubscriptionPaymentMethodType)
+	fc.Result = res
+	return ec.marshalOsubscriptionPaymentMethodType2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionPaymentMethodType(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _merchandise_currency(ctx context.Context, field graphql.CollectedField, obj *model.Merchandise) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -11770,6 +13390,146 @@ func (ec *executionContext) _merchandise_updatedAt(ctx context.Context, field gr
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _newebpayForm_merchantId(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.MerchantID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _newebpayForm_tradeInfo(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TradeInfo, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _newebpayForm_tradeSha(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TradeSha, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _newebpayForm_version(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayForm) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayForm",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Version, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _newebpayPayment_id(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayPayment) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -12413,6 +14173,102 @@ func (ec *executionContext) _newebpayPayment_frequency(ctx context.Context, fiel
 	return ec.marshalOnewebpayPaymentFrequencyType2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐNewebpayPaymentFrequencyType(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _newebpayPayment_refundAmount(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayPayment) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayPayment",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RefundAmount, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*int)
+	fc.Result = res
+	return ec.marshalOInt2ᚖint(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _newebpayPayment_refundStatus(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayPayment) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayPayment",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RefundStatus, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _newebpayPayment_refundTime(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayPayment) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "newebpayPayment",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RefundTime, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _newebpayPayment_createdAt(ctx context.Context, field graphql.CollectedField, obj *model.NewebpayPayment) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -12704,6 +14560,216 @@ func (ec *executionContext) _newebpayPaymentInfo_updatedAt(ctx context.Context,
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _paymentRefund_paymentId(ctx context.Context, field graphql.CollectedField, obj *model.PaymentRefund) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "paymentRefund",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.PaymentID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNID2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _paymentRefund_subscriptionId(ctx context.Context, field graphql.CollectedField, obj *model.PaymentRefund) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "paymentRefund",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.SubscriptionID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNID2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _paymentRefund_paymentMethod(ctx context.Context, field graphql.CollectedField, obj *model.PaymentRefund) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "paymentRefund",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.PaymentMethod, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(model.SubscriptionPaymentMethodType)
+	fc.Result = res
+	return ec.marshalNsubscriptionPaymentMethodType2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionPaymentMethodType(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _paymentRefund_amount(ctx context.Context, field graphql.CollectedField, obj *model.PaymentRefund) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "paymentRefund",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Amount, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _paymentRefund_refundedAmount(ctx context.Context, field graphql.CollectedField, obj *model.PaymentRefund) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "paymentRefund",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RefundedAmount, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _paymentRefund_isCancelled(ctx context.Context, field graphql.CollectedField, obj *model.PaymentRefund) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "paymentRefund",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.IsCancelled, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(bool)
+	fc.Result = res
+	return ec.marshalNBoolean2bool(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _promotion_id(ctx context.Context, field graphql.CollectedField, obj *model.Promotion) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -12995,6 +15061,143 @@ func (ec *executionContext) _promotion_updatedAt(ctx context.Context, field grap
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _shareLink_postId(ctx context.Context, field graphql.CollectedField, obj *model.ShareLink) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "shareLink",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.PostID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _shareLink_token(ctx context.Context, field graphql.CollectedField, obj *model.ShareLink) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "shareLink",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Token, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _shareLink_expiresAt(ctx context.Context, field graphql.CollectedField, obj *model.ShareLink) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "shareLink",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.ExpiresAt, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _shareLink_remainingQuota(ctx context.Context, field graphql.CollectedField, obj *model.ShareLink) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "shareLink",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RemainingQuota, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*int)
+	fc.Result = res
+	return ec.marshalOInt2ᚖint(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _subscription_id(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -13872,6 +16075,198 @@ func (ec *executionContext) _subscription_periodEndDatetime(ctx context.Context,
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _subscription_trialEndDatetime(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscription",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TrialEndDatetime, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscription_isGift(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscription",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.IsGift, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*bool)
+	fc.Result = res
+	return ec.marshalOBoolean2ᚖbool(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscription_giftCode(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscription",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftCode, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscription_giftRecipientEmail(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscription",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftRecipientEmail, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscription_giftRedeemer(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscription",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftRedeemer, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.Member)
+	fc.Result = res
+	return ec.marshalOmember2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐMember(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscription_giftRedeemedDatetime(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscription",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftRedeemedDatetime, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _subscription_changePlanDatetime(ctx context.Context, field graphql.CollectedField, obj *model.Subscription) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -14675,6 +17070,70 @@ func (ec *executionContext) _subscriptionCreation_newebpayPayload(ctx context.Co
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _subscriptionCreation_newebpayForm(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionCreation) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionCreation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.NewebpayForm, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.NewebpayForm)
+	fc.Result = res
+	return ec.marshalOnewebpayForm2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐNewebpayForm(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscriptionCreation_checkoutForm(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionCreation) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionCreation",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.CheckoutForm, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.CheckoutForm)
+	fc.Result = res
+	return ec.marshalOcheckoutForm2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCheckoutForm(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _subscriptionHistory_id(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionHistory) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -16025,6 +18484,166 @@ func (ec *executionContext) _subscriptionInfo_periodEndDatetime(ctx context.Cont
 	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
 }
 
+func (ec *executionContext) _subscriptionInfo_trialEndDatetime(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionInfo) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionInfo",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TrialEndDatetime, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscriptionInfo_isGift(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionInfo) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionInfo",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.IsGift, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*bool)
+	fc.Result = res
+	return ec.marshalOBoolean2ᚖbool(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscriptionInfo_giftCode(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionInfo) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionInfo",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftCode, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscriptionInfo_giftRecipientEmail(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionInfo) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionInfo",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftRecipientEmail, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) _subscriptionInfo_giftRedeemedDatetime(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionInfo) (ret graphql.Marshaler) {
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	fc := &graphql.FieldContext{
+		Object:     "subscriptionInfo",
+		Field:      field,
+		Args:       nil,
+		IsMethod:   false,
+		IsResolver: false,
+	}
+
+	ctx = graphql.WithFieldContext(ctx, fc)
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.GiftRedeemedDatetime, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
 func (ec *executionContext) _subscriptionInfo_changePlanDatetime(ctx context.Context, field graphql.CollectedField, obj *model.SubscriptionInfo) (ret graphql.Marshaler) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -19419,6 +22038,45 @@ func (ec *executionContext) unmarshalInputgroupOrderByInput(ctx context.Context,
 	return it, nil
 }
 
+func (ec *executionContext) unmarshalInputgroupRelateToOneInput(ctx context.Context, obj interface{}) (model.GroupRelateToOneInput, error) {
+	var it model.GroupRelateToOneInput
+	asMap := map[string]interface{}{}
+	for k, v := range obj.(map[string]interface{}) {
+		asMap[k] = v
+	}
+
+	for k, v := range asMap {
+		switch k {
+		case "connect":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("connect"))
+			it.Connect, err = ec.unmarshalOgroupWhereUniqueInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupWhereUniqueInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "disconnect":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("disconnect"))
+			it.Disconnect, err = ec.unmarshalOgroupWhereUniqueInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupWhereUniqueInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "disconnectAll":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("disconnectAll"))
+			it.DisconnectAll, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		}
+	}
+
+	return it, nil
+}
+
 func (ec *executionContext) unmarshalInputgroupWhereInput(ctx context.Context, obj interface{}) (model.GroupWhereInput, error) {
 	var it model.GroupWhereInput
 	asMap := map[string]interface{}{}
@@ -23670,6 +26328,14 @@ func (ec *executionContext) unmarshalInputmemberOrderByInput(ctx context.Context
 			if err != nil {
 				return it, err
 			}
+		case "groupJoinedDatetime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("groupJoinedDatetime"))
+			it.GroupJoinedDatetime, err = ec.unmarshalOOrderDirection2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐOrderDirection(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		}
 	}
 
@@ -23900,6 +26566,22 @@ func (ec *executionContext) unmarshalInputmemberPrivateUpdateInput(ctx context.C
 			if err != nil {
 				return it, err
 			}
+		case "group":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("group"))
+			it.Group, err = ec.unmarshalOgroupRelateToOneInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupRelateToOneInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "groupJoinedDatetime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("groupJoinedDatetime"))
+			it.GroupJoinedDatetime, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		case "type":
 			var err error
 
@@ -26928,6 +29610,30 @@ func (ec *executionContext) unmarshalInputnewebpayPaymentUpdateInput(ctx context
 			if err != nil {
 				return it, err
 			}
+		case "refundAmount":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("refundAmount"))
+			it.RefundAmount, err = ec.unmarshalOInt2ᚖint(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "refundStatus":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("refundStatus"))
+			it.RefundStatus, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "refundTime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("refundTime"))
+			it.RefundTime, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		case "createdAt":
 			var err error
 
@@ -30503,6 +33209,54 @@ func (ec *executionContext) unmarshalInputsubscriptionCreateInput(ctx context.Co
 			if err != nil {
 				return it, err
 			}
+		case "trialEndDatetime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("trialEndDatetime"))
+			it.TrialEndDatetime, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "isGift":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("isGift"))
+			it.IsGift, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftCode":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftCode"))
+			it.GiftCode, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRecipientEmail":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRecipientEmail"))
+			it.GiftRecipientEmail, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRedeemer":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRedeemer"))
+			it.GiftRedeemer, err = ec.unmarshalOmemberRelateToOneInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐMemberRelateToOneInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRedeemedDatetime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRedeemedDatetime"))
+			it.GiftRedeemedDatetime, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		case "changePlanDatetime":
 			var err error
 
@@ -30685,6 +33439,29 @@ func (ec *executionContext) unmarshalInputsubscriptionCreateInput(ctx context.Co
 	return it, nil
 }
 
+func (ec *executionContext) unmarshalInputsubscriptionGiftCreateInfo(ctx context.Context, obj interface{}) (model.SubscriptionGiftCreateInfo, error) {
+	var it model.SubscriptionGiftCreateInfo
+	asMap := map[string]interface{}{}
+	for k, v := range obj.(map[string]interface{}) {
+		asMap[k] = v
+	}
+
+	for k, v := range asMap {
+		switch k {
+		case "returnToPath":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("returnToPath"))
+			it.ReturnToPath, err = ec.unmarshalNString2string(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		}
+	}
+
+	return it, nil
+}
+
 func (ec *executionContext) unmarshalInputsubscriptionHistoryCreateInput(ctx context.Context, obj interface{}) (model.SubscriptionHistoryCreateInput, error) {
 	var it model.SubscriptionHistoryCreateInput
 	asMap := map[string]interface{}{}
@@ -33401,6 +36178,14 @@ func (ec *executionContext) unmarshalInputsubscriptionOneTimeCreateInfo(ctx cont
 			if err != nil {
 				return it, err
 			}
+		case "promotionCode":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("promotionCode"))
+			it.PromotionCode, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		}
 	}
 
@@ -33919,6 +36704,54 @@ func (ec *executionContext) unmarshalInputsubscriptionPrivateUpdateInput(ctx con
 			if err != nil {
 				return it, err
 			}
+		case "trialEndDatetime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("trialEndDatetime"))
+			it.TrialEndDatetime, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "isGift":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("isGift"))
+			it.IsGift, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftCode":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftCode"))
+			it.GiftCode, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRecipientEmail":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRecipientEmail"))
+			it.GiftRecipientEmail, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRedeemer":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRedeemer"))
+			it.GiftRedeemer, err = ec.unmarshalOmemberRelateToOneInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐMemberRelateToOneInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRedeemedDatetime":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRedeemedDatetime"))
+			it.GiftRedeemedDatetime, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		case "changePlanDatetime":
 			var err error
 
@@ -34118,6 +36951,22 @@ func (ec *executionContext) unmarshalInputsubscriptionRecurringCreateInfo(ctx co
 			if err != nil {
 				return it, err
 			}
+		case "trial":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("trial"))
+			it.Trial, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "promotionCode":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("promotionCode"))
+			it.PromotionCode, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		}
 	}
 
@@ -34323,6 +37172,22 @@ func (ec *executionContext) unmarshalInputsubscriptionWhereInput(ctx context.Con
 			if err != nil {
 				return it, err
 			}
+		case "giftRedeemer":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRedeemer"))
+			it.GiftRedeemer, err = ec.unmarshalOmemberWhereInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐMemberWhereInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftRedeemer_is_null":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftRedeemer_is_null"))
+			it.GiftRedeemerIsNull, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		case "orderNumber":
 			var err error
 
@@ -35163,6 +38028,30 @@ func (ec *executionContext) unmarshalInputsubscriptionWhereInput(ctx context.Con
 			if err != nil {
 				return it, err
 			}
+		case "isGift":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("isGift"))
+			it.IsGift, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "isGift_not":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("isGift_not"))
+			it.IsGiftNot, err = ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+		case "giftCode":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("giftCode"))
+			it.GiftCode, err = ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
 		case "frequency":
 			var err error
 
@@ -37777,8 +40666,20 @@ func (ec *executionContext) _Mutation(ctx context.Context, sel ast.SelectionSet)
 			out.Values[i] = ec._Mutation_createSubscriptionRecurring(ctx, field)
 		case "createsSubscriptionOneTime":
 			out.Values[i] = ec._Mutation_createsSubscriptionOneTime(ctx, field)
+		case "createGiftSubscription":
+			out.Values[i] = ec._Mutation_createGiftSubscription(ctx, field)
+		case "redeemGiftSubscription":
+			out.Values[i] = ec._Mutation_redeemGiftSubscription(ctx, field)
 		case "updatesubscription":
 			out.Values[i] = ec._Mutation_updatesubscription(ctx, field)
+		case "createShareLink":
+			out.Values[i] = ec._Mutation_createShareLink(ctx, field)
+		case "inviteGroupMember":
+			out.Values[i] = ec._Mutation_inviteGroupMember(ctx, field)
+		case "removeGroupMember":
+			out.Values[i] = ec._Mutation_removeGroupMember(ctx, field)
+		case "refundPayment":
+			out.Values[i] = ec._Mutation_refundPayment(ctx, field)
 		default:
 			panic("unknown field " + strconv.Quote(field.Name))
 		}
@@ -38115,6 +41016,75 @@ func (ec *executionContext) _appStorePayment(ctx context.Context, sel ast.Select
 	return out
 }
 
+var checkoutFormImplementors = []string{"checkoutForm"}
+
+func (ec *executionContext) _checkoutForm(ctx context.Context, sel ast.SelectionSet, obj *model.CheckoutForm) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, checkoutFormImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	var invalids uint32
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("checkoutForm")
+		case "paymentMethod":
+			out.Values[i] = ec._checkoutForm_paymentMethod(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "action":
+			out.Values[i] = ec._checkoutForm_action(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "fields":
+			out.Values[i] = ec._checkoutForm_fields(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch()
+	if invalids > 0 {
+		return graphql.Null
+	}
+	return out
+}
+
+var checkoutFormFieldImplementors = []string{"checkoutFormField"}
+
+func (ec *executionContext) _checkoutFormField(ctx context.Context, sel ast.SelectionSet, obj *model.CheckoutFormField) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, checkoutFormFieldImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	var invalids uint32
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("checkoutFormField")
+		case "name":
+			out.Values[i] = ec._checkoutFormField_name(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "value":
+			out.Values[i] = ec._checkoutFormField_value(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch()
+	if invalids > 0 {
+		return graphql.Null
+	}
+	return out
+}
+
 var googlePlayPaymentImplementors = []string{"googlePlayPayment"}
 
 func (ec *executionContext) _googlePlayPayment(ctx context.Context, sel ast.SelectionSet, obj *model.GooglePlayPayment) graphql.Marshaler {
@@ -38207,6 +41177,45 @@ func (ec *executionContext) _group(ctx context.Context, sel ast.SelectionSet, ob
 	return out
 }
 
+var groupMembershipImplementors = []string{"groupMembership"}
+
+func (ec *executionContext) _groupMembership(ctx context.Context, sel ast.SelectionSet, obj *model.GroupMembership) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, groupMembershipImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	var invalids uint32
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("groupMembership")
+		case "groupId":
+			out.Values[i] = ec._groupMembership_groupId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "memberId":
+			out.Values[i] = ec._groupMembership_memberId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "memberCount":
+			out.Values[i] = ec._groupMembership_memberCount(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "seats":
+			out.Values[i] = ec._groupMembership_seats(ctx, field, obj)
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch()
+	if invalids > 0 {
+		return graphql.Null
+	}
+	return out
+}
+
 var invoiceImplementors = []string{"invoice"}
 
 func (ec *executionContext) _invoice(ctx context.Context, sel ast.SelectionSet, obj *model.Invoice) graphql.Marshaler {
@@ -38329,6 +41338,8 @@ func (ec *executionContext) _member(ctx context.Context, sel ast.SelectionSet, o
 			out.Values[i] = ec._member_marketingMembership(ctx, field, obj)
 		case "group":
 			out.Values[i] = ec._member_group(ctx, field, obj)
+		case "groupJoinedDatetime":
+			out.Values[i] = ec._member_groupJoinedDatetime(ctx, field, obj)
 		case "type":
 			out.Values[i] = ec._member_type(ctx, field, obj)
 		case "state":
@@ -38365,6 +41376,10 @@ func (ec *executionContext) _member(ctx context.Context, sel ast.SelectionSet, o
 			out.Values[i] = ec._member_subscription(ctx, field, obj)
 		case "subscriptionCount":
 			out.Values[i] = ec._member_subscriptionCount(ctx, field, obj)
+		case "redeemedGift":
+			out.Values[i] = ec._member_redeemedGift(ctx, field, obj)
+		case "redeemedGiftCount":
+			out.Values[i] = ec._member_redeemedGiftCount(ctx, field, obj)
 		case "createdAt":
 			out.Values[i] = ec._member_createdAt(ctx, field, obj)
 		case "updatedAt":
@@ -38469,6 +41484,10 @@ func (ec *executionContext) _merchandise(ctx context.Context, sel ast.SelectionS
 			out.Values[i] = ec._merchandise_code(ctx, field, obj)
 		case "price":
 			out.Values[i] = ec._merchandise_price(ctx, field, obj)
+		case "trialDays":
+			out.Values[i] = ec._merchandise_trialDays(ctx, field, obj)
+		case "paymentMethod":
+			out.Values[i] = ec._merchandise_paymentMethod(ctx, field, obj)
 		case "currency":
 			out.Values[i] = ec._merchandise_currency(ctx, field, obj)
 		case "state":
@@ -38492,6 +41511,48 @@ func (ec *executionContext) _merchandise(ctx context.Context, sel ast.SelectionS
 	return out
 }
 
+var newebpayFormImplementors = []string{"newebpayForm"}
+
+func (ec *executionContext) _newebpayForm(ctx context.Context, sel ast.SelectionSet, obj *model.NewebpayForm) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, newebpayFormImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	var invalids uint32
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("newebpayForm")
+		case "merchantId":
+			out.Values[i] = ec._newebpayForm_merchantId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "tradeInfo":
+			out.Values[i] = ec._newebpayForm_tradeInfo(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "tradeSha":
+			out.Values[i] = ec._newebpayForm_tradeSha(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "version":
+			out.Values[i] = ec._newebpayForm_version(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch()
+	if invalids > 0 {
+		return graphql.Null
+	}
+	return out
+}
+
 var newebpayPaymentImplementors = []string{"newebpayPayment"}
 
 func (ec *executionContext) _newebpayPayment(ctx context.Context, sel ast.SelectionSet, obj *model.NewebpayPayment) graphql.Marshaler {
@@ -38546,6 +41607,12 @@ func (ec *executionContext) _newebpayPayment(ctx context.Context, sel ast.Select
 			out.Values[i] = ec._newebpayPayment_cardInfoExp(ctx, field, obj)
 		case "frequency":
 			out.Values[i] = ec._newebpayPayment_frequency(ctx, field, obj)
+		case "refundAmount":
+			out.Values[i] = ec._newebpayPayment_refundAmount(ctx, field, obj)
+		case "refundStatus":
+			out.Values[i] = ec._newebpayPayment_refundStatus(ctx, field, obj)
+		case "refundTime":
+			out.Values[i] = ec._newebpayPayment_refundTime(ctx, field, obj)
 		case "createdAt":
 			out.Values[i] = ec._newebpayPayment_createdAt(ctx, field, obj)
 		case "updatedAt":
@@ -38600,6 +41667,58 @@ func (ec *executionContext) _newebpayPaymentInfo(ctx context.Context, sel ast.Se
 	return out
 }
 
+var paymentRefundImplementors = []string{"paymentRefund"}
+
+func (ec *executionContext) _paymentRefund(ctx context.Context, sel ast.SelectionSet, obj *model.PaymentRefund) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, paymentRefundImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	var invalids uint32
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("paymentRefund")
+		case "paymentId":
+			out.Values[i] = ec._paymentRefund_paymentId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "subscriptionId":
+			out.Values[i] = ec._paymentRefund_subscriptionId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "paymentMethod":
+			out.Values[i] = ec._paymentRefund_paymentMethod(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "amount":
+			out.Values[i] = ec._paymentRefund_amount(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "refundedAmount":
+			out.Values[i] = ec._paymentRefund_refundedAmount(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "isCancelled":
+			out.Values[i] = ec._paymentRefund_isCancelled(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch()
+	if invalids > 0 {
+		return graphql.Null
+	}
+	return out
+}
+
 var promotionImplementors = []string{"promotion"}
 
 func (ec *executionContext) _promotion(ctx context.Context, sel ast.SelectionSet, obj *model.Promotion) graphql.Marshaler {
@@ -38643,6 +41762,45 @@ func (ec *executionContext) _promotion(ctx context.Context, sel ast.SelectionSet
 	return out
 }
 
+var shareLinkImplementors = []string{"shareLink"}
+
+func (ec *executionContext) _shareLink(ctx context.Context, sel ast.SelectionSet, obj *model.ShareLink) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, shareLinkImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	var invalids uint32
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("shareLink")
+		case "postId":
+			out.Values[i] = ec._shareLink_postId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "token":
+			out.Values[i] = ec._shareLink_token(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "expiresAt":
+			out.Values[i] = ec._shareLink_expiresAt(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				invalids++
+			}
+		case "remainingQuota":
+			out.Values[i] = ec._shareLink_remainingQuota(ctx, field, obj)
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch()
+	if invalids > 0 {
+		return graphql.Null
+	}
+	return out
+}
+
 var subscriptionImplementors = []string{"subscription"}
 
 func (ec *executionContext) _subscription(ctx context.Context, sel ast.SelectionSet, obj *model.Subscription) graphql.Marshaler {
@@ -38709,6 +41867,18 @@ func (ec *executionContext) _subscription(ctx context.Context, sel ast.Selection
 			out.Values[i] = ec._subscription_periodFirstDatetime(ctx, field, obj)
 		case "periodEndDatetime":
 			out.Values[i] = ec._subscription_periodEndDatetime(ctx, field, obj)
+		case "trialEndDatetime":
+			out.Values[i] = ec._subscription_trialEndDatetime(ctx, field, obj)
+		case "isGift":
+			out.Values[i] = ec._subscription_isGift(ctx, field, obj)
+		case "giftCode":
+			out.Values[i] = ec._subscription_giftCode(ctx, field, obj)
+		case "giftRecipientEmail":
+			out.Values[i] = ec._subscription_giftRecipientEmail(ctx, field, obj)
+		case "giftRedeemer":
+			out.Values[i] = ec._subscription_giftRedeemer(ctx, field, obj)
+		case "giftRedeemedDatetime":
+			out.Values[i] = ec._subscription_giftRedeemedDatetime(ctx, field, obj)
 		case "changePlanDatetime":
 			out.Values[i] = ec._subscription_changePlanDatetime(ctx, field, obj)
 		case "googlePlayStatus":
@@ -38784,6 +41954,10 @@ func (ec *executionContext) _subscriptionCreation(ctx context.Context, sel ast.S
 			}
 		case "newebpayPayload":
 			out.Values[i] = ec._subscriptionCreation_newebpayPayload(ctx, field, obj)
+		case "newebpayForm":
+			out.Values[i] = ec._subscriptionCreation_newebpayForm(ctx, field, obj)
+		case "checkoutForm":
+			out.Values[i] = ec._subscriptionCreation_checkoutForm(ctx, field, obj)
 		default:
 			panic("unknown field " + strconv.Quote(field.Name))
 		}
@@ -38918,6 +42092,16 @@ func (ec *executionContext) _subscriptionInfo(ctx context.Context, sel ast.Selec
 			out.Values[i] = ec._subscriptionInfo_periodFirstDatetime(ctx, field, obj)
 		case "periodEndDatetime":
 			out.Values[i] = ec._subscriptionInfo_periodEndDatetime(ctx, field, obj)
+		case "trialEndDatetime":
+			out.Values[i] = ec._subscriptionInfo_trialEndDatetime(ctx, field, obj)
+		case "isGift":
+			out.Values[i] = ec._subscriptionInfo_isGift(ctx, field, obj)
+		case "giftCode":
+			out.Values[i] = ec._subscriptionInfo_giftCode(ctx, field, obj)
+		case "giftRecipientEmail":
+			out.Values[i] = ec._subscriptionInfo_giftRecipientEmail(ctx, field, obj)
+		case "giftRedeemedDatetime":
+			out.Values[i] = ec._subscriptionInfo_giftRedeemedDatetime(ctx, field, obj)
 		case "changePlanDatetime":
 			out.Values[i] = ec._subscriptionInfo_changePlanDatetime(ctx, field, obj)
 		case "note":
@@ -39349,6 +42533,60 @@ func (ec *executionContext) unmarshalNappStorePaymentWhereInput2ᚖgithubᚗcom
 	return &res, graphql.ErrorOnPath(ctx, err)
 }
 
+func (ec *executionContext) marshalNcheckoutFormField2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCheckoutFormFieldᚄ(ctx context.Context, sel ast.SelectionSet, v []*model.CheckoutFormField) graphql.Marshaler {
+	ret := make(graphql.Array, len(v))
+	var wg sync.WaitGroup
+	isLen1 := len(v) == 1
+	if !isLen1 {
+		wg.Add(len(v))
+	}
+	for i := range v {
+		i := i
+		fc := &graphql.FieldContext{
+			Index:  &i,
+			Result: &v[i],
+		}
+		ctx := graphql.WithFieldContext(ctx, fc)
+		f := func(i int) {
+			defer func() {
+				if r := recover(); r != nil {
+					ec.Error(ctx, ec.Recover(ctx, r))
+					ret = nil
+				}
+			}()
+			if !isLen1 {
+				defer wg.Done()
+			}
+			ret[i] = ec.marshalNcheckoutFormField2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCheckoutFormField(ctx, sel, v[i])
+		}
+		if isLen1 {
+			f(i)
+		} else {
+			go f(i)
+		}
+
+	}
+	wg.Wait()
+
+	for _, e := range ret {
+		if e == graphql.Null {
+			return graphql.Null
+		}
+	}
+
+	return ret
+}
+
+func (ec *executionContext) marshalNcheckoutFormField2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCheckoutFormField(ctx context.Context, sel ast.SelectionSet, v *model.CheckoutFormField) graphql.Marshaler {
+	if v == nil {
+		if !graphql.HasFieldError(ctx, graphql.GetFieldContext(ctx)) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	return ec._checkoutFormField(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalNcreateSubscriptionStatusType2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCreateSubscriptionStatusType(ctx context.Context, v interface{}) (model.CreateSubscriptionStatusType, error) {
 	var res model.CreateSubscriptionStatusType
 	err := res.UnmarshalGQL(v)
@@ -39575,6 +42813,15 @@ func (ec *executionContext) marshalNsubscriptionFrequencyType2githubᚗcomᚋmir
 	return v
 }
 
+func (ec *executionContext) unmarshalNsubscriptionGiftCreateInfo2githubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionGiftCreateInfo(ctx context.Context, v interface{}) (model.SubscriptionGiftCreateInfo, error) {
+	res, err := ec.unmarshalInputsubscriptionGiftCreateInfo(ctx, v)
+	return res, graphql.ErrorOnPath(ctx, err)
+}
+
+func (ec *executionContext) unmarshalNsubscriptionGiftCreateInput2map(ctx context.Context, v interface{}) (map[string]interface{}, error) {
+	return v.(map[string]interface{}), nil
+}
+
 func (ec *executionContext) unmarshalNsubscriptionHistoryWhereInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionHistoryWhereInput(ctx context.Context, v interface{}) (*model.SubscriptionHistoryWhereInput, error) {
 	res, err := ec.unmarshalInputsubscriptionHistoryWhereInput(ctx, v)
 	return &res, graphql.ErrorOnPath(ctx, err)
@@ -40288,6 +43535,13 @@ func (ec *executionContext) unmarshalOappStorePaymentWhereUniqueInput2ᚖgithub
 	return &res, graphql.ErrorOnPath(ctx, err)
 }
 
+func (ec *executionContext) marshalOcheckoutForm2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐCheckoutForm(ctx context.Context, sel ast.SelectionSet, v *model.CheckoutForm) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	return ec._checkoutForm(ctx, sel, v)
+}
+
 func (ec *executionContext) marshalOgooglePlayPayment2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGooglePlayPaymentᚄ(ctx context.Context, sel ast.SelectionSet, v []*model.GooglePlayPayment) graphql.Marshaler {
 	if v == nil {
 		return graphql.Null
@@ -40535,6 +43789,21 @@ func (ec *executionContext) marshalOgroup2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapi
 	return ec._group(ctx, sel, v)
 }
 
+func (ec *executionContext) marshalOgroupMembership2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupMembership(ctx context.Context, sel ast.SelectionSet, v *model.GroupMembership) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	return ec._groupMembership(ctx, sel, v)
+}
+
+func (ec *executionContext) unmarshalOgroupRelateToOneInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupRelateToOneInput(ctx context.Context, v interface{}) (*model.GroupRelateToOneInput, error) {
+	if v == nil {
+		return nil, nil
+	}
+	res, err := ec.unmarshalInputgroupRelateToOneInput(ctx, v)
+	return &res, graphql.ErrorOnPath(ctx, err)
+}
+
 func (ec *executionContext) unmarshalOgroupWhereInput2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupWhereInputᚄ(ctx context.Context, v interface{}) ([]*model.GroupWhereInput, error) {
 	if v == nil {
 		return nil, nil
@@ -40559,6 +43828,14 @@ func (ec *executionContext) unmarshalOgroupWhereInput2ᚕᚖgithubᚗcomᚋmirro
 	return res, nil
 }
 
+func (ec *executionContext) unmarshalOgroupWhereUniqueInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐGroupWhereUniqueInput(ctx context.Context, v interface{}) (*model.GroupWhereUniqueInput, error) {
+	if v == nil {
+		return nil, nil
+	}
+	res, err := ec.unmarshalInputgroupWhereUniqueInput(ctx, v)
+	return &res, graphql.ErrorOnPath(ctx, err)
+}
+
 func (ec *executionContext) marshalOinvoice2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐInvoice(ctx context.Context, sel ast.SelectionSet, v *model.Invoice) graphql.Marshaler {
 	if v == nil {
 		return graphql.Null
@@ -41364,6 +44641,13 @@ func (ec *executionContext) unmarshalOmerchandiseWhereUniqueInput2ᚖgithubᚗco
 	return &res, graphql.ErrorOnPath(ctx, err)
 }
 
+func (ec *executionContext) marshalOnewebpayForm2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐNewebpayForm(ctx context.Context, sel ast.SelectionSet, v *model.NewebpayForm) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	return ec._newebpayForm(ctx, sel, v)
+}
+
 func (ec *executionContext) marshalOnewebpayPayment2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐNewebpayPaymentᚄ(ctx context.Context, sel ast.SelectionSet, v []*model.NewebpayPayment) graphql.Marshaler {
 	if v == nil {
 		return graphql.Null
@@ -41690,6 +44974,13 @@ func (ec *executionContext) unmarshalOnewebpayPaymentWhereUniqueInput2ᚖgithub
 	return &res, graphql.ErrorOnPath(ctx, err)
 }
 
+func (ec *executionContext) marshalOpaymentRefund2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐPaymentRefund(ctx context.Context, sel ast.SelectionSet, v *model.PaymentRefund) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	return ec._paymentRefund(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalOpromotionCreateInput2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐPromotionCreateInput(ctx context.Context, v interface{}) (*model.PromotionCreateInput, error) {
 	if v == nil {
 		return nil, nil
@@ -41892,6 +45183,13 @@ func (ec *executionContext) unmarshalOpromotionWhereInput2ᚕᚖgithubᚗcomᚋm
 	return res, nil
 }
 
+func (ec *executionContext) marshalOshareLink2ᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐShareLink(ctx context.Context, sel ast.SelectionSet, v *model.ShareLink) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	return ec._shareLink(ctx, sel, v)
+}
+
 func (ec *executionContext) marshalOsubscription2ᚕᚖgithubᚗcomᚋmirrorᚑmediaᚋapigatewayᚋgraphᚋmemberᚋmodelᚐSubscriptionᚄ(ctx context.Context, sel ast.SelectionSet, v []*model.Subscription) graphql.Marshaler {
 	if v == nil {
 		return graphql.Null
@@ -10,16 +10,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	graphqlclient "github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/gift"
 	"github.com/mirror-media/apigateway/graph/member/model"
 	"github.com/mirror-media/apigateway/graph/member/mutationgraph/generated"
 	"github.com/mirror-media/apigateway/payment"
 	"github.com/pkg/errors"
-	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -63,6 +63,7 @@ func (r *mutationResolver) Createmember(ctx context.Context, data map[string]int
 		logrus.WithField("mutation", "createmember")
 		return nil, err
 	}
+	r.InvalidateEntitlement(ctx, firebaseID)
 
 	return resp.MemberInfo, err
 }
@@ -104,6 +105,7 @@ func (r *mutationResolver) Updatemember(ctx context.Context, id string, data map
 		logrus.WithField("mutation", "updatemember").Error(err)
 		return nil, err
 	}
+	r.InvalidateEntitlement(ctx, firebaseID)
 
 	return resp.MemberInfo, err
 }
@@ -150,6 +152,7 @@ func (r *mutationResolver) UpsertAppSubscription(ctx context.Context, info model
 		}
 
 		if respData.Status == "success" {
+			r.InvalidateEntitlement(ctx, firebaseID)
 			ret = &model.SubscriptionUpsert{
 				Success: true,
 			}
@@ -193,6 +196,7 @@ func (r *mutationResolver) UpsertAppSubscription(ctx context.Context, info model
 		}
 
 		if respData.Status == "success" {
+			r.InvalidateEntitlement(ctx, firebaseID)
 			ret = &model.SubscriptionUpsert{
 				Success: true,
 			}
@@ -231,112 +235,140 @@ func (r *mutationResolver) CreateSubscriptionRecurring(ctx context.Context, data
 	if state != model.MerchandiseStateTypeActive {
 		return nil, fmt.Errorf("frequency(%s) is not %s", data["frequency"], model.MerchandiseStateTypeActive)
 	}
+	provider, err := r.SelectPaymentProvider(ctx, frequency, true, data)
+	if err != nil {
+		return nil, err
+	}
+	price, releasePromotion, err := r.ApplyPromotion(ctx, info.PromotionCode, model.SubscriptionFrequencyType(frequency), firebaseID, price, data)
+	if err != nil {
+		return nil, err
+	}
+	var trialDays int
+	releaseTrial := func() {}
+	if info.Trial != nil && *info.Trial {
+		trialDays, releaseTrial, err = r.StartTrial(ctx, frequency, firebaseID, time.Now(), data)
+		if err != nil {
+			releasePromotion()
+			return nil, err
+		}
+	}
 	data["nextFrequency"] = data["frequency"]
 	data["amount"] = price
 	data["currency"] = currency
 	data["comment"] = comment
 	data["desc"] = description
-	data["orderNumber"] = "preparing-order-" + xid.New().String()
-
-	// Construct GraphQL mutation
-
-	preGQL := []string{"mutation ($input: subscriptionCreateInput) {", "createsubscription(data: $input) {"}
 
-	subscriptionFieldsOnly := Map(GetPreloads(ctx), func(s string) string {
-		ns := strings.Split(s, ".")
-		if ns[0] == "subscription" && len(ns) == 2 {
-			return ns[len(ns)-1]
-		} else {
-			return ""
+	subscription, creationTimeUnix, err := r.CreateSubscription(ctx, data)
+	if subscription != nil {
+		r.InvalidateEntitlement(ctx, firebaseID)
+	}
+	if err != nil {
+		// a created subscription without its order number gives back the promotion when it's invalidated by the reconciliation, and it has used the trial
+		if subscription == nil {
+			releasePromotion()
 		}
-	})
-
-	preGQL = append(preGQL, subscriptionFieldsOnly...)
-	if !contain(subscriptionFieldsOnly, "createdAt") {
-		preGQL = append(preGQL, "createdAt")
+		releaseTrial()
+		return nil, err
 	}
-	if !contain(subscriptionFieldsOnly, "id") {
-		preGQL = append(preGQL, "id")
+	if trialDays > 0 {
+		if err = r.Trials.Start(ctx, subscription.ID); err != nil {
+			logrus.WithField("subscription", subscription.ID).Warn(err)
+		}
 	}
-	preGQL = append(preGQL, "}", "}")
-	gql := strings.Join(preGQL, "\n")
-	req := graphqlclient.NewRequest(gql)
-	req.Var("input", data)
 
-	var resp struct {
-		SubscriptionInfo *model.SubscriptionInfo `json:"createsubscription"`
-	}
+	return r.CreateCheckout(provider, subscription, payment.Checkout{
+		PurchaseInfo: payment.PurchaseInfo{
+			Merchandise: payment.Merchandise{
+				Code:   frequency,
+				Amount: price,
+			},
+			PurchasedAtUnixTime: creationTimeUnix,
+			OrderNumber:         *subscription.OrderNumber,
+			MemberFirebaseID:    firebaseID,
+			ReturnPath:          info.ReturnToPath,
+			TrialDays:           trialDays,
+		},
+		Amount:       int(price),
+		Email:        data["email"].(string),
+		ItemDesc:     description,
+		OrderComment: comment,
+		TokenTerm:    firebaseID,
+		IsRecurring:  true,
+	})
+}
 
-	err = r.Client.Run(ctx, req, &resp)
+func (r *mutationResolver) CreatesSubscriptionOneTime(ctx context.Context, data map[string]interface{}, info model.SubscriptionOneTimeCreateInfo) (*model.SubscriptionCreation, error) {
+	if data == nil {
+		return nil, fmt.Errorf("data cannot be null")
+	}
 
+	firebaseID, err := r.GetFirebaseID(ctx)
 	if err != nil {
-		logrus.WithField("mutation", "createsubscription").Error(err)
 		return nil, err
 	}
 
-	id, _ := strconv.ParseUint(resp.SubscriptionInfo.ID, 10, 64)
-	orderNumber := createOrderNumberByTaipeiTZ(time.Now(), id)
-
-	resp.SubscriptionInfo.OrderNumber = &orderNumber
-
-	gql = `
-mutation ($id: ID!, $orderNumber: String!) {
-  updatesubscription(id: $id, data: {orderNumber: $orderNumber}) {
-    orderNumber
-  }
-}
-`
-
-	req = graphqlclient.NewRequest(gql)
-	req.Var("id", resp.SubscriptionInfo.ID)
-	req.Var("orderNumber", orderNumber)
+	data["member"] = MemberConnect{
+		Connect: Connect{
+			FirebaseID: firebaseID,
+		},
+	}
+	data["frequency"] = model.SubscriptionFrequencyTypeOneTime.String()
+	data["nextFrequency"] = model.SubscriptionNextFrequencyTypeNone.String()
 
-	err = r.Client.Run(ctx, req, nil)
+	price, currency, state, comment, description, err := r.RetrieveMerchandise(ctx, model.SubscriptionFrequencyTypeOneTime.String())
 	if err != nil {
-		err = errors.Wrapf(err, "update odernumber to subscription(%s) encounter error", resp.SubscriptionInfo.ID)
-		logrus.WithField("mutation", "createsubscription.updatesubscription").Error(err)
 		return nil, err
 	}
-
-	createAt := *resp.SubscriptionInfo.CreatedAt
-
-	t, err := time.Parse(time.RFC3339, createAt)
+	if state != model.MerchandiseStateTypeActive {
+		return nil, fmt.Errorf("frequency(%s) is not %s", model.SubscriptionFrequencyTypeOneTime, model.MerchandiseStateTypeActive)
+	}
+	provider, err := r.SelectPaymentProvider(ctx, model.SubscriptionFrequencyTypeOneTime.String(), false, data)
 	if err != nil {
 		return nil, err
 	}
+	price, releasePromotion, err := r.ApplyPromotion(ctx, info.PromotionCode, model.SubscriptionFrequencyTypeOneTime, firebaseID, price, data)
+	if err != nil {
+		return nil, err
+	}
+	data["amount"] = price
+	data["currency"] = currency
+	data["comment"] = comment
+	data["desc"] = description
 
-	creationTimeUnix := t.Unix()
-
-	payload, err := r.NewebpayStore.CreateNewebpayAgreementPayload(payment.NewebpayAgreementInfo{
-		Amount:              int(price),
-		Email:               data["email"].(string),
-		IsAbleToModifyEmail: r.NewebpayStore.IsAbleToModifyEmail,
-		LoginType:           r.NewebpayStore.LoginType,
-		RespondType:         r.NewebpayStore.RespondType,
-		ItemDesc:            description,
-		OrderComment:        comment,
-		TokenTerm:           firebaseID,
-	}, payment.PurchaseInfo{
-		Merchandise: payment.Merchandise{
-			Code:   frequency,
-			Amount: price,
-		},
-		PurchasedAtUnixTime: creationTimeUnix,
-		OrderNumber:         orderNumber,
-		MemberFirebaseID:    firebaseID,
-		ReturnPath:          info.ReturnToPath,
-	})
+	// a created subscription without its order number gives back the promotion when it's invalidated by the reconciliation
+	subscription, creationTimeUnix, err := r.CreateSubscription(ctx, data)
+	if subscription != nil {
+		r.InvalidateEntitlement(ctx, firebaseID)
+	} else {
+		releasePromotion()
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.SubscriptionCreation{
-		Subscription:    resp.SubscriptionInfo,
-		NewebpayPayload: &payload,
-	}, err
+	return r.CreateCheckout(provider, subscription, payment.Checkout{
+		PurchaseInfo: payment.PurchaseInfo{
+			Merchandise: payment.Merchandise{
+				Code:      model.SubscriptionFrequencyTypeOneTime.String(),
+				PostID:    data["postId"].(string),
+				PostSlug:  info.PostSlug,
+				PostTitle: info.PostTitle,
+				Amount:    price,
+			},
+			PurchasedAtUnixTime: creationTimeUnix,
+			OrderNumber:         *subscription.OrderNumber,
+			MemberFirebaseID:    firebaseID,
+			ReturnPath:          info.ReturnToPath,
+		},
+		Amount:       int(price),
+		Email:        data["email"].(string),
+		ItemDesc:     description,
+		OrderComment: *subscription.OrderNumber,
+		TokenTerm:    firebaseID,
+	})
 }
 
-func (r *mutationResolver) CreatesSubscriptionOneTime(ctx context.Context, data map[string]interface{}, info model.SubscriptionOneTimeCreateInfo) (*model.SubscriptionCreation, error) {
+func (r *mutationResolver) CreateGiftSubscription(ctx context.Context, data map[string]interface{}, info model.SubscriptionGiftCreateInfo) (*model.SubscriptionCreation, error) {
 	if data == nil {
 		return nil, fmt.Errorf("data cannot be null")
 	}
@@ -351,120 +383,130 @@ func (r *mutationResolver) CreatesSubscriptionOneTime(ctx context.Context, data
 			FirebaseID: firebaseID,
 		},
 	}
-	data["frequency"] = model.SubscriptionFrequencyTypeOneTime.String()
+	data["frequency"] = model.SubscriptionFrequencyTypeYearly.String()
 	data["nextFrequency"] = model.SubscriptionNextFrequencyTypeNone.String()
+	// the gift code is assigned when the gift is paid, so it cannot be passed to the recipient before
+	data["isGift"] = true
 
-	price, currency, state, comment, description, err := r.RetrieveMerchandise(ctx, model.SubscriptionFrequencyTypeOneTime.String())
+	price, currency, state, comment, description, err := r.RetrieveMerchandise(ctx, model.SubscriptionFrequencyTypeYearly.String())
 	if err != nil {
 		return nil, err
 	}
 	if state != model.MerchandiseStateTypeActive {
-		return nil, fmt.Errorf("frequency(%s) is not %s", model.SubscriptionFrequencyTypeOneTime, model.MerchandiseStateTypeActive)
+		return nil, fmt.Errorf("frequency(%s) is not %s", model.SubscriptionFrequencyTypeYearly, model.MerchandiseStateTypeActive)
+	}
+	provider, err := r.SelectPaymentProvider(ctx, model.SubscriptionFrequencyTypeYearly.String(), false, data)
+	if err != nil {
+		return nil, err
 	}
 	data["amount"] = price
 	data["currency"] = currency
 	data["comment"] = comment
 	data["desc"] = description
-	data["orderNumber"] = "preparing-order-" + xid.New().String()
-
-	// Construct GraphQL mutation
-
-	preGQL := []string{"mutation ($input: subscriptionCreateInput) {", "createsubscription(data: $input) {"}
 
-	subscriptionFieldsOnly := Map(GetPreloads(ctx), func(s string) string {
-		ns := strings.Split(s, ".")
-		if ns[0] == "subscription" && len(ns) == 2 {
-			return ns[len(ns)-1]
-		} else {
-			return ""
-		}
-	})
-
-	preGQL = append(preGQL, subscriptionFieldsOnly...)
-	if !contain(subscriptionFieldsOnly, "createdAt") {
-		preGQL = append(preGQL, "createdAt")
-	}
-	if !contain(subscriptionFieldsOnly, "id") {
-		preGQL = append(preGQL, "id")
-	}
-	preGQL = append(preGQL, "}", "}")
-	gql := strings.Join(preGQL, "\n")
-	req := graphqlclient.NewRequest(gql)
-	req.Var("input", data)
-
-	var resp struct {
-		SubscriptionInfo *model.SubscriptionInfo `json:"createsubscription"`
+	subscription, creationTimeUnix, err := r.CreateSubscription(ctx, data)
+	if err != nil {
+		return nil, err
 	}
 
-	err = r.Client.Run(ctx, req, &resp)
+	return r.CreateCheckout(provider, subscription, payment.Checkout{
+		PurchaseInfo: payment.PurchaseInfo{
+			Merchandise: payment.Merchandise{
+				Code:   model.SubscriptionFrequencyTypeYearly.String(),
+				Amount: price,
+			},
+			PurchasedAtUnixTime: creationTimeUnix,
+			OrderNumber:         *subscription.OrderNumber,
+			MemberFirebaseID:    firebaseID,
+			ReturnPath:          info.ReturnToPath,
+			IsGift:              true,
+		},
+		Amount:       int(price),
+		Email:        data["email"].(string),
+		ItemDesc:     description,
+		OrderComment: *subscription.OrderNumber,
+		TokenTerm:    firebaseID,
+	})
+}
 
+func (r *mutationResolver) RedeemGiftSubscription(ctx context.Context, giftCode string) (*model.SubscriptionInfo, error) {
+	firebaseID, err := r.GetFirebaseID(ctx)
 	if err != nil {
-		logrus.WithField("mutation", "createsubscription").Error(err)
 		return nil, err
 	}
-	id, _ := strconv.ParseUint(resp.SubscriptionInfo.ID, 10, 64)
-	orderNumber := createOrderNumberByTaipeiTZ(time.Now(), id)
-
-	resp.SubscriptionInfo.OrderNumber = &orderNumber
 
-	gql = `
-	mutation ($id: ID!, $orderNumber: String!) {
-	  updatesubscription(id: $id, data: {orderNumber: $orderNumber}) {
-	    orderNumber
-	  }
+	code, err := gift.NormalizeCode(giftCode)
+	if err != nil {
+		return nil, err
 	}
-`
-
-	req = graphqlclient.NewRequest(gql)
-	req.Var("id", resp.SubscriptionInfo.ID)
-	req.Var("orderNumber", orderNumber)
 
-	err = r.Client.Run(ctx, req, nil)
+	isClaimed, err := r.Gifts.Claim(ctx, code, firebaseID)
 	if err != nil {
-		err = errors.Wrapf(err, "update odernumber to subscription(%s) encounter error", resp.SubscriptionInfo.ID)
-		logrus.WithField("mutation", "createsubscription.updatesubscription").Error(err)
 		return nil, err
+	} else if !isClaimed {
+		return nil, fmt.Errorf("gift(%s) is being redeemed", code)
 	}
+	isRedeemed := false
+	defer func() {
+		if isRedeemed {
+			return
+		}
+		if err := r.Gifts.Release(ctx, code); err != nil {
+			logrus.WithField("mutation", "redeemGiftSubscription").Error(err)
+		}
+	}()
 
-	createAt := *resp.SubscriptionInfo.CreatedAt
-
-	t, err := time.Parse(time.RFC3339, createAt)
+	subscription, err := r.RetrieveGiftSubscription(ctx, code)
 	if err != nil {
 		return nil, err
+	} else if subscription.Status == nil || *subscription.Status != model.SubscriptionStatusTypePaid {
+		return nil, fmt.Errorf("gift(%s) is not %s", code, model.SubscriptionStatusTypePaid)
+	} else if subscription.GiftRedeemer != nil {
+		return nil, fmt.Errorf("gift(%s) has been redeemed", code)
 	}
 
-	creationTimeUnix := t.Unix()
+	// Construct GraphQL mutation
 
-	payload, err := r.NewebpayStore.CreateNewebpayMPGPayload(payment.NewebpayMGPInfo{
-		Amount:              int(price),
-		Email:               data["email"].(string),
-		IsAbleToModifyEmail: r.NewebpayStore.IsAbleToModifyEmail,
-		LoginType:           r.NewebpayStore.LoginType,
-		RespondType:         r.NewebpayStore.RespondType,
-		ItemDescription:     description,
-		OrderComment:        orderNumber,
-		TokenTerm:           firebaseID,
-	}, payment.PurchaseInfo{
-		Merchandise: payment.Merchandise{
-			Code:      model.SubscriptionFrequencyTypeOneTime.String(),
-			PostID:    data["postId"].(string),
-			PostSlug:  info.PostSlug,
-			PostTitle: info.PostTitle,
-			Amount:    price,
-		},
-		PurchasedAtUnixTime: creationTimeUnix,
-		OrderNumber:         orderNumber,
-		MemberFirebaseID:    firebaseID,
-		ReturnPath:          info.ReturnToPath,
+	preGQL := []string{"mutation ($id: ID!, $input: subscriptionPrivateUpdateInput) {", "updatesubscription(id: $id, data: $input) {"}
+
+	fieldsOnly := Map(GetPreloads(ctx), func(s string) string {
+		ns := strings.Split(s, ".")
+		return ns[len(ns)-1]
+	})
+
+	preGQL = append(preGQL, fieldsOnly...)
+	if !contain(fieldsOnly, "id") {
+		preGQL = append(preGQL, "id")
+	}
+	preGQL = append(preGQL, "}", "}")
+	gql := strings.Join(preGQL, "\n")
+	req := graphqlclient.NewRequest(gql)
+	req.Var("id", subscription.ID)
+
+	now := time.Now()
+	req.Var("input", map[string]interface{}{
+		"giftRedeemer":         MemberConnect{Connect: Connect{FirebaseID: firebaseID}},
+		"giftRedeemedDatetime": now.Format(time.RFC3339),
+		"periodFirstDatetime":  now.Format(time.RFC3339),
+		"periodEndDatetime":    gift.Period(now).Format(time.RFC3339),
 	})
+
+	var resp struct {
+		SubscriptionInfo *model.SubscriptionInfo `json:"updatesubscription"`
+	}
+
+	err = r.Client.Run(ctx, req, &resp)
 	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"mutation":   "redeemGiftSubscription",
+			"firebaseId": firebaseID,
+		}).Error(err)
 		return nil, err
 	}
+	isRedeemed = true
+	r.InvalidateEntitlement(ctx, firebaseID)
 
-	return &model.SubscriptionCreation{
-		Subscription:    resp.SubscriptionInfo,
-		NewebpayPayload: &payload,
-	}, err
+	return resp.SubscriptionInfo, err
 }
 
 func (r *mutationResolver) Updatesubscription(ctx context.Context, id string, data map[string]interface{}) (*model.SubscriptionInfo, error) {
@@ -481,13 +523,15 @@ func (r *mutationResolver) Updatesubscription(ctx context.Context, id string, da
 		return nil, errors.New("invalid mutation: you may not un-cancel the subscription")
 	}
 
-	_firebaseID, _frequency, err := r.RetrieveExistingSubscriptionFromRemote(ctx, id)
+	_firebaseID, _frequency, isGift, err := r.RetrieveExistingSubscriptionFromRemote(ctx, id)
 	if err != nil {
 		return nil, err
 	} else if _firebaseID != firebaseID {
 		return nil, fmt.Errorf("you do not have access to this resource, subscription(%s)", id)
 	} else if _frequency == model.SubscriptionFrequencyTypeOneTime.String() {
 		return nil, fmt.Errorf("%s subscription cannot be updated", _frequency)
+	} else if isGift {
+		return nil, fmt.Errorf("gift subscription(%s) cannot be updated", id)
 	}
 
 	if nextFrequency, ok := data["nextFrequency"]; ok {
@@ -527,10 +571,178 @@ func (r *mutationResolver) Updatesubscription(ctx context.Context, id string, da
 		logrus.WithField("mutation", "updatesubscription").Error(err)
 		return nil, err
 	}
+	r.InvalidateEntitlement(ctx, firebaseID)
 
 	return resp.SubscriptionInfo, err
 }
 
+func (r *mutationResolver) CreateShareLink(ctx context.Context, postID string) (*model.ShareLink, error) {
+	if r.ShareLinker == nil {
+		return nil, errors.New("share link is not enabled")
+	}
+
+	firebaseID, err := r.GetFirebaseID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAccessible, err := r.IsPostAccessibleToMember(ctx, firebaseID, postID)
+	if err != nil {
+		return nil, err
+	} else if !isAccessible {
+		return nil, fmt.Errorf("member(%s) has no access to post(%s)", firebaseID, postID)
+	}
+
+	token, expiresAt, remaining, err := r.ShareLinker.Mint(ctx, firebaseID, postID)
+	if err != nil {
+		logrus.WithField("mutation", "createShareLink").Error(err)
+		return nil, err
+	}
+
+	shareLink := &model.ShareLink{
+		PostID:    postID,
+		Token:     token,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}
+	if r.Conf.ShareLink.MonthlyQuota > 0 {
+		shareLink.RemainingQuota = &remaining
+	}
+	return shareLink, err
+}
+
+func (r *mutationResolver) InviteGroupMember(ctx context.Context, groupID string, email string) (*model.GroupMembership, error) {
+	group, err := r.GetGroupAdministeredByRequester(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := r.Privileges.GroupState(*group)
+	if err != nil {
+		return nil, err
+	} else if state != entitlement.StateActive {
+		return nil, fmt.Errorf("group(%s) is %s", groupID, state)
+	}
+	memberCount := 0
+	if group.MemberCount != nil {
+		memberCount = *group.MemberCount
+	}
+	seats := r.Privileges.Seats(groupID)
+	if seats > 0 && memberCount >= seats {
+		return nil, fmt.Errorf("group(%s) has no vacant seat of %d seats", groupID, seats)
+	}
+
+	member, err := r.GetActiveMemberByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	} else if member.Group != nil {
+		return nil, fmt.Errorf("member(%s) already belongs to group(%s)", member.ID, member.Group.ID)
+	} else if member.Type != nil && *member.Type != model.MemberTypeTypeNone && *member.Type != model.MemberTypeTypeSubscribeOneTime {
+		return nil, fmt.Errorf("member(%s) already has premium privilege as %s", member.ID, *member.Type)
+	}
+
+	err = r.UpdateGroupOfMember(ctx, member.ID, groupID, model.MemberTypeTypeSubscribeGroup)
+	if err != nil {
+		return nil, err
+	}
+	if member.FirebaseID != nil {
+		r.InvalidateEntitlement(ctx, *member.FirebaseID)
+	}
+
+	membership := &model.GroupMembership{
+		GroupID:     groupID,
+		MemberID:    member.ID,
+		MemberCount: memberCount + 1,
+	}
+	if seats > 0 {
+		membership.Seats = &seats
+	}
+	return membership, err
+}
+
+func (r *mutationResolver) RemoveGroupMember(ctx context.Context, groupID string, memberID string) (*model.GroupMembership, error) {
+	group, err := r.GetGroupAdministeredByRequester(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := graphqlclient.NewRequest("query ($id: ID!) { member(where: {id: $id}) { id, firebaseId, type, group { id } } }")
+	req.Var("id", memberID)
+
+	var resp struct {
+		Member *model.Member `json:"member"`
+	}
+
+	err = r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("mutation", "removeGroupMember").Error(err)
+		return nil, err
+	} else if resp.Member == nil || resp.Member.Group == nil || resp.Member.Group.ID != groupID {
+		return nil, fmt.Errorf("member(%s) is not found in group(%s)", memberID, groupID)
+	}
+
+	member := resp.Member
+	memberType := model.MemberTypeTypeNone
+	if member.Type != nil && *member.Type != model.MemberTypeTypeSubscribeGroup {
+		memberType = *member.Type
+	}
+	err = r.UpdateGroupOfMember(ctx, memberID, "", memberType)
+	if err != nil {
+		return nil, err
+	}
+	if member.FirebaseID != nil {
+		r.InvalidateEntitlement(ctx, *member.FirebaseID)
+	}
+
+	membership := &model.GroupMembership{
+		GroupID:  groupID,
+		MemberID: memberID,
+	}
+	if group.MemberCount != nil && *group.MemberCount > 0 {
+		membership.MemberCount = *group.MemberCount - 1
+	}
+	if seats := r.Privileges.Seats(groupID); seats > 0 {
+		membership.Seats = &seats
+	}
+	return membership, err
+}
+
+func (r *mutationResolver) RefundPayment(ctx context.Context, id string, amount *int, reason *string, paymentMethod *model.SubscriptionPaymentMethodType) (*model.PaymentRefund, error) {
+	if !r.IsAdmin(ctx) {
+		return nil, fmt.Errorf("only administrators can refund payments")
+	}
+	name := payment.NewebpayProviderName
+	if paymentMethod != nil {
+		name = paymentMethod.String()
+	}
+	provider, err := r.Payments.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var note string
+	if reason != nil {
+		note = *reason
+	}
+	refund, err := r.Settler.Refund(ctx, provider, id, amount, note)
+	// the subscription may be deactivated even if the refund isn't recorded completely
+	if refund.RefundedAmount > 0 && refund.FirebaseID != "" {
+		r.InvalidateEntitlement(ctx, refund.FirebaseID)
+	}
+	if err != nil {
+		logrus.WithField("mutation", "refundPayment").Error(err)
+		return nil, err
+	}
+
+	return &model.PaymentRefund{
+		PaymentID:      refund.PaymentID,
+		SubscriptionID: refund.SubscriptionID,
+		PaymentMethod:  model.SubscriptionPaymentMethodType(refund.Provider),
+		Amount:         refund.Amount,
+		RefundedAmount: refund.RefundedAmount,
+		IsCancelled:    refund.IsCancelled,
+	}, nil
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
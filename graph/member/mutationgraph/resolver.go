@@ -6,17 +6,26 @@ package mutationgraph
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	graphql99 "github.com/99designs/gqlgen/graphql"
 	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/billing"
 	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/gift"
 	"github.com/mirror-media/apigateway/graph/member/model"
 	"github.com/mirror-media/apigateway/middleware"
 	"github.com/mirror-media/apigateway/payment"
+	"github.com/mirror-media/apigateway/paywall"
+	"github.com/mirror-media/apigateway/promotion"
+	"github.com/mirror-media/apigateway/trial"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 
 	"firebase.google.com/go/v4/auth"
@@ -32,10 +41,17 @@ type MemberConnect struct {
 }
 
 type Resolver struct {
-	Client        *graphql.Client
-	Conf          config.Conf
-	UserSvrURL    string
-	NewebpayStore payment.NewebPayStore
+	Client       *graphql.Client
+	Conf         config.Conf
+	UserSvrURL   string
+	Payments     *payment.Providers
+	Settler      *billing.Settler
+	ShareLinker  *paywall.ShareLinker
+	Entitlements *entitlement.Cache
+	Privileges   *entitlement.Privileges
+	Promotions   *promotion.Redeemer
+	Trials       *trial.Tracker
+	Gifts        *gift.Claims
 }
 
 type WebhookPlayStoreResponse struct {
@@ -63,8 +79,8 @@ type Data struct {
 	PeriodCreateDatetime    string `json:"periodCreateDatetime"`
 }
 
-func (r Resolver) RetrieveExistingSubscriptionFromRemote(ctx context.Context, subscriptionID string) (firebaseID, frequency string, err error) {
-	req := graphql.NewRequest("query ($id: ID!) { subscription(where: {id: $id}) { frequency, member { firebaseId } } }")
+func (r Resolver) RetrieveExistingSubscriptionFromRemote(ctx context.Context, subscriptionID string) (firebaseID, frequency string, isGift bool, err error) {
+	req := graphql.NewRequest("query ($id: ID!) { subscription(where: {id: $id}) { frequency, isGift, member { firebaseId } } }")
 	req.Var("id", subscriptionID)
 
 	var resp struct {
@@ -74,13 +90,14 @@ func (r Resolver) RetrieveExistingSubscriptionFromRemote(ctx context.Context, su
 	err = r.Client.Run(ctx, req, &resp)
 	if err != nil {
 		logrus.WithField("query", "RetrieveMemberFirebaseIDOfSubscriptionFromRemote").Error(err)
-		return "", "", err
+		return "", "", false, err
 	} else if resp.Subscription == nil {
-		return "", "", fmt.Errorf("subscription(%s) is not found", subscriptionID)
+		return "", "", false, fmt.Errorf("subscription(%s) is not found", subscriptionID)
 	} else if resp.Subscription.Member == nil {
-		return "", "", fmt.Errorf("member of subscription(%s) is not found", subscriptionID)
+		return "", "", false, fmt.Errorf("member of subscription(%s) is not found", subscriptionID)
 	}
-	return *resp.Subscription.Member.FirebaseID, resp.Subscription.Frequency.String(), err
+	isGift = resp.Subscription.IsGift != nil && *resp.Subscription.IsGift
+	return *resp.Subscription.Member.FirebaseID, resp.Subscription.Frequency.String(), isGift, err
 }
 
 func (r Resolver) RetrieveMerchandise(ctx context.Context, code string) (price float64, currency model.MerchandiseCurrencyType, state model.MerchandiseStateType, comment, description string, err error) {
@@ -130,6 +147,454 @@ func (r Resolver) GetMemberIDFromRemote(ctx context.Context, firebaseID string)
 	return resp.Member.ID, err
 }
 
+// IsPostAccessibleToMember tells if an active member has premium privilege or an active one time subscription to the post
+func (r Resolver) IsPostAccessibleToMember(ctx context.Context, firebaseID, postID string) (bool, error) {
+	gql := `query ($firebaseId: String!, $postId: String!) {
+  member(where: {firebaseId: $firebaseId}) {
+    ` + r.Privileges.MemberFields() + `
+    subscription(where: {frequency: one_time, isActive: true, postId: $postId}) {
+      id
+    }
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("firebaseId", firebaseID)
+	req.Var("postId", postID)
+
+	var resp struct {
+		Member *entitlement.Member `json:"member"`
+	}
+
+	err := r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "IsPostAccessibleToMember").Error(err)
+		return false, err
+	} else if resp.Member == nil {
+		return false, fmt.Errorf("%s is not found", firebaseID)
+	}
+
+	member := resp.Member
+	if member.State == nil || *member.State != model.MemberStateTypeActive {
+		return false, nil
+	}
+	if len(member.Subscription) > 0 {
+		return true, nil
+	}
+	return r.Privileges.HasPremiumPrivilege(ctx, member)
+}
+
+// GetGroupAdministeredByRequester returns the group if the requester is its admin, i.e., the verified email of the requester is the requesterEmail of the group
+func (r Resolver) GetGroupAdministeredByRequester(ctx context.Context, groupID string) (*model.Group, error) {
+	firebaseID, err := r.GetFirebaseID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	firebaseClient, err := FirebaseClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := firebaseClient.GetUser(ctx, firebaseID)
+	if err != nil {
+		logrus.WithField("query", "GetGroupAdministeredByRequester").Error(err)
+		return nil, err
+	}
+
+	gql := `query ($id: ID!) {
+  group(where: {id: $id}) {
+    id
+    startDate
+    endDate
+    memberCount
+    requesterEmail
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("id", groupID)
+
+	var resp struct {
+		Group *model.Group `json:"group"`
+	}
+
+	err = r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "GetGroupAdministeredByRequester").Error(err)
+		return nil, err
+	} else if resp.Group == nil {
+		return nil, fmt.Errorf("group(%s) is not found", groupID)
+	}
+
+	group := resp.Group
+	if !user.EmailVerified || group.RequesterEmail == nil || !strings.EqualFold(user.Email, *group.RequesterEmail) {
+		return nil, fmt.Errorf("member(%s) is not the admin of group(%s)", firebaseID, groupID)
+	}
+	return group, nil
+}
+
+// GetActiveMemberByEmail returns the only active member with the email
+func (r Resolver) GetActiveMemberByEmail(ctx context.Context, email string) (*model.Member, error) {
+	gql := `query ($email: String!) {
+  allMembers(where: {email_i: $email, state: active}) {
+    id
+    firebaseId
+    type
+    group {
+      id
+    }
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("email", email)
+
+	var resp struct {
+		Members []*model.Member `json:"allMembers"`
+	}
+
+	err := r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "GetActiveMemberByEmail").Error(err)
+		return nil, err
+	} else if len(resp.Members) == 0 {
+		return nil, fmt.Errorf("active member with email(%s) is not found", email)
+	} else if len(resp.Members) > 1 {
+		return nil, fmt.Errorf("there are %d active members with email(%s)", len(resp.Members), email)
+	}
+	return resp.Members[0], nil
+}
+
+// UpdateGroupOfMember connects the member to the group, or disconnects the member from any group if groupID is empty, and updates the type of the member. The time of joining decides the seat of the member in the group, and it's only recorded if the member service has groupJoinedDatetime.
+func (r Resolver) UpdateGroupOfMember(ctx context.Context, memberID, groupID string, memberType model.MemberTypeType) error {
+	input := map[string]interface{}{
+		"group": map[string]interface{}{"disconnectAll": true},
+		"type":  memberType,
+	}
+	var joinedAt interface{}
+	if groupID != "" {
+		input["group"] = map[string]interface{}{"connect": map[string]string{"id": groupID}}
+		joinedAt = time.Now().Format(time.RFC3339)
+	}
+	if r.Conf.MemberSchema.HasGroupJoinedDatetime {
+		input["groupJoinedDatetime"] = joinedAt
+	}
+
+	gql := `mutation ($id: ID!, $input: memberUpdateInput) {
+  updatemember(id: $id, data: $input) {
+    id
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("id", memberID)
+	req.Var("input", input)
+
+	err := r.Client.Run(ctx, req, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "updating group of member(%s) encountered error", memberID)
+		logrus.WithField("mutation", "UpdateGroupOfMember").Error(err)
+	}
+	return err
+}
+
+// ApplyPromotion reserves a redemption of the promotion code for the member and returns the discounted price. promoteId in data is set only by a valid code. releasePromotion gives back the reservation if the subscription isn't created, and the settlement gives it back if the subscription fails.
+func (r Resolver) ApplyPromotion(ctx context.Context, code *string, frequency model.SubscriptionFrequencyType, firebaseID string, price float64, data map[string]interface{}) (discountedPrice float64, releasePromotion func(), err error) {
+	delete(data, "promoteId")
+	if code == nil || *code == "" {
+		return price, func() {}, nil
+	}
+
+	p, err := r.Promotions.Retrieve(ctx, *code, frequency)
+	if err != nil {
+		return 0, nil, err
+	}
+	if discountedPrice, err = p.Apply(price); err != nil {
+		return 0, nil, err
+	}
+	if releasePromotion, err = r.Promotions.Reserve(ctx, p, firebaseID); err != nil {
+		return 0, nil, err
+	}
+	data["promoteId"] = p.ID
+	return discountedPrice, releasePromotion, nil
+}
+
+// RetrieveTrialDays returns the trial length of the merchandise. 0 means the merchandise has no trial.
+func (r Resolver) RetrieveTrialDays(ctx context.Context, code string) (int, error) {
+	gql := `query ($code: String) {
+  merchandise(where: {code: $code}) {
+    trialDays
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("code", code)
+
+	var resp struct {
+		Merchandise *model.Merchandise `json:"merchandise"`
+	}
+
+	err := r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "RetrieveTrialDays").Error(err)
+		return 0, err
+	} else if resp.Merchandise == nil {
+		return 0, fmt.Errorf("merchandise with code %s is not found", code)
+	} else if resp.Merchandise.TrialDays == nil {
+		return 0, nil
+	}
+	return *resp.Merchandise.TrialDays, nil
+}
+
+// HasUsedTrial tells if the member has a subscription with a trial, whatever its state is
+func (r Resolver) HasUsedTrial(ctx context.Context, firebaseID string) (bool, error) {
+	gql := `query ($firebaseId: String!) {
+  allSubscriptions(where: {member: {firebaseId: $firebaseId}, trialEndDatetime_not: null}, first: 1) {
+    id
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("firebaseId", firebaseID)
+
+	var resp struct {
+		Subscriptions []*model.Subscription `json:"allSubscriptions"`
+	}
+
+	err := r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "HasUsedTrial").Error(err)
+		return false, err
+	}
+	return len(resp.Subscriptions) > 0, nil
+}
+
+// StartTrial claims the free trial of the merchandise for the member, and sets the end of the trial in data as the next pay datetime. Each member has only one free trial, which is used once the member has a subscription with trialEndDatetime, so trials are only started if the member service has the field. release gives back the claim if the subscription fails to be created.
+func (r Resolver) StartTrial(ctx context.Context, code string, firebaseID string, now time.Time, data map[string]interface{}) (trialDays int, release func(), err error) {
+	release = func() {}
+	if !r.Conf.MemberSchema.HasTrialEndDatetime {
+		return 0, release, errors.New("free trials are not enabled")
+	}
+	if trialDays, err = r.RetrieveTrialDays(ctx, code); err != nil {
+		return 0, release, err
+	} else if !trial.Days[trialDays] {
+		return 0, release, fmt.Errorf("merchandise(%s) has no valid trial", code)
+	}
+
+	// the claim keeps another trial of the member from being started until the subscription is created
+	isClaimed, err := r.Trials.Claim(ctx, firebaseID)
+	if err != nil {
+		return 0, release, err
+	} else if !isClaimed {
+		return 0, release, fmt.Errorf("member(%s) is starting another trial", firebaseID)
+	}
+	release = func() {
+		if err := r.Trials.Release(ctx, firebaseID); err != nil {
+			logrus.WithField("firebaseId", firebaseID).Error(err)
+		}
+	}
+
+	isUsed, err := r.HasUsedTrial(ctx, firebaseID)
+	if err != nil {
+		release()
+		return 0, func() {}, err
+	} else if isUsed {
+		release()
+		return 0, func() {}, fmt.Errorf("member(%s) has used the free trial", firebaseID)
+	}
+
+	trialEnd := now.AddDate(0, 0, trialDays).Format(time.RFC3339)
+	data["trialEndDatetime"] = trialEnd
+	data["periodNextPayDatetime"] = trialEnd
+	return trialDays, release, nil
+}
+
+// RetrieveGiftSubscription returns the gift subscription with the code
+func (r Resolver) RetrieveGiftSubscription(ctx context.Context, code string) (*model.Subscription, error) {
+	gql := `query ($code: String!) {
+  allSubscriptions(where: {giftCode: $code, isGift: true}) {
+    id
+    status
+    giftRedeemer {
+      id
+    }
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("code", code)
+
+	var resp struct {
+		Subscriptions []*model.Subscription `json:"allSubscriptions"`
+	}
+
+	err := r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "RetrieveGiftSubscription").Error(err)
+		return nil, err
+	} else if len(resp.Subscriptions) == 0 {
+		return nil, fmt.Errorf("gift(%s) is not found", code)
+	}
+	return resp.Subscriptions[0], nil
+}
+
+// RetrieveMerchandisePaymentMethod returns the payment provider of the merchandise. It's empty if the merchandise has no preference.
+func (r Resolver) RetrieveMerchandisePaymentMethod(ctx context.Context, code string) (string, error) {
+	gql := `query ($code: String) {
+  merchandise(where: {code: $code}) {
+    paymentMethod
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("code", code)
+
+	var resp struct {
+		Merchandise *model.Merchandise `json:"merchandise"`
+	}
+
+	err := r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("query", "RetrieveMerchandisePaymentMethod").Error(err)
+		return "", err
+	} else if resp.Merchandise == nil {
+		return "", fmt.Errorf("merchandise with code %s is not found", code)
+	} else if resp.Merchandise.PaymentMethod == nil {
+		return "", nil
+	}
+	return resp.Merchandise.PaymentMethod.String(), nil
+}
+
+// SelectPaymentProvider returns the provider of paymentMethod in data, or of the merchandise, or the default provider, and sets paymentMethod in data to it
+func (r Resolver) SelectPaymentProvider(ctx context.Context, code string, isRecurring bool, data map[string]interface{}) (payment.Provider, error) {
+	var name string
+	var err error
+	if method, ok := data["paymentMethod"]; ok && method != nil {
+		name = fmt.Sprint(method)
+	} else if name, err = r.RetrieveMerchandisePaymentMethod(ctx, code); err != nil {
+		return nil, err
+	}
+
+	provider, err := r.Payments.Get(name)
+	if err != nil {
+		return nil, err
+	} else if isRecurring && !provider.SupportsRecurring() {
+		return nil, fmt.Errorf("payment provider(%s) doesn't support recurring subscriptions", provider.Name())
+	}
+	data["paymentMethod"] = provider.Name()
+	return provider, nil
+}
+
+// CreateCheckout creates the form to pay the subscription with the provider. newebpayPayload and newebpayForm are kept for the clients of NewebPay not using checkoutForm yet.
+func (r Resolver) CreateCheckout(provider payment.Provider, subscription *model.SubscriptionInfo, checkout payment.Checkout) (*model.SubscriptionCreation, error) {
+	form, err := provider.CreateCheckout(checkout)
+	if err != nil {
+		logrus.WithField("mutation", "CreateCheckout").Error(err)
+		return nil, err
+	}
+
+	names := make([]string, 0, len(form.Fields))
+	for name := range form.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]*model.CheckoutFormField, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, &model.CheckoutFormField{
+			Name:  name,
+			Value: form.Fields[name],
+		})
+	}
+
+	creation := &model.SubscriptionCreation{
+		Subscription: subscription,
+		CheckoutForm: &model.CheckoutForm{
+			PaymentMethod: model.SubscriptionPaymentMethodType(provider.Name()),
+			Action:        form.Action,
+			Fields:        fields,
+		},
+	}
+	if provider.Name() == payment.NewebpayProviderName {
+		creation.NewebpayPayload = &form.Payload
+		creation.NewebpayForm = &model.NewebpayForm{
+			MerchantID: form.Fields["MerchantID"],
+			TradeInfo:  form.Fields["TradeInfo"],
+			TradeSha:   form.Fields["TradeSha"],
+			Version:    form.Fields["Version"],
+		}
+	}
+	return creation, nil
+}
+
+// CreateSubscription creates the subscription of data with the fields queried by the mutation and assigns its order number. It returns the subscription with the order number and the unix time it's created. subscription is not nil if it's created even if the order number fails to be assigned.
+func (r Resolver) CreateSubscription(ctx context.Context, data map[string]interface{}) (subscription *model.SubscriptionInfo, createdAtUnixTime int64, err error) {
+	data["orderNumber"] = "preparing-order-" + xid.New().String()
+
+	// Construct GraphQL mutation
+
+	preGQL := []string{"mutation ($input: subscriptionCreateInput) {", "createsubscription(data: $input) {"}
+
+	subscriptionFieldsOnly := Map(GetPreloads(ctx), func(s string) string {
+		ns := strings.Split(s, ".")
+		if ns[0] == "subscription" && len(ns) == 2 {
+			return ns[len(ns)-1]
+		} else {
+			return ""
+		}
+	})
+
+	preGQL = append(preGQL, subscriptionFieldsOnly...)
+	if !contain(subscriptionFieldsOnly, "createdAt") {
+		preGQL = append(preGQL, "createdAt")
+	}
+	if !contain(subscriptionFieldsOnly, "id") {
+		preGQL = append(preGQL, "id")
+	}
+	preGQL = append(preGQL, "}", "}")
+	gql := strings.Join(preGQL, "\n")
+	req := graphql.NewRequest(gql)
+	req.Var("input", data)
+
+	var resp struct {
+		SubscriptionInfo *model.SubscriptionInfo `json:"createsubscription"`
+	}
+
+	err = r.Client.Run(ctx, req, &resp)
+	if err != nil {
+		logrus.WithField("mutation", "createsubscription").Error(err)
+		return nil, 0, err
+	}
+	subscription = resp.SubscriptionInfo
+
+	id, _ := strconv.ParseUint(subscription.ID, 10, 64)
+	orderNumber := createOrderNumberByTaipeiTZ(time.Now(), id)
+
+	subscription.OrderNumber = &orderNumber
+
+	gql = `
+mutation ($id: ID!, $orderNumber: String!) {
+  updatesubscription(id: $id, data: {orderNumber: $orderNumber}) {
+    orderNumber
+  }
+}
+`
+
+	req = graphql.NewRequest(gql)
+	req.Var("id", subscription.ID)
+	req.Var("orderNumber", orderNumber)
+
+	err = r.Client.Run(ctx, req, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "update odernumber to subscription(%s) encounter error", subscription.ID)
+		logrus.WithField("mutation", "createsubscription.updatesubscription").Error(err)
+		return subscription, 0, err
+	}
+
+	t, err := time.Parse(time.RFC3339, *subscription.CreatedAt)
+	if err != nil {
+		return subscription, 0, err
+	}
+	return subscription, t.Unix(), nil
+}
+
+// InvalidateEntitlement makes the cached entitlement of the member to be refetched after a mutation changes it. Failures are only logged because the cache expires shortly anyway.
+func (r Resolver) InvalidateEntitlement(ctx context.Context, firebaseID string) {
+	if err := r.Entitlements.Invalidate(ctx, firebaseID); err != nil {
+		logrus.WithField("firebaseId", firebaseID).Warn(err)
+	}
+}
+
 func (r Resolver) GetFirebaseID(ctx context.Context) (string, error) {
 
 	gCTX, err := GinContextFromContext(ctx)
@@ -142,6 +607,15 @@ func (r Resolver) GetFirebaseID(ctx context.Context) (string, error) {
 	return id, err
 }
 
+// IsAdmin tells if the request is authenticated by the admin token
+func (r Resolver) IsAdmin(ctx context.Context) bool {
+	gCTX, err := GinContextFromContext(ctx)
+	if err != nil {
+		return false
+	}
+	return gCTX.GetBool(middleware.GCtxIsAdminKey)
+}
+
 func (r Resolver) IsRequestMatchingRequesterFirebaseID(ctx context.Context, userID string) (bool, error) {
 
 	gCTX, err := GinContextFromContext(ctx)
@@ -0,0 +1,222 @@
+// Package institution grants premium access to the readers in the IP ranges or signed in with the SSO of the institutions with licences
+package institution
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+)
+
+// usageRetention is how long the monthly usage counters are kept
+const usageRetention = 400 * 24 * time.Hour
+
+// Institution is a licensee of which readers are granted premium access by their IPs or their sign-in providers
+type Institution struct {
+	ID           string
+	Name         string
+	networks     []*net.IPNet
+	ssoProviders map[string]bool
+}
+
+// Registry matches requests to the institutions and counts their usage
+type Registry struct {
+	rdb            cache.Rediser
+	institutions   []Institution
+	trustedProxies TrustedProxies
+	location       *time.Location
+	now            func() time.Time
+}
+
+// NewRegistry returns nil if no institution is configured. The usage counters bypass the local cache of rdb.
+func NewRegistry(rdb cache.Rediser, c config.Institutions) (*Registry, error) {
+	if len(c.Registry) == 0 {
+		return nil, nil
+	}
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load the location for institutions")
+	}
+	r := &Registry{
+		rdb:      cache.Unwrap(rdb),
+		location: tz,
+		now:      time.Now,
+	}
+	if r.trustedProxies, err = NewTrustedProxies(c.TrustedProxies); err != nil {
+		return nil, err
+	}
+	ssoInstitutions := make(map[string]string)
+	for _, i := range c.Registry {
+		if i.ID == "" {
+			return nil, fmt.Errorf("institution(%s) has no ID", i.Name)
+		}
+		networks, err := parseCIDRs(i.CIDRs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "institution(%s) has invalid CIDRs", i.ID)
+		}
+		ssoProviders := make(map[string]bool, len(i.SSOProviders))
+		for _, provider := range i.SSOProviders {
+			// only the providers set up for institutions are accepted, so a public provider like google.com can't grant everyone
+			if !strings.HasPrefix(provider, "saml.") && !strings.HasPrefix(provider, "oidc.") {
+				return nil, fmt.Errorf("SSO provider(%s) of institution(%s) is neither SAML nor OIDC", provider, i.ID)
+			} else if other, ok := ssoInstitutions[provider]; ok {
+				return nil, fmt.Errorf("SSO provider(%s) is shared by institution(%s) and institution(%s)", provider, other, i.ID)
+			}
+			ssoInstitutions[provider] = i.ID
+			ssoProviders[provider] = true
+		}
+		r.institutions = append(r.institutions, Institution{
+			ID:           i.ID,
+			Name:         i.Name,
+			networks:     networks,
+			ssoProviders: ssoProviders,
+		})
+	}
+	return r, nil
+}
+
+// parseCIDRs parses CIDRs or single IPs
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, s := range cidrs {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP(%s)", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedProxies are the load balancers in front of the gateway. Only the X-Forwarded-For appended by them is honoured.
+type TrustedProxies []*net.IPNet
+
+// NewTrustedProxies parses the CIDRs or single IPs of the proxies
+func NewTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid trusted proxies")
+	}
+	return networks, nil
+}
+
+// ClientIP returns the IP of the client. X-Forwarded-For is walked from the right, i.e., the nearest hop, and the first address not in the trusted proxies is the client, so addresses forged by the client are never used.
+func (t TrustedProxies) ClientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(req.RemoteAddr)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !contains(t, ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// the chain is broken, so the last trusted address is the client
+			return ip
+		}
+		ip = hop
+		if !contains(t, ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// ClientIP returns the IP of the client walked through the trusted proxies of the registry
+func (r *Registry) ClientIP(req *http.Request) net.IP {
+	return r.trustedProxies.ClientIP(req)
+}
+
+// Match returns the institution of which the IP ranges contain the client IP of the request
+func (r *Registry) Match(req *http.Request) (Institution, bool) {
+	if r == nil {
+		return Institution{}, false
+	}
+	ip := r.ClientIP(req)
+	if ip == nil {
+		return Institution{}, false
+	}
+	for _, i := range r.institutions {
+		if contains(i.networks, ip) {
+			return i, true
+		}
+	}
+	return Institution{}, false
+}
+
+// MatchSignInProvider returns the institution of which the SSO is the Firebase sign-in provider of a verified token
+func (r *Registry) MatchSignInProvider(provider string) (Institution, bool) {
+	if r == nil || provider == "" {
+		return Institution{}, false
+	}
+	for _, i := range r.institutions {
+		if i.ssoProviders[provider] {
+			return i, true
+		}
+	}
+	return Institution{}, false
+}
+
+func (r *Registry) usageKey(institutionID string, month time.Time) string {
+	return fmt.Sprintf("%s.%s.%s.%s", "apigateway", "institution", institutionID, month.In(r.location).Format("200601"))
+}
+
+// CountUsage counts a request granted by the institution in the current month
+func (r *Registry) CountUsage(ctx context.Context, institutionID string) error {
+	key := r.usageKey(institutionID, r.now())
+	n, err := r.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return errors.Wrapf(err, "counting usage of institution(%s) encountered error", institutionID)
+	}
+	if n == 1 {
+		if err = r.rdb.Expire(ctx, key, usageRetention).Err(); err != nil {
+			return errors.Wrapf(err, "setting expiration of usage(%s) encountered error", key)
+		}
+	}
+	return nil
+}
+
+// Usage returns the numbers of requests granted by each institution in the month
+func (r *Registry) Usage(ctx context.Context, month time.Time) (map[string]int64, error) {
+	usage := make(map[string]int64, len(r.institutions))
+	for _, i := range r.institutions {
+		n, err := r.rdb.Get(ctx, r.usageKey(i.ID, month)).Int64()
+		if err != nil && err != redis.Nil {
+			return nil, errors.Wrapf(err, "reading usage of institution(%s) encountered error", i.ID)
+		}
+		usage[i.ID] = n
+	}
+	return usage, nil
+}
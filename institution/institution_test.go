@@ -0,0 +1,209 @@
+package institution
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+)
+
+// memoryRediser implements Get, Incr and Expire of cache.Rediser in memory. Expiration is ignored.
+type memoryRediser struct {
+	cache.Rediser
+	counters map[string]int64
+}
+
+func (m *memoryRediser) Get(ctx context.Context, key string) *redis.StringCmd {
+	n, ok := m.counters[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(strconv.FormatInt(n, 10), nil)
+}
+
+func (m *memoryRediser) Incr(ctx context.Context, key string) *redis.IntCmd {
+	m.counters[key]++
+	return redis.NewIntResult(m.counters[key], nil)
+}
+
+func (m *memoryRediser) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func newTestRegistry(t *testing.T) *Registry {
+	r, err := NewRegistry(&memoryRediser{counters: map[string]int64{}}, config.Institutions{
+		Registry: []config.Institution{
+			{ID: "library", Name: "Library", CIDRs: []string{"203.0.113.0/24"}, SSOProviders: []string{"saml.library"}},
+			{ID: "campus", Name: "Campus", CIDRs: []string{"198.51.100.7", "2001:db8::/32"}, SSOProviders: []string{"oidc.campus", "saml.campus"}},
+		},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	})
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	return r
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       config.Institutions
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty registry", wantNil: true},
+		{
+			name:    "invalid CIDR",
+			c:       config.Institutions{Registry: []config.Institution{{ID: "library", CIDRs: []string{"203.0.113.0/33"}}}},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "missing ID",
+			c:       config.Institutions{Registry: []config.Institution{{Name: "Library", CIDRs: []string{"203.0.113.0/24"}}}},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "invalid trusted proxy",
+			c:       config.Institutions{Registry: []config.Institution{{ID: "library"}}, TrustedProxies: []string{"proxy"}},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "public SSO provider",
+			c:       config.Institutions{Registry: []config.Institution{{ID: "library", SSOProviders: []string{"google.com"}}}},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "shared SSO provider",
+			c:       config.Institutions{Registry: []config.Institution{{ID: "library", SSOProviders: []string{"saml.city"}}, {ID: "campus", SSOProviders: []string{"saml.city"}}}},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name: "valid",
+			c:    config.Institutions{Registry: []config.Institution{{ID: "library", CIDRs: []string{"203.0.113.1"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRegistry(nil, tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRegistry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("NewRegistry() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestRegistry_Match(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		wantID     string
+		wantOK     bool
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.5:1234", wantID: "library", wantOK: true},
+		{name: "single IP", remoteAddr: "198.51.100.7:1234", wantID: "campus", wantOK: true},
+		{name: "IPv6", remoteAddr: "[2001:db8::1]:1234", wantID: "campus", wantOK: true},
+		{name: "outsider", remoteAddr: "192.0.2.1:1234"},
+		{name: "through trusted proxies", remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.5, 10.0.0.2"}, wantID: "library", wantOK: true},
+		{name: "multiple headers", remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.5", "10.0.0.2"}, wantID: "library", wantOK: true},
+		{name: "forged by the client", remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.5, 192.0.2.1"}},
+		{name: "not forwarded by a trusted proxy", remoteAddr: "192.0.2.1:1234", xff: []string{"203.0.113.5"}},
+		{name: "broken chain", remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.5, unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v0/posts", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			got, ok := r.Match(req)
+			if ok != tt.wantOK || got.ID != tt.wantID {
+				t.Errorf("Match() = %v, %v, want %v, %v", got.ID, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegistry_MatchSignInProvider(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		provider string
+		wantID   string
+		wantOK   bool
+	}{
+		{provider: "saml.library", wantID: "library", wantOK: true},
+		{provider: "saml.campus", wantID: "campus", wantOK: true},
+		{provider: "oidc.campus", wantID: "campus", wantOK: true},
+		{provider: "google.com"},
+		{provider: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			got, ok := r.MatchSignInProvider(tt.provider)
+			if ok != tt.wantOK || got.ID != tt.wantID {
+				t.Errorf("MatchSignInProvider() = %v, %v, want %v, %v", got.ID, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegistry_Match_nil(t *testing.T) {
+	var r *Registry
+	req := httptest.NewRequest("GET", "/api/v0/posts", nil)
+	if _, ok := r.Match(req); ok {
+		t.Error("Match() of a nil registry should not match")
+	}
+	if _, ok := r.MatchSignInProvider("saml.library"); ok {
+		t.Error("MatchSignInProvider() of a nil registry should not match")
+	}
+}
+
+func TestRegistry_Usage(t *testing.T) {
+	r := newTestRegistry(t)
+	r.now = func() time.Time { return time.Date(2021, 10, 31, 17, 0, 0, 0, time.UTC) } // 2021-11-01 01:00 in Taipei
+
+	ctx := context.Background()
+	for _, id := range []string{"library", "library", "campus"} {
+		if err := r.CountUsage(ctx, id); err != nil {
+			t.Fatalf("CountUsage() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		month time.Time
+		want  map[string]int64
+	}{
+		{name: "counted month", month: time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC), want: map[string]int64{"library": 2, "campus": 1}},
+		{name: "previous month", month: time.Date(2021, 10, 15, 0, 0, 0, 0, time.UTC), want: map[string]int64{"library": 0, "campus": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Usage(ctx, tt.month)
+			if err != nil {
+				t.Fatalf("Usage() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Usage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -129,6 +130,27 @@ func SetUserID(firebaseClient *auth.Client) gin.HandlerFunc {
 	}
 }
 
+// AuthenticateAdminToken is a middleware to reject the request unless it has the admin token as the bearer token. The gin context is saved to the request context for the resolvers.
+func AuthenticateAdminToken(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logrus.WithFields(logrus.Fields{
+			"path": c.FullPath(),
+		})
+		bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(adminToken)) != 1 {
+			err := errors.New("invalid admin token")
+			logger.Info(err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+		c.Set(GCtxIsAdminKey, true)
+		ginContextToContextMiddleware(c)
+		c.Next()
+	}
+}
+
 // AuthenticateIDToken is a middleware to authenticate the request and save the result to the context
 func AuthenticateIDToken(firebaseClient *auth.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
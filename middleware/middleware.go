@@ -25,6 +25,8 @@ const (
 	GCtxUserIDKey string = "GCtxUserID"
 	// GCtxIsPremiumKey is the key of a boolean value to show the premium status of the member/anonymous
 	GCtxIsPremiumKey = "GCtxIsPremiumMember"
+	// GCtxIsAdminKey is the key of a boolean value to show the request has the admin token
+	GCtxIsAdminKey = "GCtxIsAdmin"
 )
 
 // PrintPayloadDebug prints the request body to stdout. Do not use it in production
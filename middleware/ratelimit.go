@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/institution"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultRateLimitWindow = 60 // seconds
+
+// rateLimiter counts requests in fixed windows in redis and estimates the sliding window by weighting the count of the previous window by its overlap
+type rateLimiter struct {
+	rdb      cache.Rediser
+	group    string
+	requests int
+	window   time.Duration
+	now      func() time.Time
+}
+
+func (l *rateLimiter) key(subject string, windowIndex int64) string {
+	return fmt.Sprintf("%s.%s.%s.%s.%d", "apigateway", "ratelimit", l.group, subject, windowIndex)
+}
+
+// take counts a request of the subject. retryAfter is how long the subject should wait if the request isn't allowed.
+func (l *rateLimiter) take(ctx context.Context, subject string) (isAllowed bool, retryAfter time.Duration, err error) {
+	now := l.now()
+	windowIndex := now.UnixNano() / int64(l.window)
+	windowStart := time.Unix(0, windowIndex*int64(l.window))
+
+	key := l.key(subject, windowIndex)
+	current, err := l.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if current == 1 {
+		// the count is still needed as the previous window of the next one
+		if err = l.rdb.Expire(ctx, key, 2*l.window).Err(); err != nil {
+			return false, 0, err
+		}
+	}
+	previous, err := l.rdb.Get(ctx, l.key(subject, windowIndex-1)).Int64()
+	if err != nil && err != redis.Nil {
+		return false, 0, err
+	}
+
+	overlap := 1 - float64(now.Sub(windowStart))/float64(l.window)
+	if estimated := float64(previous)*overlap + float64(current); estimated <= float64(l.requests) {
+		return true, 0, nil
+	}
+	if retryAfter = windowStart.Add(l.window).Sub(now); retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return false, retryAfter, nil
+}
+
+// RateLimit throttles the requests of the route group by the firebase ID set by the previous middlewares, or the client IP behind the trusted proxies if there's none. Throttled requests are replied with 429 and Retry-After. Requests are let through if redis fails. The counters bypass the local cache of rdb, so every replica reads the latest counts.
+func RateLimit(rdb cache.Rediser, group string, c config.RateLimit, trustedProxies institution.TrustedProxies) gin.HandlerFunc {
+	if c.Requests <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	rdb = cache.Unwrap(rdb)
+	window := c.Window
+	if window <= 0 {
+		window = defaultRateLimitWindow
+	}
+	limiter := &rateLimiter{
+		rdb:      rdb,
+		group:    group,
+		requests: c.Requests,
+		window:   time.Duration(window) * time.Second,
+		now:      time.Now,
+	}
+
+	return func(c *gin.Context) {
+		subject := "ip:" + trustedProxies.ClientIP(c.Request).String()
+		if firebaseID := c.GetString(GCtxUserIDKey); firebaseID != "" {
+			subject = "member:" + firebaseID
+		}
+
+		isAllowed, retryAfter, err := limiter.take(c.Request.Context(), subject)
+		if err != nil {
+			logrus.WithField("path", c.FullPath()).Warnf("rate limiting %s encountered error: %v", subject, err)
+			c.Next()
+			return
+		} else if !isAllowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorReply{
+				Errors: []Error{{Message: http.StatusText(http.StatusTooManyRequests)}},
+			})
+			return
+		}
+		c.Next()
+	}
+}
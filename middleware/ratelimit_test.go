@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/institution"
+)
+
+// counterRediser implements Incr, Expire and Get of cache.Rediser in memory
+type counterRediser struct {
+	cache.Rediser
+	counts map[string]int64
+}
+
+func (r *counterRediser) Incr(ctx context.Context, key string) *redis.IntCmd {
+	r.counts[key]++
+	return redis.NewIntResult(r.counts[key], nil)
+}
+
+func (r *counterRediser) Expire(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func (r *counterRediser) Get(ctx context.Context, key string) *redis.StringCmd {
+	n, ok := r.counts[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(strconv.FormatInt(n, 10), nil)
+}
+
+func Test_rateLimiter_take(t *testing.T) {
+	start := time.Unix(6000, 0)
+	now := start
+	l := &rateLimiter{
+		rdb:      &counterRediser{counts: make(map[string]int64)},
+		group:    "v0",
+		requests: 2,
+		window:   time.Minute,
+		now:      func() time.Time { return now },
+	}
+
+	tests := []struct {
+		name           string
+		at             time.Duration
+		subject        string
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "first", at: 0, subject: "a", wantAllowed: true},
+		{name: "second", at: 10 * time.Second, subject: "a", wantAllowed: true},
+		{name: "over the limit", at: 20 * time.Second, subject: "a", wantAllowed: false, wantRetryAfter: 40 * time.Second},
+		{name: "another subject", at: 20 * time.Second, subject: "b", wantAllowed: true},
+		// 3 requests in the previous window are weighted by 3/4
+		{name: "previous window still counts", at: 75 * time.Second, subject: "a", wantAllowed: false, wantRetryAfter: 45 * time.Second},
+		// the throttled request in the previous window is weighted by 1/2
+		{name: "previous window fades out", at: 150 * time.Second, subject: "a", wantAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start.Add(tt.at)
+			isAllowed, retryAfter, err := l.take(context.Background(), tt.subject)
+			if err != nil {
+				t.Fatalf("rateLimiter.take() error = %v", err)
+			}
+			if isAllowed != tt.wantAllowed || retryAfter != tt.wantRetryAfter {
+				t.Errorf("rateLimiter.take() = (%v, %v), want (%v, %v)", isAllowed, retryAfter, tt.wantAllowed, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	trustedProxies, _ := institution.NewTrustedProxies([]string{"10.0.0.0/8"})
+	r := gin.New()
+	r.Use(RateLimit(&counterRediser{counts: make(map[string]int64)}, "v2", config.RateLimit{Requests: 1}, trustedProxies))
+	r.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// the cases run in order against the same counters
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantStatus   int
+	}{
+		{name: "first request of a client", remoteAddr: "10.0.0.1:1234", forwardedFor: "1.1.1.1", wantStatus: http.StatusOK},
+		{name: "same client behind another proxy", remoteAddr: "10.0.0.2:1234", forwardedFor: "1.1.1.1", wantStatus: http.StatusTooManyRequests},
+		{name: "another client", remoteAddr: "10.0.0.1:1234", forwardedFor: "2.2.2.2", wantStatus: http.StatusOK},
+		{name: "first spoofed request of an untrusted client", remoteAddr: "3.3.3.3:1234", forwardedFor: "4.4.4.4", wantStatus: http.StatusOK},
+		{name: "spoofing another address doesn't reset the limit", remoteAddr: "3.3.3.3:1234", forwardedFor: "5.5.5.5", wantStatus: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("RateLimit() replied %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ECPayProviderName is the paymentMethod of the subscriptions paid with ECPay
+const ECPayProviderName = "ecpay"
+
+// ecpayTimeLayout is the layout of the time in Taipei of ECPay
+const ecpayTimeLayout = "2006/01/02 15:04:05"
+
+// ecpayURLEncoder converts the lower case escaped string to the URL encoding of .NET, which ECPay signs
+var ecpayURLEncoder = strings.NewReplacer("%2d", "-", "%5f", "_", "%2e", ".", "%21", "!", "%2a", "*", "%28", "(", "%29", ")")
+
+// ECPayStore is the ECPay merchant. Only the one-time credit card payments of AIO are supported.
+// Ref: https://developers.ecpay.com.tw/?p=2856
+type ECPayStore struct {
+	ID               string
+	HashKey          string
+	HashIV           string
+	Endpoint         string // https://payment.ecpay.com.tw, or https://payment-stage.ecpay.com.tw for testing
+	CallbackProtocol string
+	CallbackHost     string
+	ClientBackPath   string
+	ReturnPath       string
+	NotifyProtocol   string
+	NotifyHost       string
+	NotifyPath       string
+}
+
+// CheckMacValue signs the parameters except CheckMacValue. The parameters sorted by the name are wrapped by HashKey and HashIV, URL encoded in lower case, and hashed by SHA-256 in upper case.
+func (s ECPayStore) CheckMacValue(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "CheckMacValue" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+
+	var b strings.Builder
+	b.WriteString("HashKey=" + s.HashKey)
+	for _, k := range keys {
+		b.WriteString("&" + k + "=" + params.Get(k))
+	}
+	b.WriteString("&HashIV=" + s.HashIV)
+
+	sum := sha256.Sum256([]byte(ecpayURLEncoder.Replace(strings.ToLower(url.QueryEscape(b.String())))))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// verifyCheckMacValue tells if the parameters are signed by the store
+func (s ECPayStore) verifyCheckMacValue(params url.Values) error {
+	if s.HashKey == "" || s.HashIV == "" {
+		return fmt.Errorf("store has no HashKey or HashIV")
+	} else if subtle.ConstantTimeCompare([]byte(s.CheckMacValue(params)), []byte(strings.ToUpper(params.Get("CheckMacValue")))) != 1 {
+		return fmt.Errorf("parameters have an invalid CheckMacValue")
+	}
+	return nil
+}
+
+// Name implements Provider
+func (s ECPayStore) Name() string {
+	return ECPayProviderName
+}
+
+// SupportsRecurring implements Provider. The periodic payments of ECPay are not supported yet.
+func (s ECPayStore) SupportsRecurring() bool {
+	return false
+}
+
+func (s ECPayStore) getNotifyURL(purchaseInfo PurchaseInfo) (string, error) {
+	return getCallbackUrl(s.NotifyProtocol, s.NotifyHost, s.NotifyPath, &PurchaseInfo{
+		Merchandise: Merchandise{
+			Code: purchaseInfo.Code,
+		},
+		IsGift: purchaseInfo.IsGift,
+	})
+}
+
+// CreateCheckout creates the AIO form to pay the subscription once by a credit card
+func (s ECPayStore) CreateCheckout(c Checkout) (form CheckoutForm, err error) {
+	if c.IsRecurring || c.TrialDays > 0 {
+		return form, errors.Wrap(ErrUnsupported, "ECPay only pays a subscription once")
+	} else if c.PurchasedAtUnixTime <= 0 {
+		return form, fmt.Errorf("purchaseInfo has invalid PurchasedAtUnixTime(%d)", c.PurchasedAtUnixTime)
+	} else if c.Amount <= 0 {
+		return form, fmt.Errorf("checkout has invalid amount(%d)", c.Amount)
+	} else if c.ItemDesc == "" {
+		return form, fmt.Errorf("checkout has no ItemDesc")
+	} else if c.OrderNumber == "" {
+		return form, fmt.Errorf("purchaseInfo has no OrderNumber")
+	} else if err = validatePurchaseCode(c.PurchaseInfo); err != nil {
+		return form, err
+	}
+
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return form, errors.Wrap(err, "cannot load the location of MerchantTradeDate")
+	}
+	notifyURL, err := s.getNotifyURL(c.PurchaseInfo)
+	if err != nil {
+		return form, err
+	}
+	returnURL, err := getCallbackUrl(s.CallbackProtocol, s.CallbackHost, s.ReturnPath, &c.PurchaseInfo)
+	if err != nil {
+		return form, err
+	}
+	clientBackURL, err := getCallbackUrl(s.CallbackProtocol, s.CallbackHost, s.ClientBackPath, &c.PurchaseInfo)
+	if err != nil {
+		return form, err
+	}
+
+	params := url.Values{
+		"MerchantID":        {s.ID},
+		"MerchantTradeNo":   {c.OrderNumber},
+		"MerchantTradeDate": {time.Unix(c.PurchasedAtUnixTime, 0).In(tz).Format(ecpayTimeLayout)},
+		"PaymentType":       {"aio"},
+		"TotalAmount":       {strconv.Itoa(c.Amount)},
+		"TradeDesc":         {c.ItemDesc},
+		"ItemName":          {c.ItemDesc},
+		"ReturnURL":         {notifyURL},
+		"OrderResultURL":    {returnURL},
+		"ClientBackURL":     {clientBackURL},
+		"ChoosePayment":     {"Credit"},
+		"EncryptType":       {"1"},
+	}
+	params.Set("CheckMacValue", s.CheckMacValue(params))
+
+	form.Fields = make(map[string]string, len(params))
+	for k := range params {
+		form.Fields[k] = params.Get(k)
+	}
+	if s.Endpoint != "" {
+		form.Action = strings.TrimSuffix(s.Endpoint, "/") + "/Cashier/AioCheckOut/V5"
+	}
+	return form, nil
+}
+
+// parseECPayTime parses the time in Taipei replied by ECPay
+func parseECPayTime(value string) (time.Time, error) {
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "cannot load the location of ECPay")
+	}
+	t, err := time.ParseInLocation(ecpayTimeLayout, value, tz)
+	return t, errors.Wrapf(err, "invalid time(%s)", value)
+}
+
+// ecpayTrade converts the parameters of a trade to the trade with the fields of ecpayPayment
+func ecpayTrade(params url.Values, isSuccessful bool, status, message, paymentDate string) (trade Trade, err error) {
+	amount, err := strconv.Atoi(params.Get("TradeAmt"))
+	if err != nil {
+		return trade, errors.Wrapf(err, "trade(%s) has invalid TradeAmt", params.Get("TradeNo"))
+	}
+	var paidAt time.Time
+	if isSuccessful {
+		if paidAt, err = parseECPayTime(paymentDate); err != nil {
+			return trade, err
+		}
+	}
+	return Trade{
+		Provider:     ECPayProviderName,
+		IsSuccessful: isSuccessful,
+		Status:       status,
+		Message:      message,
+		OrderNumber:  params.Get("MerchantTradeNo"),
+		TradeNumber:  params.Get("TradeNo"),
+		Amount:       amount,
+		PaidAt:       paidAt,
+		Record: map[string]interface{}{
+			"amount":        amount,
+			"status":        status,
+			"message":       message,
+			"paymentMethod": params.Get("PaymentType"),
+			"tradeNumber":   params.Get("TradeNo"),
+			"merchantId":    params.Get("MerchantID"),
+			"orderNumber":   params.Get("MerchantTradeNo"),
+		},
+	}, nil
+}
+
+// VerifyCallback verifies the CheckMacValue of the payment POSTed to ReturnURL. RtnCode 1 means paid. Payments simulated in the ECPay console are rejected.
+func (s ECPayStore) VerifyCallback(form url.Values) (trade Trade, err error) {
+	if merchantID := form.Get("MerchantID"); merchantID != s.ID {
+		return trade, fmt.Errorf("callback is for merchant(%s) instead of the store", merchantID)
+	} else if err = s.verifyCheckMacValue(form); err != nil {
+		return trade, err
+	} else if form.Get("MerchantTradeNo") == "" {
+		return trade, fmt.Errorf("trade(%s) has no MerchantTradeNo", form.Get("TradeNo"))
+	} else if form.Get("SimulatePaid") == "1" {
+		return trade, fmt.Errorf("trade(%s) is simulated", form.Get("TradeNo"))
+	}
+	return ecpayTrade(form, form.Get("RtnCode") == "1", form.Get("RtnCode"), form.Get("RtnMsg"), form.Get("PaymentDate"))
+}
+
+// CallbackReply implements Provider
+func (s ECPayStore) CallbackReply() string {
+	return "1|OK"
+}
+
+// post signs the parameters and POSTs them to the API of the path. The reply is URL encoded.
+func (s ECPayStore) post(ctx context.Context, path string, params url.Values) (url.Values, error) {
+	if s.Endpoint == "" {
+		return nil, fmt.Errorf("store has no Endpoint")
+	}
+	params.Set("CheckMacValue", s.CheckMacValue(params))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.Endpoint, "/")+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "posting to ECPay(%s) encountered error", path)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ECPay(%s) replied status %d", path, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading the reply of ECPay(%s) encountered error", path)
+	}
+	reply, err := url.ParseQuery(string(body))
+	return reply, errors.Wrapf(err, "ECPay(%s) replied invalid parameters", path)
+}
+
+// QueryTrade queries the trade of the order by QueryTradeInfo. TradeStatus 1 means paid, and 0 means not paid yet. The amount is checked by the caller.
+func (s ECPayStore) QueryTrade(ctx context.Context, orderNumber string, amount int) (trade Trade, err error) {
+	reply, err := s.post(ctx, "/Cashier/QueryTradeInfo/V5", url.Values{
+		"MerchantID":      {s.ID},
+		"MerchantTradeNo": {orderNumber},
+		"TimeStamp":       {strconv.FormatInt(time.Now().Unix(), 10)},
+	})
+	if err != nil {
+		return trade, err
+	} else if err = s.verifyCheckMacValue(reply); err != nil {
+		return trade, errors.Wrapf(err, "reply of order(%s) is not signed by ECPay", orderNumber)
+	} else if reply.Get("MerchantTradeNo") != orderNumber {
+		return trade, fmt.Errorf("ECPay replied order(%s) instead of order(%s)", reply.Get("MerchantTradeNo"), orderNumber)
+	}
+	trade, err = ecpayTrade(reply, reply.Get("TradeStatus") == "1", reply.Get("TradeStatus"), "", reply.Get("PaymentDate"))
+	trade.IsPending = reply.Get("TradeStatus") == "0"
+	return trade, err
+}
+
+// Actions of DoAction
+const (
+	ecpayActionRefund      = "R" // refunds the amount of a captured trade
+	ecpayActionCancelClose = "E" // cancels the close of a trade before it's captured
+	ecpayActionGiveUp      = "N" // gives up the authorisation of a trade which isn't closed
+)
+
+// ECPayAPIError is the error replied by DoAction of ECPay, e.g., the authorisation can't be given up after the trade is captured
+type ECPayAPIError struct {
+	Action  string
+	RtnCode string
+	RtnMsg  string
+}
+
+func (e *ECPayAPIError) Error() string {
+	return fmt.Sprintf("action %s of ECPay failed with %s: %s", e.Action, e.RtnCode, e.RtnMsg)
+}
+
+// doAction requests the action of the credit card trade by DoAction
+func (s ECPayStore) doAction(ctx context.Context, orderNumber, tradeNumber, action string, amount int) error {
+	reply, err := s.post(ctx, "/CreditDetail/DoAction", url.Values{
+		"MerchantID":      {s.ID},
+		"MerchantTradeNo": {orderNumber},
+		"TradeNo":         {tradeNumber},
+		"Action":          {action},
+		"TotalAmount":     {strconv.Itoa(amount)},
+	})
+	if err != nil {
+		return err
+	} else if reply.Get("RtnCode") != "1" {
+		return &ECPayAPIError{Action: action, RtnCode: reply.Get("RtnCode"), RtnMsg: reply.Get("RtnMsg")}
+	}
+	return nil
+}
+
+// Refund gives up the authorisation if the whole trade is refunded before it's captured. The close of the trade is cancelled first, which ECPay rejects if the trade isn't closed. Otherwise, or if ECPay rejects giving up the authorisation, the amount is refunded by DoAction.
+func (s ECPayStore) Refund(ctx context.Context, orderNumber, tradeNumber string, amount int, isFull bool) (refund Refund, err error) {
+	if amount <= 0 {
+		return refund, fmt.Errorf("refund has invalid amount(%d)", amount)
+	}
+	refund = Refund{
+		Provider:    ECPayProviderName,
+		OrderNumber: orderNumber,
+		TradeNumber: tradeNumber,
+		Amount:      amount,
+	}
+	if isFull {
+		var apiErr *ECPayAPIError
+		closeErr := s.doAction(ctx, orderNumber, tradeNumber, ecpayActionCancelClose, amount)
+		if closeErr != nil && !errors.As(closeErr, &apiErr) {
+			return refund, closeErr
+		}
+		err = s.doAction(ctx, orderNumber, tradeNumber, ecpayActionGiveUp, amount)
+		if err == nil {
+			refund.IsCancelled = true
+			return refund, nil
+		} else if !errors.As(err, &apiErr) {
+			return refund, err
+		} else if closeErr == nil {
+			// the trade is neither captured nor refunded, so it has to be closed or given up manually
+			return refund, errors.Wrapf(err, "close of trade(%s) is cancelled, but the authorisation cannot be given up", tradeNumber)
+		}
+	}
+	if err = s.doAction(ctx, orderNumber, tradeNumber, ecpayActionRefund, amount); err != nil {
+		return refund, errors.Wrapf(err, "refunding trade(%s) encountered error", tradeNumber)
+	}
+	return refund, nil
+}
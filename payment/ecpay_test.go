@@ -0,0 +1,320 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The key, the IV and the example are from the CheckMacValue document of ECPay
+const (
+	testECPayHashKey = "pwFHCqoQZGmho4w6"
+	testECPayHashIV  = "EkRm7iFT261dpevs"
+)
+
+func TestECPayStore_CheckMacValue(t *testing.T) {
+	store := ECPayStore{HashKey: testECPayHashKey, HashIV: testECPayHashIV}
+	tests := []struct {
+		name   string
+		params url.Values
+		want   string
+	}{
+		{
+			name: "document example",
+			params: url.Values{
+				"ChoosePayment":     {"ALL"},
+				"EncryptType":       {"1"},
+				"ItemName":          {"Apple iphone 15"},
+				"MerchantID":        {"3002607"},
+				"MerchantTradeDate": {"2023/03/12 15:30:23"},
+				"MerchantTradeNo":   {"ecpay20230312153023"},
+				"PaymentType":       {"aio"},
+				"ReturnURL":         {"https://www.ecpay.com.tw/receive.php"},
+				"TotalAmount":       {"30000"},
+				"TradeDesc":         {"促銷方案"},
+			},
+			want: "6C51C9E6888DE861FD62FB1DD17029FC742634498FD813DC43D4243B5685B840",
+		},
+		{
+			name: "CheckMacValue is left out",
+			params: url.Values{
+				"ChoosePayment":     {"ALL"},
+				"EncryptType":       {"1"},
+				"ItemName":          {"Apple iphone 15"},
+				"MerchantID":        {"3002607"},
+				"MerchantTradeDate": {"2023/03/12 15:30:23"},
+				"MerchantTradeNo":   {"ecpay20230312153023"},
+				"PaymentType":       {"aio"},
+				"ReturnURL":         {"https://www.ecpay.com.tw/receive.php"},
+				"TotalAmount":       {"30000"},
+				"TradeDesc":         {"促銷方案"},
+				"CheckMacValue":     {"whatever"},
+			},
+			want: "6C51C9E6888DE861FD62FB1DD17029FC742634498FD813DC43D4243B5685B840",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.CheckMacValue(tt.params); got != tt.want {
+				t.Errorf("ECPayStore.CheckMacValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestECPayStore_CreateCheckout(t *testing.T) {
+	store := ECPayStore{
+		ID:               "3002607",
+		HashKey:          testECPayHashKey,
+		HashIV:           testECPayHashIV,
+		Endpoint:         "https://payment-stage.ecpay.com.tw",
+		CallbackProtocol: "https",
+		CallbackHost:     "www.mirrormedia.mg",
+		ReturnPath:       "/subscribe/return",
+		ClientBackPath:   "/subscribe/back",
+		NotifyProtocol:   "https",
+		NotifyHost:       "apigateway.mirrormedia.mg",
+		NotifyPath:       "/ecpay/notify",
+	}
+	oneTime := Checkout{
+		PurchaseInfo: PurchaseInfo{
+			Merchandise: Merchandise{
+				Code:   "one_time",
+				PostID: "1",
+			},
+			PurchasedAtUnixTime: 1638316800,
+			OrderNumber:         "M21120100001",
+		},
+		Amount:   5,
+		ItemDesc: "單篇文章",
+	}
+	tests := []struct {
+		name       string
+		checkout   Checkout
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:     "one time",
+			checkout: oneTime,
+			wantFields: map[string]string{
+				"MerchantTradeNo":   "M21120100001",
+				"MerchantTradeDate": "2021/12/01 08:00:00",
+				"TotalAmount":       "5",
+				"ChoosePayment":     "Credit",
+				"ReturnURL":         "https://apigateway.mirrormedia.mg/ecpay/notify?code=one_time",
+			},
+		},
+		{
+			name:     "recurring",
+			checkout: Checkout{PurchaseInfo: PurchaseInfo{Merchandise: Merchandise{Code: "monthly"}, PurchasedAtUnixTime: 1638316800, OrderNumber: "M21120100002"}, Amount: 199, ItemDesc: "月訂閱", IsRecurring: true},
+			wantErr:  true,
+		},
+		{
+			name:     "no amount",
+			checkout: Checkout{PurchaseInfo: oneTime.PurchaseInfo, ItemDesc: "單篇文章"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.CreateCheckout(tt.checkout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ECPayStore.CreateCheckout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Action != "https://payment-stage.ecpay.com.tw/Cashier/AioCheckOut/V5" {
+				t.Errorf("ECPayStore.CreateCheckout() action = %v", got.Action)
+			}
+			for k, want := range tt.wantFields {
+				if got.Fields[k] != want {
+					t.Errorf("ECPayStore.CreateCheckout() %s = %v, want %v", k, got.Fields[k], want)
+				}
+			}
+			params := url.Values{}
+			for k, v := range got.Fields {
+				params.Set(k, v)
+			}
+			if err := store.verifyCheckMacValue(params); err != nil {
+				t.Errorf("ECPayStore.CreateCheckout() isn't signed: %v", err)
+			}
+		})
+	}
+}
+
+// signed returns the parameters signed by the store
+func signed(store ECPayStore, params url.Values) url.Values {
+	params.Set("CheckMacValue", store.CheckMacValue(params))
+	return params
+}
+
+func TestECPayStore_VerifyCallback(t *testing.T) {
+	store := ECPayStore{ID: "3002607", HashKey: testECPayHashKey, HashIV: testECPayHashIV}
+	paid := func() url.Values {
+		return url.Values{
+			"MerchantID":      {"3002607"},
+			"MerchantTradeNo": {"M21120100001"},
+			"RtnCode":         {"1"},
+			"RtnMsg":          {"交易成功"},
+			"TradeNo":         {"2112010800001234"},
+			"TradeAmt":        {"5"},
+			"PaymentDate":     {"2021/12/01 08:01:02"},
+			"PaymentType":     {"Credit_CreditCard"},
+			"SimulatePaid":    {"0"},
+		}
+	}
+	tampered := signed(store, paid())
+	tampered.Set("TradeAmt", "1")
+	simulated := paid()
+	simulated.Set("SimulatePaid", "1")
+	failed := paid()
+	failed.Set("RtnCode", "10100058")
+	failed.Set("PaymentDate", "")
+	otherMerchant := paid()
+	otherMerchant.Set("MerchantID", "2000132")
+
+	tests := []struct {
+		name             string
+		form             url.Values
+		wantIsSuccessful bool
+		wantAmount       int
+		wantPaidAt       time.Time
+		wantErr          bool
+	}{
+		{name: "paid", form: signed(store, paid()), wantIsSuccessful: true, wantAmount: 5, wantPaidAt: time.Date(2021, 12, 1, 0, 1, 2, 0, time.UTC)},
+		{name: "failed", form: signed(store, failed), wantAmount: 5},
+		{name: "tampered", form: tampered, wantErr: true},
+		{name: "unsigned", form: paid(), wantErr: true},
+		{name: "simulated", form: signed(store, simulated), wantErr: true},
+		{name: "other merchant", form: signed(store, otherMerchant), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.VerifyCallback(tt.form)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ECPayStore.VerifyCallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.IsSuccessful != tt.wantIsSuccessful || got.Amount != tt.wantAmount || !got.PaidAt.Equal(tt.wantPaidAt) {
+				t.Errorf("ECPayStore.VerifyCallback() = %+v, want successful %v of %d at %v", got, tt.wantIsSuccessful, tt.wantAmount, tt.wantPaidAt)
+			}
+			if err == nil && (got.Provider != ECPayProviderName || got.OrderNumber != "M21120100001" || got.Record["tradeNumber"] != "2112010800001234") {
+				t.Errorf("ECPayStore.VerifyCallback() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestECPayStore_QueryTrade(t *testing.T) {
+	store := ECPayStore{ID: "3002607", HashKey: testECPayHashKey, HashIV: testECPayHashIV}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Cashier/QueryTradeInfo/V5" || r.ParseForm() != nil || store.verifyCheckMacValue(r.PostForm) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reply := url.Values{
+			"MerchantID":      {"3002607"},
+			"MerchantTradeNo": {r.PostForm.Get("MerchantTradeNo")},
+			"TradeNo":         {"2112010800001234"},
+			"TradeAmt":        {"5"},
+			"PaymentDate":     {"2021/12/01 08:01:02"},
+			"PaymentType":     {"Credit_CreditCard"},
+			"TradeStatus":     {"1"},
+		}
+		switch r.PostForm.Get("MerchantTradeNo") {
+		case "unpaid":
+			reply.Set("TradeStatus", "0")
+			reply.Set("PaymentDate", "")
+		case "unsigned":
+			_, _ = w.Write([]byte(reply.Encode()))
+			return
+		}
+		_, _ = w.Write([]byte(signed(store, reply).Encode()))
+	}))
+	defer server.Close()
+	store.Endpoint = server.URL
+
+	tests := []struct {
+		name             string
+		orderNumber      string
+		wantIsSuccessful bool
+		wantErr          bool
+	}{
+		{name: "paid", orderNumber: "M21120100001", wantIsSuccessful: true},
+		{name: "unpaid", orderNumber: "unpaid"},
+		{name: "unsigned reply", orderNumber: "unsigned", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.QueryTrade(context.Background(), tt.orderNumber, 5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ECPayStore.QueryTrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.IsSuccessful != tt.wantIsSuccessful {
+				t.Errorf("ECPayStore.QueryTrade() = %+v, want successful %v", got, tt.wantIsSuccessful)
+			}
+		})
+	}
+}
+
+func TestECPayStore_Refund(t *testing.T) {
+	store := ECPayStore{ID: "3002607", HashKey: testECPayHashKey, HashIV: testECPayHashIV}
+	// accepted are the actions ECPay accepts by the state of the trade
+	accepted := map[string]string{
+		"authorised": "N",
+		"closed":     "EN",
+		"captured":   "R",
+		"stuck":      "E",
+	}
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/CreditDetail/DoAction" || r.ParseForm() != nil || store.verifyCheckMacValue(r.PostForm) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		action := r.PostForm.Get("Action")
+		actions = append(actions, action)
+		if !strings.Contains(accepted[r.PostForm.Get("TradeNo")], action) {
+			_, _ = w.Write([]byte("RtnCode=10200047&RtnMsg=Action+failed"))
+			return
+		}
+		_, _ = w.Write([]byte("RtnCode=1&RtnMsg=OK"))
+	}))
+	defer server.Close()
+	store.Endpoint = server.URL
+
+	tests := []struct {
+		name            string
+		tradeNumber     string
+		amount          int
+		isFull          bool
+		wantIsCancelled bool
+		wantActions     string
+		wantErr         bool
+	}{
+		{name: "partial refund", tradeNumber: "captured", amount: 5, wantActions: "R"},
+		{name: "authorisation given up", tradeNumber: "authorised", amount: 5, isFull: true, wantIsCancelled: true, wantActions: "EN"},
+		{name: "close cancelled and authorisation given up", tradeNumber: "closed", amount: 5, isFull: true, wantIsCancelled: true, wantActions: "EN"},
+		{name: "captured", tradeNumber: "captured", amount: 5, isFull: true, wantActions: "ENR"},
+		{name: "close cancelled but authorisation kept", tradeNumber: "stuck", amount: 5, isFull: true, wantActions: "EN", wantErr: true},
+		{name: "rejected", tradeNumber: "refunded", amount: 5, wantActions: "R", wantErr: true},
+		{name: "no amount", tradeNumber: "captured", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions = nil
+			got, err := store.Refund(context.Background(), "M21120100001", tt.tradeNumber, tt.amount, tt.isFull)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ECPayStore.Refund() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.IsCancelled != tt.wantIsCancelled || strings.Join(actions, "") != tt.wantActions {
+				t.Errorf("ECPayStore.Refund() = %+v with actions %v, want cancelled %v with actions %s", got, actions, tt.wantIsCancelled, tt.wantActions)
+			}
+		})
+	}
+}
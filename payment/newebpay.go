@@ -27,7 +27,10 @@ type NewebPayStore struct {
 	CallbackHost        string
 	CallbackProtocol    string
 	ClientBackPath      string            // ? Unknown
+	Endpoint            string            // https://core.newebpay.com, or https://ccore.newebpay.com for testing
 	ID                  string            // ? Unknown
+	HashKey             string            // The 32-byte key to encrypt TradeInfo
+	HashIV              string            // The 16-byte IV to encrypt TradeInfo
 	IsAbleToModifyEmail Boolean           // Use 1
 	LoginType           NewebpayLoginType // Use 0
 	NotifyProtocol      string
@@ -53,8 +56,13 @@ type PurchaseInfo struct {
 	OrderNumber         string `url:"orderNumber,omitempty"`
 	MemberFirebaseID    string `url:"memberFirebaseId,omitempty"`
 	ReturnPath          string `url:"returnPath,omitempty"`
+	TrialDays           int    `url:"trialDays,omitempty"`
+	IsGift              bool   `url:"isGift,omitempty"`
 }
 
+// TrialAuthorisationAmount is the amount to authorise the card of a trial. NewebPay doesn't accept an agreement of 0, so the card is verified with the minimum amount, which is cancelled once the trade is settled, and charged with the token after the trial ends.
+const TrialAuthorisationAmount = 1
+
 func (s NewebPayStore) getNotifyURL(purchaseInfo PurchaseInfo) (string, error) {
 	protocol := s.NotifyProtocol
 	domain := s.NotifyHost
@@ -63,6 +71,7 @@ func (s NewebPayStore) getNotifyURL(purchaseInfo PurchaseInfo) (string, error) {
 		Merchandise: Merchandise{
 			Code: purchaseInfo.Code,
 		},
+		IsGift: purchaseInfo.IsGift,
 	})
 }
 
@@ -149,6 +158,8 @@ func (s NewebPayStore) CreateNewebpayAgreementPayload(agreementInfo NewebpayAgre
 		return "", fmt.Errorf("agreementInfo.OrderComment contains unsafe a character(%s)", (agreementInfo.OrderComment)[i:i+1])
 	} else if err := validatePurchaseCode(purchaseInfo); err != nil {
 		return "", err
+	} else if purchaseInfo.TrialDays > 0 && purchaseInfo.Code == model.SubscriptionFrequencyTypeOneTime.String() {
+		return "", fmt.Errorf("purchaseInfo code is %s, but TrialDays is provided", model.SubscriptionFrequencyTypeOneTime)
 	}
 
 	returnURL, err := s.getReturnURL(purchaseInfo)
@@ -166,9 +177,14 @@ func (s NewebPayStore) CreateNewebpayAgreementPayload(agreementInfo NewebpayAgre
 		return "", nil
 	}
 
+	amount := agreementInfo.Amount
+	if purchaseInfo.TrialDays > 0 {
+		amount = TrialAuthorisationAmount
+	}
+
 	tradeInfo := NewebpayTradeInfoAgreement{
 		NewebpayTradeInfo: NewebpayTradeInfo{
-			Amt:                 agreementInfo.Amount,
+			Amt:                 amount,
 			ClientBackURL:       clientBackURL,
 			Email:               agreementInfo.Email,
 			IsAbleToModifyEmail: Boolean(s.IsAbleToModifyEmail),
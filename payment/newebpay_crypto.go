@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NewebpayForm has the fields of the form to POST to NewebPay
+type NewebpayForm struct {
+	MerchantID string
+	TradeInfo  string // The hex of the AES-256-CBC encrypted payload
+	TradeSha   string // The upper case hex of the SHA-256 of TradeInfo with HashKey and HashIV
+	Version    string
+}
+
+func (s NewebPayStore) newCipherBlock() (cipher.Block, error) {
+	if len(s.HashKey) != 32 {
+		return nil, fmt.Errorf("store has a HashKey of %d bytes, but it should be 32 bytes", len(s.HashKey))
+	} else if len(s.HashIV) != aes.BlockSize {
+		return nil, fmt.Errorf("store has a HashIV of %d bytes, but it should be %d bytes", len(s.HashIV), aes.BlockSize)
+	}
+	return aes.NewCipher([]byte(s.HashKey))
+}
+
+// pkcs7Pad pads the data to a multiple of the block size
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// pkcs7Unpad removes the padding and validates it
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("data of %d bytes is not padded to blocks of %d bytes", len(data), blockSize)
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || !bytes.Equal(data[len(data)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, fmt.Errorf("data has invalid padding")
+	}
+	return data[:len(data)-n], nil
+}
+
+// EncryptTradeInfo encrypts the URL-encoded payload with AES-256-CBC and PKCS7 padding, and returns the lower case hex
+func (s NewebPayStore) EncryptTradeInfo(payload string) (string, error) {
+	block, err := s.newCipherBlock()
+	if err != nil {
+		return "", err
+	}
+	data := pkcs7Pad([]byte(payload), aes.BlockSize)
+	cipher.NewCBCEncrypter(block, []byte(s.HashIV)).CryptBlocks(data, data)
+	return hex.EncodeToString(data), nil
+}
+
+// DecryptTradeInfo decrypts the hex of TradeInfo encrypted by EncryptTradeInfo or by NewebPay
+func (s NewebPayStore) DecryptTradeInfo(tradeInfo string) (string, error) {
+	block, err := s.newCipherBlock()
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(tradeInfo)
+	if err != nil {
+		return "", errors.Wrap(err, "TradeInfo is not hex")
+	} else if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("TradeInfo of %d bytes is not encrypted by blocks", len(data))
+	}
+	cipher.NewCBCDecrypter(block, []byte(s.HashIV)).CryptBlocks(data, data)
+	data, err = pkcs7Unpad(data, aes.BlockSize)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot decrypt TradeInfo")
+	}
+	return string(data), nil
+}
+
+// SignTradeInfo returns the TradeSha of the encrypted TradeInfo
+func (s NewebPayStore) SignTradeInfo(tradeInfo string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("HashKey=%s&%s&HashIV=%s", s.HashKey, tradeInfo, s.HashIV)))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// VerifyTradeSha tells if tradeSha is the signature of the encrypted TradeInfo
+func (s NewebPayStore) VerifyTradeSha(tradeInfo, tradeSha string) bool {
+	return subtle.ConstantTimeCompare([]byte(s.SignTradeInfo(tradeInfo)), []byte(strings.ToUpper(tradeSha))) == 1
+}
+
+// CreateNewebpayForm encrypts and signs the payload created by CreateNewebpayAgreementPayload or CreateNewebpayMPGPayload
+func (s NewebPayStore) CreateNewebpayForm(payload string) (form NewebpayForm, err error) {
+	if payload == "" {
+		return form, fmt.Errorf("payload is empty")
+	}
+	tradeInfo, err := s.EncryptTradeInfo(payload)
+	if err != nil {
+		return form, err
+	}
+	return NewebpayForm{
+		MerchantID: s.ID,
+		TradeInfo:  tradeInfo,
+		TradeSha:   s.SignTradeInfo(tradeInfo),
+		Version:    s.Version,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package payment
+
+import (
+	"testing"
+)
+
+// The key, the IV and the manual example are from the NewebPay MPG manual. Other cases are verified with openssl enc -aes-256-cbc.
+const (
+	testHashKey = "12345678901234567890123456789012"
+	testHashIV  = "1234567890123456"
+)
+
+func TestNewebPayStore_EncryptTradeInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   NewebPayStore
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "manual example",
+			store:   NewebPayStore{HashKey: testHashKey, HashIV: testHashIV},
+			payload: "MerchantID=3430112&RespondType=JSON&TimeStamp=1485232229&Version=1.4&MerchantOrderNo=S_1485232229&Amt=40&ItemDesc=UnitTest",
+			want:    "ff91c8aa01379e4de621a44e5f11f72e4d25bdb1a18242db6cef9ef07d80b0165e476fd1d9acaa53170272c82d122961e1a0700a7427cfa1cf90db7f6d6593bbc93102a4d4b9b66d9974c13c31a7ab4bba1d4e0790f0cbbbd7ad64c6d3c8012a601ceaa808bff70f94a8efa5a4f984b9d41304ffd879612177c622f75f4214fa",
+		},
+		{
+			name:    "padded with a full block",
+			store:   NewebPayStore{HashKey: testHashKey, HashIV: testHashIV},
+			payload: "0123456789abcdef",
+			want:    "e56bc00ad41bf5eeac8c741be1d09074f267567bc0b566157b9ceff781055494",
+		},
+		{
+			name:    "short key",
+			store:   NewebPayStore{HashKey: "1234567890123456", HashIV: testHashIV},
+			payload: "Amt=40",
+			wantErr: true,
+		},
+		{
+			name:    "short IV",
+			store:   NewebPayStore{HashKey: testHashKey, HashIV: "12345678"},
+			payload: "Amt=40",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.store.EncryptTradeInfo(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewebPayStore.EncryptTradeInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewebPayStore.EncryptTradeInfo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewebPayStore_CreateNewebpayForm(t *testing.T) {
+	store := NewebPayStore{
+		ID:      "3430112",
+		HashKey: testHashKey,
+		HashIV:  testHashIV,
+		Version: "1.4",
+	}
+	tests := []struct {
+		name    string
+		payload string
+		want    NewebpayForm
+		wantErr bool
+	}{
+		{
+			name:    "manual example",
+			payload: "MerchantID=3430112&RespondType=JSON&TimeStamp=1485232229&Version=1.4&MerchantOrderNo=S_1485232229&Amt=40&ItemDesc=UnitTest",
+			want: NewebpayForm{
+				MerchantID: "3430112",
+				TradeInfo:  "ff91c8aa01379e4de621a44e5f11f72e4d25bdb1a18242db6cef9ef07d80b0165e476fd1d9acaa53170272c82d122961e1a0700a7427cfa1cf90db7f6d6593bbc93102a4d4b9b66d9974c13c31a7ab4bba1d4e0790f0cbbbd7ad64c6d3c8012a601ceaa808bff70f94a8efa5a4f984b9d41304ffd879612177c622f75f4214fa",
+				TradeSha:   "EA0A6CC37F40C1EA5692E7CBB8AE097653DF3E91365E6A9CD7E91312413C7BB8",
+				Version:    "1.4",
+			},
+		},
+		{
+			name:    "empty payload",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.CreateNewebpayForm(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewebPayStore.CreateNewebpayForm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewebPayStore.CreateNewebpayForm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewebPayStore_DecryptTradeInfo(t *testing.T) {
+	store := NewebPayStore{HashKey: testHashKey, HashIV: testHashIV}
+	tests := []struct {
+		name      string
+		tradeInfo string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "manual example",
+			tradeInfo: "ff91c8aa01379e4de621a44e5f11f72e4d25bdb1a18242db6cef9ef07d80b0165e476fd1d9acaa53170272c82d122961e1a0700a7427cfa1cf90db7f6d6593bbc93102a4d4b9b66d9974c13c31a7ab4bba1d4e0790f0cbbbd7ad64c6d3c8012a601ceaa808bff70f94a8efa5a4f984b9d41304ffd879612177c622f75f4214fa",
+			want:      "MerchantID=3430112&RespondType=JSON&TimeStamp=1485232229&Version=1.4&MerchantOrderNo=S_1485232229&Amt=40&ItemDesc=UnitTest",
+		},
+		{
+			name:      "padded with a full block",
+			tradeInfo: "e56bc00ad41bf5eeac8c741be1d09074f267567bc0b566157b9ceff781055494",
+			want:      "0123456789abcdef",
+		},
+		{
+			name:      "not hex",
+			tradeInfo: "not hex",
+			wantErr:   true,
+		},
+		{
+			name:      "not blocks",
+			tradeInfo: "e56bc00ad41bf5ee",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid padding",
+			tradeInfo: "e56bc00ad41bf5eeac8c741be1d09074",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.DecryptTradeInfo(tt.tradeInfo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewebPayStore.DecryptTradeInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewebPayStore.DecryptTradeInfo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewebPayStore_VerifyTradeSha(t *testing.T) {
+	store := NewebPayStore{HashKey: testHashKey, HashIV: testHashIV}
+	tradeInfo := "ff91c8aa01379e4de621a44e5f11f72e4d25bdb1a18242db6cef9ef07d80b0165e476fd1d9acaa53170272c82d122961e1a0700a7427cfa1cf90db7f6d6593bbc93102a4d4b9b66d9974c13c31a7ab4bba1d4e0790f0cbbbd7ad64c6d3c8012a601ceaa808bff70f94a8efa5a4f984b9d41304ffd879612177c622f75f4214fa"
+	tests := []struct {
+		name     string
+		tradeSha string
+		want     bool
+	}{
+		{name: "manual example", tradeSha: "EA0A6CC37F40C1EA5692E7CBB8AE097653DF3E91365E6A9CD7E91312413C7BB8", want: true},
+		{name: "lower case", tradeSha: "ea0a6cc37f40c1ea5692e7cbb8ae097653df3e91365e6a9cd7e91312413c7bb8", want: true},
+		{name: "tampered", tradeSha: "EA0A6CC37F40C1EA5692E7CBB8AE097653DF3E91365E6A9CD7E91312413C7BB9"},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.VerifyTradeSha(tradeInfo, tt.tradeSha); got != tt.want {
+				t.Errorf("NewebPayStore.VerifyTradeSha() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewebpayStatusSuccess is the status of a successful trade
+const NewebpayStatusSuccess = "SUCCESS"
+
+// newebpayTimeLayout is the layout of the time in Taipei replied by NewebPay
+const newebpayTimeLayout = "2006-01-02 15:04:05"
+
+// NewebpayTradeResult is the result of a trade in the notification. Only the fields used to record the payment are decoded.
+type NewebpayTradeResult struct {
+	MerchantID      string `json:"MerchantID"`
+	Amt             int    `json:"Amt"`
+	TradeNo         string `json:"TradeNo"`
+	MerchantOrderNo string `json:"MerchantOrderNo"`
+	PaymentType     string `json:"PaymentType"`
+	PayTime         string `json:"PayTime"`
+	RespondCode     string `json:"RespondCode"`
+	Auth            string `json:"Auth"`
+	AuthBank        string `json:"AuthBank"`
+	ECI             string `json:"ECI"`
+	Card6No         string `json:"Card6No"`
+	Card4No         string `json:"Card4No"`
+	Exp             string `json:"Exp"`
+	TokenUseStatus  int    `json:"TokenUseStatus"`
+	TokenValue      string `json:"TokenValue"`
+	TokenLife       string `json:"TokenLife"`
+}
+
+// NewebpayNotification is the decrypted TradeInfo POSTed to NotifyURL
+type NewebpayNotification struct {
+	Status  string              `json:"Status"`
+	Message string              `json:"Message"`
+	Result  NewebpayTradeResult `json:"Result"`
+}
+
+// IsSuccessful tells if the trade is paid or the card is authorised
+func (n NewebpayNotification) IsSuccessful() bool {
+	return n.Status == NewebpayStatusSuccess
+}
+
+// PaidAt returns PayTime, which is in Taipei
+func (r NewebpayTradeResult) PaidAt() (time.Time, error) {
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "cannot load the location of PayTime")
+	}
+	t, err := time.ParseInLocation(newebpayTimeLayout, r.PayTime, tz)
+	if err != nil {
+		return t, errors.Wrapf(err, "invalid PayTime of trade(%s)", r.TradeNo)
+	}
+	return t, nil
+}
+
+// DecodeNewebpayNotification verifies the fields POSTed to NotifyURL and decrypts TradeInfo. The notification has to be for the store and signed by TradeSha.
+func (s NewebPayStore) DecodeNewebpayNotification(merchantID, tradeInfo, tradeSha string) (n NewebpayNotification, err error) {
+	if merchantID != s.ID {
+		return n, fmt.Errorf("notification is for merchant(%s) instead of the store", merchantID)
+	} else if !s.VerifyTradeSha(tradeInfo, tradeSha) {
+		return n, fmt.Errorf("notification has an invalid TradeSha")
+	}
+
+	plain, err := s.DecryptTradeInfo(tradeInfo)
+	if err != nil {
+		return n, err
+	}
+	var decoded NewebpayNotification
+	if err = json.Unmarshal([]byte(plain), &decoded); err != nil {
+		return n, errors.Wrap(err, "TradeInfo is not JSON")
+	} else if decoded.Result.MerchantID != s.ID {
+		return n, fmt.Errorf("trade(%s) is for merchant(%s) instead of the store", decoded.Result.TradeNo, decoded.Result.MerchantID)
+	} else if decoded.Result.MerchantOrderNo == "" {
+		return n, fmt.Errorf("trade(%s) has no MerchantOrderNo", decoded.Result.TradeNo)
+	}
+	return decoded, nil
+}
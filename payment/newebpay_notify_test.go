@@ -0,0 +1,58 @@
+package payment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewebPayStore_DecodeNewebpayNotification(t *testing.T) {
+	store := NewebPayStore{ID: "MS12345", HashKey: testHashKey, HashIV: testHashIV}
+	encrypt := func(plain string) (tradeInfo, tradeSha string) {
+		tradeInfo, err := store.EncryptTradeInfo(plain)
+		if err != nil {
+			t.Fatalf("NewebPayStore.EncryptTradeInfo() error = %v", err)
+		}
+		return tradeInfo, store.SignTradeInfo(tradeInfo)
+	}
+	paid, paidSha := encrypt(`{"Status":"SUCCESS","Message":"授權成功","Result":{"MerchantID":"MS12345","Amt":1490,"TradeNo":"21120112345678","MerchantOrderNo":"M202112010001","PaymentType":"CREDIT","PayTime":"2021-12-01 12:00:00","RespondCode":"00","Auth":"123456","Card6No":"400022","Card4No":"1111","Exp":"2512","TokenUseStatus":1,"TokenValue":"token"}}`)
+	other, otherSha := encrypt(`{"Status":"SUCCESS","Result":{"MerchantID":"MS00000","Amt":1490,"TradeNo":"21120112345678","MerchantOrderNo":"M202112010001"}}`)
+	noOrder, noOrderSha := encrypt(`{"Status":"SUCCESS","Result":{"MerchantID":"MS12345","Amt":1490,"TradeNo":"21120112345678"}}`)
+	notJSON, notJSONSha := encrypt(`Status=SUCCESS`)
+
+	tests := []struct {
+		name       string
+		merchantID string
+		tradeInfo  string
+		tradeSha   string
+		wantAmt    int
+		wantErr    bool
+	}{
+		{name: "paid", merchantID: "MS12345", tradeInfo: paid, tradeSha: paidSha, wantAmt: 1490},
+		{name: "another merchant", merchantID: "MS00000", tradeInfo: paid, tradeSha: paidSha, wantErr: true},
+		{name: "invalid TradeSha", merchantID: "MS12345", tradeInfo: paid, tradeSha: otherSha, wantErr: true},
+		{name: "result of another merchant", merchantID: "MS12345", tradeInfo: other, tradeSha: otherSha, wantErr: true},
+		{name: "no order number", merchantID: "MS12345", tradeInfo: noOrder, tradeSha: noOrderSha, wantErr: true},
+		{name: "not JSON", merchantID: "MS12345", tradeInfo: notJSON, tradeSha: notJSONSha, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.DecodeNewebpayNotification(tt.merchantID, tt.tradeInfo, tt.tradeSha)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewebPayStore.DecodeNewebpayNotification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Result.Amt != tt.wantAmt {
+				t.Errorf("NewebPayStore.DecodeNewebpayNotification() = %v, want Amt %v", got, tt.wantAmt)
+			}
+		})
+	}
+}
+
+func TestNewebpayTradeResult_PaidAt(t *testing.T) {
+	got, err := NewebpayTradeResult{PayTime: "2021-12-01 08:00:00"}.PaidAt()
+	if err != nil {
+		t.Fatalf("NewebpayTradeResult.PaidAt() error = %v", err)
+	}
+	if want := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NewebpayTradeResult.PaidAt() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package payment
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewebpayProviderName is the paymentMethod of the subscriptions paid with NewebPay
+const NewebpayProviderName = "newebpay"
+
+// Name implements Provider
+func (s NewebPayStore) Name() string {
+	return NewebpayProviderName
+}
+
+// SupportsRecurring implements Provider. Recurring subscriptions are paid by the credit card agreement.
+func (s NewebPayStore) SupportsRecurring() bool {
+	return true
+}
+
+// CreateCheckout creates the agreement payload for a recurring subscription, or the MPG payload otherwise, and encrypts it into the form
+func (s NewebPayStore) CreateCheckout(c Checkout) (form CheckoutForm, err error) {
+	var payload string
+	if c.IsRecurring {
+		payload, err = s.CreateNewebpayAgreementPayload(NewebpayAgreementInfo{
+			Amount:              c.Amount,
+			Email:               c.Email,
+			IsAbleToModifyEmail: s.IsAbleToModifyEmail,
+			LoginType:           s.LoginType,
+			RespondType:         s.RespondType,
+			ItemDesc:            c.ItemDesc,
+			OrderComment:        c.OrderComment,
+			TokenTerm:           c.TokenTerm,
+		}, c.PurchaseInfo)
+	} else {
+		payload, err = s.CreateNewebpayMPGPayload(NewebpayMGPInfo{
+			Amount:              c.Amount,
+			Email:               c.Email,
+			IsAbleToModifyEmail: s.IsAbleToModifyEmail,
+			LoginType:           s.LoginType,
+			RespondType:         s.RespondType,
+			ItemDescription:     c.ItemDesc,
+			OrderComment:        c.OrderComment,
+			TokenTerm:           c.TokenTerm,
+		}, c.PurchaseInfo)
+	}
+	if err != nil {
+		return form, err
+	}
+
+	f, err := s.CreateNewebpayForm(payload)
+	if err != nil {
+		return form, err
+	}
+	var action string
+	if s.Endpoint != "" {
+		action = strings.TrimSuffix(s.Endpoint, "/") + "/MPG/mpg_gateway"
+	}
+	return CheckoutForm{
+		Action: action,
+		Fields: map[string]string{
+			"MerchantID": f.MerchantID,
+			"TradeInfo":  f.TradeInfo,
+			"TradeSha":   f.TradeSha,
+			"Version":    f.Version,
+		},
+		Payload: payload,
+	}, nil
+}
+
+// VerifyCallback decodes the notification POSTed to NotifyURL
+func (s NewebPayStore) VerifyCallback(form url.Values) (trade Trade, err error) {
+	n, err := s.DecodeNewebpayNotification(form.Get("MerchantID"), form.Get("TradeInfo"), form.Get("TradeSha"))
+	if err != nil {
+		return trade, err
+	}
+	return n.Trade()
+}
+
+// CallbackReply implements Provider. NewebPay only needs the status 200.
+func (s NewebPayStore) CallbackReply() string {
+	return "OK"
+}
+
+// QueryTrade queries the trade of the order by QueryTradeInfo
+func (s NewebPayStore) QueryTrade(ctx context.Context, orderNumber string, amount int) (Trade, error) {
+	result, err := s.QueryTradeInfo(ctx, orderNumber, amount)
+	if err != nil {
+		return Trade{}, err
+	}
+	return result.Trade()
+}
+
+// Trade converts the notification to the trade with the fields of newebpayPayment
+func (n NewebpayNotification) Trade() (Trade, error) {
+	r := n.Result
+	var paidAt time.Time
+	if n.IsSuccessful() {
+		var err error
+		if paidAt, err = r.PaidAt(); err != nil {
+			return Trade{}, err
+		}
+	}
+	return Trade{
+		Provider:     NewebpayProviderName,
+		IsSuccessful: n.IsSuccessful(),
+		Status:       n.Status,
+		Message:      n.Message,
+		OrderNumber:  r.MerchantOrderNo,
+		TradeNumber:  r.TradeNo,
+		Amount:       r.Amt,
+		PaidAt:       paidAt,
+		Record: map[string]interface{}{
+			"amount":           r.Amt,
+			"status":           n.Status,
+			"message":          n.Message,
+			"paymentMethod":    r.PaymentType,
+			"tradeNumber":      r.TradeNo,
+			"merchantId":       r.MerchantID,
+			"orderNumber":      r.MerchantOrderNo,
+			"tokenUseStatus":   r.TokenUseStatus,
+			"respondCode":      r.RespondCode,
+			"ECI":              r.ECI,
+			"authCode":         r.Auth,
+			"authBank":         r.AuthBank,
+			"cardInfoLastFour": r.Card4No,
+			"cardInfoFirstSix": r.Card6No,
+			"cardInfoExp":      r.Exp,
+		},
+	}, nil
+}
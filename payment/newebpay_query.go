@@ -0,0 +1,145 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// newebpayQueryVersion is the version of QueryTradeInfo
+const newebpayQueryVersion = "1.3"
+
+// TradeStatus of QueryTradeInfo
+const (
+	NewebpayTradeStatusUnpaid    = "0"
+	NewebpayTradeStatusPaid      = "1"
+	NewebpayTradeStatusFailed    = "2"
+	NewebpayTradeStatusCancelled = "3"
+	NewebpayTradeStatusRefunded  = "6"
+)
+
+// NewebpayQueryResult is the trade replied by QueryTradeInfo. Only the fields used to record the payment are decoded.
+type NewebpayQueryResult struct {
+	MerchantID      string `json:"MerchantID"`
+	Amt             int    `json:"Amt"`
+	TradeNo         string `json:"TradeNo"`
+	MerchantOrderNo string `json:"MerchantOrderNo"`
+	TradeStatus     string `json:"TradeStatus"`
+	PaymentType     string `json:"PaymentType"`
+	PayTime         string `json:"PayTime"`
+	CheckCode       string `json:"CheckCode"`
+	RespondCode     string `json:"RespondCode"`
+	RespondMsg      string `json:"RespondMsg"`
+	Auth            string `json:"Auth"`
+	AuthBank        string `json:"AuthBank"`
+	ECI             string `json:"ECI"`
+	Card6No         string `json:"Card6No"`
+	Card4No         string `json:"Card4No"`
+}
+
+// newebpayQueryReply is the reply of QueryTradeInfo
+type newebpayQueryReply struct {
+	Status  string              `json:"Status"`
+	Message string              `json:"Message"`
+	Result  NewebpayQueryResult `json:"Result"`
+}
+
+// newebpayCheckValue is the upper case hex of the SHA-256 of the fields wrapped by the key and the IV. QueryTradeInfo names them differently in the request and in the reply.
+func newebpayCheckValue(prefix, fields, suffix string) string {
+	sum := sha256.Sum256([]byte(prefix + "&" + fields + "&" + suffix))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// QueryCheckValue signs the query of the order of the amount
+func (s NewebPayStore) QueryCheckValue(orderNumber string, amount int) string {
+	return newebpayCheckValue("IV="+s.HashIV, fmt.Sprintf("Amt=%d&MerchantID=%s&MerchantOrderNo=%s", amount, s.ID, orderNumber), "Key="+s.HashKey)
+}
+
+// ResultCheckCode signs the trade replied by QueryTradeInfo
+func (s NewebPayStore) ResultCheckCode(r NewebpayQueryResult) string {
+	return newebpayCheckValue("HashIV="+s.HashIV, fmt.Sprintf("Amt=%d&MerchantID=%s&MerchantOrderNo=%s&TradeNo=%s", r.Amt, r.MerchantID, r.MerchantOrderNo, r.TradeNo), "HashKey="+s.HashKey)
+}
+
+// QueryTradeInfo queries the trade of the order of the amount. The result has to be signed by CheckCode.
+// Ref: https://www.newebpay.com/website/Page/download_file?name=NewebPay_QueryTradeInfo_1.0.4.pdf
+func (s NewebPayStore) QueryTradeInfo(ctx context.Context, orderNumber string, amount int) (result NewebpayQueryResult, err error) {
+	if s.Endpoint == "" {
+		return result, fmt.Errorf("store has no Endpoint")
+	} else if s.HashKey == "" || s.HashIV == "" {
+		return result, fmt.Errorf("store has no HashKey or HashIV")
+	}
+
+	form := url.Values{
+		"MerchantID":      {s.ID},
+		"Version":         {newebpayQueryVersion},
+		"RespondType":     {string(RespondWithJSON)},
+		"CheckValue":      {s.QueryCheckValue(orderNumber, amount)},
+		"TimeStamp":       {strconv.FormatInt(time.Now().Unix(), 10)},
+		"MerchantOrderNo": {orderNumber},
+		"Amt":             {strconv.Itoa(amount)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.Endpoint, "/")+"/API/QueryTradeInfo", strings.NewReader(form.Encode()))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return result, errors.Wrapf(err, "querying order(%s) encountered error", orderNumber)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("querying order(%s) replied status %d", orderNumber, resp.StatusCode)
+	}
+
+	var reply newebpayQueryReply
+	if err = json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return result, errors.Wrapf(err, "reply of order(%s) is not JSON", orderNumber)
+	} else if reply.Status != NewebpayStatusSuccess {
+		return result, fmt.Errorf("querying order(%s) failed with %s: %s", orderNumber, reply.Status, reply.Message)
+	} else if reply.Result.MerchantID != s.ID || reply.Result.MerchantOrderNo != orderNumber {
+		return result, fmt.Errorf("reply is for order(%s) of merchant(%s) instead of order(%s)", reply.Result.MerchantOrderNo, reply.Result.MerchantID, orderNumber)
+	} else if subtle.ConstantTimeCompare([]byte(s.ResultCheckCode(reply.Result)), []byte(strings.ToUpper(reply.Result.CheckCode))) != 1 {
+		return result, fmt.Errorf("reply of order(%s) has an invalid CheckCode", orderNumber)
+	}
+	return reply.Result, nil
+}
+
+// Trade converts the queried trade to the trade with the fields of newebpayPayment. A paid trade has the status SUCCESS like the notification, otherwise the status is TradeStatus.
+func (r NewebpayQueryResult) Trade() (Trade, error) {
+	status := r.TradeStatus
+	if r.TradeStatus == NewebpayTradeStatusPaid {
+		status = NewebpayStatusSuccess
+	}
+	trade, err := NewebpayNotification{
+		Status:  status,
+		Message: r.RespondMsg,
+		Result: NewebpayTradeResult{
+			MerchantID:      r.MerchantID,
+			Amt:             r.Amt,
+			TradeNo:         r.TradeNo,
+			MerchantOrderNo: r.MerchantOrderNo,
+			PaymentType:     r.PaymentType,
+			PayTime:         r.PayTime,
+			RespondCode:     r.RespondCode,
+			Auth:            r.Auth,
+			AuthBank:        r.AuthBank,
+			ECI:             r.ECI,
+			Card6No:         r.Card6No,
+			Card4No:         r.Card4No,
+		},
+	}.Trade()
+	trade.IsPending = r.TradeStatus == NewebpayTradeStatusUnpaid
+	return trade, err
+}
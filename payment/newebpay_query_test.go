@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNewebPayStore_QueryTrade(t *testing.T) {
+	store := NewebPayStore{ID: "MS12345", HashKey: testHashKey, HashIV: testHashIV}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		amount, _ := strconv.Atoi(r.PostFormValue("Amt"))
+		orderNumber := r.PostFormValue("MerchantOrderNo")
+		if r.URL.Path != "/API/QueryTradeInfo" || r.PostFormValue("CheckValue") != store.QueryCheckValue(orderNumber, amount) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reply := newebpayQueryReply{
+			Status: NewebpayStatusSuccess,
+			Result: NewebpayQueryResult{
+				MerchantID:      "MS12345",
+				Amt:             amount,
+				TradeNo:         "21120108000012345",
+				MerchantOrderNo: orderNumber,
+				TradeStatus:     NewebpayTradeStatusPaid,
+				PaymentType:     "CREDIT",
+				PayTime:         "2021-12-01 08:00:00",
+				RespondCode:     "00",
+			},
+		}
+		switch orderNumber {
+		case "unpaid":
+			reply.Result.TradeStatus = NewebpayTradeStatusUnpaid
+			reply.Result.PayTime = ""
+		case "failed":
+			reply.Result.TradeStatus = NewebpayTradeStatusFailed
+		case "not found":
+			reply = newebpayQueryReply{Status: "TRA10021", Message: "not found"}
+		}
+		reply.Result.CheckCode = store.ResultCheckCode(reply.Result)
+		if orderNumber == "tampered" {
+			reply.Result.Amt = 1
+		}
+		_ = json.NewEncoder(w).Encode(reply)
+	}))
+	defer server.Close()
+	store.Endpoint = server.URL
+
+	tests := []struct {
+		name             string
+		orderNumber      string
+		wantIsSuccessful bool
+		wantIsPending    bool
+		wantStatus       string
+		wantErr          bool
+	}{
+		{name: "paid", orderNumber: "M21120100001", wantIsSuccessful: true, wantStatus: NewebpayStatusSuccess},
+		{name: "unpaid", orderNumber: "unpaid", wantIsPending: true, wantStatus: NewebpayTradeStatusUnpaid},
+		{name: "failed", orderNumber: "failed", wantStatus: NewebpayTradeStatusFailed},
+		{name: "not found", orderNumber: "not found", wantErr: true},
+		{name: "tampered", orderNumber: "tampered", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.QueryTrade(context.Background(), tt.orderNumber, 199)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewebPayStore.QueryTrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.IsSuccessful != tt.wantIsSuccessful || got.IsPending != tt.wantIsPending || got.Status != tt.wantStatus {
+				t.Errorf("NewebPayStore.QueryTrade() = %+v, want successful %v, pending %v and status %q", got, tt.wantIsSuccessful, tt.wantIsPending, tt.wantStatus)
+			}
+			if tt.wantIsSuccessful && (got.Amount != 199 || got.PaidAt.IsZero() || got.Record["tradeNumber"] != "21120108000012345") {
+				t.Errorf("NewebPayStore.QueryTrade() = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Versions of the credit card APIs
+const (
+	newebpayCancelVersion = "1.0"
+	newebpayCloseVersion  = "1.1"
+)
+
+// newebpayCloseTypeRefund is the CloseType of Close to refund a captured trade, while 1 is to capture it
+const newebpayCloseTypeRefund = "2"
+
+// newebpayIndexTypeOrder makes NewebPay find the trade by MerchantOrderNo
+const newebpayIndexTypeOrder = "1"
+
+// NewebpayAPIError is the error replied by the credit card APIs of NewebPay, e.g., the authorisation can't be cancelled after it's captured
+type NewebpayAPIError struct {
+	API     string
+	Status  string
+	Message string
+}
+
+func (e *NewebpayAPIError) Error() string {
+	return fmt.Sprintf("%s of NewebPay failed with %s: %s", e.API, e.Status, e.Message)
+}
+
+// postCreditCard POSTs the encrypted params of the order to the credit card API. The reply shares the fields of QueryTradeInfo, and it's verified if it has CheckCode.
+func (s NewebPayStore) postCreditCard(ctx context.Context, api, orderNumber string, params url.Values) (result NewebpayQueryResult, err error) {
+	if s.Endpoint == "" {
+		return result, fmt.Errorf("store has no Endpoint")
+	}
+	params.Set("RespondType", string(RespondWithJSON))
+	params.Set("TimeStamp", strconv.FormatInt(time.Now().Unix(), 10))
+	params.Set("MerchantOrderNo", orderNumber)
+	params.Set("IndexType", newebpayIndexTypeOrder)
+	postData, err := s.EncryptTradeInfo(params.Encode())
+	if err != nil {
+		return result, err
+	}
+
+	form := url.Values{
+		"MerchantID_": {s.ID},
+		"PostData_":   {postData},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.Endpoint, "/")+"/API/CreditCard/"+api, strings.NewReader(form.Encode()))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return result, errors.Wrapf(err, "requesting %s of order(%s) encountered error", api, orderNumber)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("requesting %s of order(%s) replied status %d", api, orderNumber, resp.StatusCode)
+	}
+
+	var reply newebpayQueryReply
+	if err = json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return result, errors.Wrapf(err, "reply of order(%s) is not JSON", orderNumber)
+	} else if reply.Status != NewebpayStatusSuccess {
+		return result, &NewebpayAPIError{API: api, Status: reply.Status, Message: reply.Message}
+	} else if reply.Result.MerchantOrderNo != orderNumber {
+		return result, fmt.Errorf("reply is for order(%s) instead of order(%s)", reply.Result.MerchantOrderNo, orderNumber)
+	} else if reply.Result.CheckCode != "" && s.ResultCheckCode(reply.Result) != strings.ToUpper(reply.Result.CheckCode) {
+		return result, fmt.Errorf("reply of order(%s) has an invalid CheckCode", orderNumber)
+	}
+	return reply.Result, nil
+}
+
+// CancelAuthorization cancels the authorisation of the whole amount of the trade before it's captured
+// Ref: https://www.newebpay.com/website/Page/download_file?name=NewebPay_CreditCard_Cancel_1.0.1.pdf
+func (s NewebPayStore) CancelAuthorization(ctx context.Context, orderNumber, tradeNumber string, amount int) (NewebpayQueryResult, error) {
+	return s.postCreditCard(ctx, "Cancel", orderNumber, url.Values{
+		"Version": {newebpayCancelVersion},
+		"Amt":     {strconv.Itoa(amount)},
+		"TradeNo": {tradeNumber},
+	})
+}
+
+// CloseRefund requests to refund the amount of the captured trade. It can be a part of the trade.
+// Ref: https://www.newebpay.com/website/Page/download_file?name=NewebPay_CreditCard_Close_1.0.6.pdf
+func (s NewebPayStore) CloseRefund(ctx context.Context, orderNumber, tradeNumber string, amount int) (NewebpayQueryResult, error) {
+	return s.postCreditCard(ctx, "Close", orderNumber, url.Values{
+		"Version":   {newebpayCloseVersion},
+		"Amt":       {strconv.Itoa(amount)},
+		"TradeNo":   {tradeNumber},
+		"CloseType": {newebpayCloseTypeRefund},
+	})
+}
+
+// Refund cancels the authorisation if the whole trade is refunded before it's captured. Otherwise, or if NewebPay rejects the cancellation, the amount is refunded by Close.
+func (s NewebPayStore) Refund(ctx context.Context, orderNumber, tradeNumber string, amount int, isFull bool) (refund Refund, err error) {
+	if amount <= 0 {
+		return refund, fmt.Errorf("refund has invalid amount(%d)", amount)
+	}
+	refund = Refund{
+		Provider:    NewebpayProviderName,
+		OrderNumber: orderNumber,
+		TradeNumber: tradeNumber,
+		Amount:      amount,
+	}
+	if isFull {
+		_, err = s.CancelAuthorization(ctx, orderNumber, tradeNumber, amount)
+		var apiErr *NewebpayAPIError
+		if err == nil {
+			refund.IsCancelled = true
+			return refund, nil
+		} else if !errors.As(err, &apiErr) {
+			return refund, err
+		}
+	}
+	if _, err = s.CloseRefund(ctx, orderNumber, tradeNumber, amount); err != nil {
+		return refund, errors.Wrapf(err, "refunding trade(%s) encountered error", tradeNumber)
+	}
+	return refund, nil
+}
@@ -0,0 +1,79 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNewebPayStore_Refund(t *testing.T) {
+	store := NewebPayStore{ID: "MS12345", HashKey: testHashKey, HashIV: testHashIV}
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := store.DecryptTradeInfo(r.PostFormValue("PostData_"))
+		if err != nil || r.PostFormValue("MerchantID_") != store.ID {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		params, _ := url.ParseQuery(payload)
+		calls = append(calls, r.URL.Path)
+		tradeNumber := params.Get("TradeNo")
+
+		reply := newebpayQueryReply{
+			Status: NewebpayStatusSuccess,
+			Result: NewebpayQueryResult{
+				MerchantID:      store.ID,
+				TradeNo:         tradeNumber,
+				MerchantOrderNo: params.Get("MerchantOrderNo"),
+			},
+		}
+		switch {
+		case r.URL.Path == "/API/CreditCard/Cancel" && tradeNumber == "captured":
+			reply = newebpayQueryReply{Status: "TRA10045", Message: "trade is captured"}
+		case r.URL.Path == "/API/CreditCard/Close" && (tradeNumber == "failed" || params.Get("CloseType") != newebpayCloseTypeRefund):
+			reply = newebpayQueryReply{Status: "TRA10052", Message: "refund failed"}
+		}
+		_ = json.NewEncoder(w).Encode(reply)
+	}))
+	defer server.Close()
+	store.Endpoint = server.URL
+
+	tests := []struct {
+		name            string
+		tradeNumber     string
+		amount          int
+		isFull          bool
+		wantIsCancelled bool
+		wantCalls       []string
+		wantErr         bool
+	}{
+		{name: "authorised", tradeNumber: "authorised", amount: 199, isFull: true, wantIsCancelled: true, wantCalls: []string{"/API/CreditCard/Cancel"}},
+		{name: "captured", tradeNumber: "captured", amount: 199, isFull: true, wantCalls: []string{"/API/CreditCard/Cancel", "/API/CreditCard/Close"}},
+		{name: "partial", tradeNumber: "authorised", amount: 99, wantCalls: []string{"/API/CreditCard/Close"}},
+		{name: "failed", tradeNumber: "failed", amount: 99, wantCalls: []string{"/API/CreditCard/Close"}, wantErr: true},
+		{name: "invalid amount", tradeNumber: "authorised", amount: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			got, err := store.Refund(context.Background(), "M21120100001", tt.tradeNumber, tt.amount, tt.isFull)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewebPayStore.Refund() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("NewebPayStore.Refund() called %v, want %v", calls, tt.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Errorf("NewebPayStore.Refund() called %v, want %v", calls, tt.wantCalls)
+				}
+			}
+			if !tt.wantErr && (got.IsCancelled != tt.wantIsCancelled || got.Amount != tt.amount) {
+				t.Errorf("NewebPayStore.Refund() = %+v, want cancelled %v", got, tt.wantIsCancelled)
+			}
+		})
+	}
+}
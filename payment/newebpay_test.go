@@ -89,6 +89,23 @@ func TestStore_GetNotifyURL(t *testing.T) {
 			fields: f,
 			want:   "https://domain/notify-payment",
 		},
+		{
+			name: "yearly gift",
+			args: args{
+				PurchaseInfo{
+					Merchandise: Merchandise{
+						Code:   "yearly",
+						Amount: 8888,
+					},
+					PurchasedAtUnixTime: 111,
+					OrderNumber:         "ordernumber",
+					MemberFirebaseID:    "memberid",
+					IsGift:              true,
+				},
+			},
+			fields: f,
+			want:   "https://domain/notify-payment?code=yearly&isGift=true",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -427,6 +444,52 @@ func TestStore_CreateNewebpayAgreementPayload(t *testing.T) {
 			wantPayload: "Amt=8888&CREDITAGREEMENT=1&ClientBackURL=https%3A%2F%2Fclientbackdomain%2Fclientback%3Famount%3D8888%26code%3Dyearly%26memberFirebaseId%3Dmemberid%26orderNumber%3Dordernumber%26purchasedAtUnixTime%3D111%26returnPath%3D%252Fstory%252Fabc&Email=email%40mail.com&EmailModify=1&LoginType=0&MerchantID=store+id&MerchantOrderNo=ordernumber&NotifyURL=https%3A%2F%2Fnotifydomain%2Fnotify&OrderComment=comment&P3D=1&RespondType=JSON&ReturnURL=https%3A%2F%2Fclientbackdomain%2Fhttp%3A%2F%2Freturnpath%3Famount%3D8888%26code%3Dyearly%26memberFirebaseId%3Dmemberid%26orderNumber%3Dordernumber%26purchasedAtUnixTime%3D111%26returnPath%3D%252Fstory%252Fabc&TimeStamp=123&TokenTerm=firebaseID&Version=1.6",
 			wantErr:     false,
 		},
+		{
+			name:   "trial authorises the minimum amount",
+			fields: store,
+			args: args{
+				agreementInfo: NewebpayAgreementInfo{
+					Amount:       8888,
+					Email:        "email@mail.com",
+					ItemDesc:     "desc",
+					OrderComment: "comment",
+					TokenTerm:    "firebaseID",
+				},
+				purchaseInfo: PurchaseInfo{
+					Merchandise: Merchandise{
+						Code:   "monthly",
+						Amount: 8888,
+					},
+					PurchasedAtUnixTime: 111,
+					OrderNumber:         "ordernumber",
+					MemberFirebaseID:    "memberid",
+					TrialDays:           14,
+				},
+			},
+			wantPayload: "Amt=1&CREDITAGREEMENT=1&ClientBackURL=https%3A%2F%2Fclientbackdomain%2Fclientback%3Famount%3D8888%26code%3Dmonthly%26memberFirebaseId%3Dmemberid%26orderNumber%3Dordernumber%26purchasedAtUnixTime%3D111%26trialDays%3D14&Email=email%40mail.com&EmailModify=1&ItemDesc=desc&LoginType=0&MerchantID=store+id&MerchantOrderNo=ordernumber&NotifyURL=https%3A%2F%2Fnotifydomain%2Fnotify%3Fcode%3Dmonthly&OrderComment=comment&P3D=1&RespondType=JSON&ReturnURL=https%3A%2F%2Fclientbackdomain%2Fhttp%3A%2F%2Freturnpath%3Famount%3D8888%26code%3Dmonthly%26memberFirebaseId%3Dmemberid%26orderNumber%3Dordernumber%26purchasedAtUnixTime%3D111%26trialDays%3D14&TimeStamp=111&TokenTerm=firebaseID&Version=1.6",
+		},
+		{
+			name:   "one-time has no trial",
+			fields: store,
+			args: args{
+				agreementInfo: NewebpayAgreementInfo{
+					Amount:       8888,
+					Email:        "email@mail.com",
+					OrderComment: "comment",
+				},
+				purchaseInfo: PurchaseInfo{
+					Merchandise: Merchandise{
+						Code:   "one_time",
+						PostID: "postid",
+						Amount: 8888,
+					},
+					PurchasedAtUnixTime: 111,
+					TrialDays:           7,
+				},
+			},
+			wantPayload: "",
+			wantErr:     true,
+		},
 		{
 			name:   "unsafe char",
 			fields: store,
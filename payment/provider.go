@@ -0,0 +1,113 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupported is returned if the provider doesn't support the operation
+var ErrUnsupported = errors.New("operation is not supported by the payment provider")
+
+// IsRejected tells if err is replied by the provider to reject the request, rather than the request fails to reach it or its reply can't be understood. A rejected refund refunds nothing.
+func IsRejected(err error) bool {
+	var newebpayErr *NewebpayAPIError
+	var ecpayErr *ECPayAPIError
+	return errors.As(err, &newebpayErr) || errors.As(err, &ecpayErr)
+}
+
+// apiClient calls the APIs of the providers. The timeout bounds a request whose context has no deadline, e.g., a refund requested by an administrator.
+var apiClient = &http.Client{Timeout: 30 * time.Second}
+
+// Checkout is the subscription to pay with a provider
+type Checkout struct {
+	PurchaseInfo
+	Amount       int
+	Email        string
+	ItemDesc     string
+	OrderComment string
+	TokenTerm    string // The member whose card is kept for the recurring payments
+	IsRecurring  bool
+}
+
+// CheckoutForm is the form the client POSTs to Action to pay the subscription
+type CheckoutForm struct {
+	Action string
+	Fields map[string]string
+	// Payload is the plain payload of the form, if the provider has one. It's only kept for the deprecated newebpayPayload.
+	Payload string
+}
+
+// Trade is a trade of a provider notified by the callback or queried from it
+type Trade struct {
+	Provider     string
+	IsSuccessful bool
+	IsPending    bool   // The queried trade is neither paid nor failed yet
+	Status       string // The status or the return code of the provider
+	Message      string
+	OrderNumber  string
+	TradeNumber  string
+	Amount       int
+	PaidAt       time.Time // Zero if the trade is not paid
+	// Record is the fields of the payment recorded in the member service
+	Record map[string]interface{}
+}
+
+// Refund is a refund of a trade by the provider
+type Refund struct {
+	Provider    string
+	OrderNumber string
+	TradeNumber string
+	Amount      int
+	IsCancelled bool // The authorisation is cancelled instead of refunding the captured amount
+}
+
+// Provider is a payment service the subscriptions are paid with. Name is the paymentMethod of the subscriptions.
+type Provider interface {
+	Name() string
+	SupportsRecurring() bool
+	// CreateCheckout creates the form to pay the subscription
+	CreateCheckout(c Checkout) (CheckoutForm, error)
+	// VerifyCallback verifies and decodes the form POSTed by the provider to notify a trade
+	VerifyCallback(form url.Values) (Trade, error)
+	// CallbackReply is the body replied to a settled callback. The provider notifies again otherwise.
+	CallbackReply() string
+	// QueryTrade queries the trade of the order from the provider
+	QueryTrade(ctx context.Context, orderNumber string, amount int) (Trade, error)
+	// Refund refunds the amount of the trade. The authorisation may be cancelled instead if isFull and it's not captured yet.
+	Refund(ctx context.Context, orderNumber, tradeNumber string, amount int, isFull bool) (Refund, error)
+}
+
+// Providers are the providers by the name. The default one is used if the subscription has no preference.
+type Providers struct {
+	defaultName string
+	providers   map[string]Provider
+}
+
+// NewProviders registers the providers with the default one
+func NewProviders(defaultProvider Provider, others ...Provider) *Providers {
+	p := &Providers{
+		defaultName: defaultProvider.Name(),
+		providers:   map[string]Provider{defaultProvider.Name(): defaultProvider},
+	}
+	for _, other := range others {
+		p.providers[other.Name()] = other
+	}
+	return p
+}
+
+// Get returns the provider of the name, or the default one if name is empty
+func (p *Providers) Get(name string) (Provider, error) {
+	if name == "" {
+		name = p.defaultName
+	}
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("payment provider(%s) is not available", name)
+	}
+	return provider, nil
+}
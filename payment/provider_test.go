@@ -0,0 +1,31 @@
+package payment
+
+import (
+	"testing"
+)
+
+func TestProviders_Get(t *testing.T) {
+	providers := NewProviders(NewebPayStore{}, ECPayStore{})
+	tests := []struct {
+		name     string
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default", want: NewebpayProviderName},
+		{name: "newebpay", provider: "newebpay", want: NewebpayProviderName},
+		{name: "ecpay", provider: "ecpay", want: ECPayProviderName},
+		{name: "unavailable", provider: "tappay", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := providers.Get(tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Providers.Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Name() != tt.want {
+				t.Errorf("Providers.Get() = %v, want %v", got.Name(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package paywall
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+const (
+	ReaderMember    = "member"
+	ReaderAnonymous = "anonymous"
+)
+
+// AnonymousIDCookie is the cookie carrying the signed id issued to an anonymous reader
+const AnonymousIDCookie = "gw_anonymous_id"
+
+// defaultAnonymousIPLimitFactor sets the quota shared behind a client IP if AnonymousIPLimit isn't configured
+const defaultAnonymousIPLimitFactor = 10
+
+// Meter counts the premium posts a reader has opened for free in the current calendar month in Taipei
+type Meter struct {
+	rdb              cache.Rediser
+	memberLimit      int
+	anonymousLimit   int
+	anonymousIPLimit int
+	secret           []byte
+	location         *time.Location
+	now              func() time.Time
+}
+
+// NewMeter returns nil if metering is disabled for both members and anonymous readers. The sets of the readers bypass the local cache of rdb.
+func NewMeter(rdb cache.Rediser, c config.Metering) (*Meter, error) {
+	if c.MemberLimit <= 0 && c.AnonymousLimit <= 0 {
+		return nil, nil
+	}
+	if rdb == nil {
+		return nil, errors.New("metering requires redis")
+	}
+	if c.AnonymousLimit > 0 && c.Secret == "" {
+		return nil, errors.New("metering anonymous readers requires a secret to sign their ids")
+	}
+	anonymousIPLimit := c.AnonymousIPLimit
+	if anonymousIPLimit <= 0 {
+		anonymousIPLimit = defaultAnonymousIPLimitFactor * c.AnonymousLimit
+	}
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load the location for metering")
+	}
+	return &Meter{
+		rdb:              cache.Unwrap(rdb),
+		memberLimit:      c.MemberLimit,
+		anonymousLimit:   c.AnonymousLimit,
+		anonymousIPLimit: anonymousIPLimit,
+		secret:           []byte(c.Secret),
+		location:         tz,
+		now:              time.Now,
+	}, nil
+}
+
+// Limit returns the monthly quota of the kind of reader
+func (m *Meter) Limit(readerType string) int {
+	if readerType == ReaderMember {
+		return m.memberLimit
+	}
+	return m.anonymousLimit
+}
+
+func (m *Meter) sign(id string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IssueAnonymousID mints a random id for an anonymous reader and signs it, so readers can't make up ids for fresh quotas
+func (m *Meter) IssueAnonymousID() string {
+	id := xid.New().String()
+	return id + "." + m.sign(id)
+}
+
+// VerifyAnonymousID returns the id in the signed value issued by IssueAnonymousID
+func (m *Meter) VerifyAnonymousID(signed string) (id string, ok bool) {
+	parts := strings.SplitN(signed, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || !hmac.Equal([]byte(parts[1]), []byte(m.sign(parts[0]))) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// Unlock records postID as opened by the reader if the quota allows. Posts opened before in the same month don't consume the quota again.
+//
+// readerID of an anonymous reader is the id verified by VerifyAnonymousID, or empty to meter by clientIP. Anonymous readers behind a client IP also share the quota of AnonymousIPLimit, so minting new ids doesn't open more posts.
+func (m *Meter) Unlock(ctx context.Context, readerType, readerID, clientIP, postID string) (isUnlocked bool, remaining int, err error) {
+	limit := m.Limit(readerType)
+	if readerType != ReaderMember && readerID == "" && clientIP != "" {
+		readerID = "ip:" + clientIP
+	}
+	if limit <= 0 || readerID == "" {
+		return false, 0, nil
+	}
+
+	now := m.now().In(m.location)
+	key := fmt.Sprintf("%s.%s.%s.%s", "apigateway", "meter", readerType, readerID)
+	isUnlocked, isNew, remaining, err := addToMonthlySet(ctx, m.rdb, key, postID, limit, now)
+	if err != nil || !isUnlocked || readerType == ReaderMember || clientIP == "" {
+		return isUnlocked, remaining, err
+	}
+
+	ipKey := fmt.Sprintf("%s.%s.%s.%s", "apigateway", "meter", "anonymous-ip", clientIP)
+	isUnlockedByIP, _, ipRemaining, ipErr := addToMonthlySet(ctx, m.rdb, ipKey, postID, m.anonymousIPLimit, now)
+	if ipErr != nil || !isUnlockedByIP {
+		if isNew {
+			// the post isn't opened, so it doesn't consume the quota of the reader either
+			if err = m.rdb.SRem(ctx, monthlyKey(key, now), postID).Err(); err != nil {
+				return false, 0, errors.Wrapf(err, "cannot roll back %s of reader(%s)", postID, readerID)
+			}
+		}
+		return false, 0, ipErr
+	}
+	return true, minInt(remaining, ipRemaining), nil
+}
+
+func monthlyKey(keyPrefix string, now time.Time) string {
+	return fmt.Sprintf("%s.%s", keyPrefix, now.Format("2006-01"))
+}
+
+// addToMonthlySet adds member to the set of the month of now unless the set is full. Adding an existing member succeeds without consuming the limit, and isNew is false. The expiration of the set is set in the same transaction, so the set never outlives the month for long.
+func addToMonthlySet(ctx context.Context, rdb cache.Rediser, keyPrefix, member string, limit int, now time.Time) (isAdded, isNew bool, remaining int, err error) {
+	key := monthlyKey(keyPrefix, now)
+	// keep the key for a while after the month ends
+	endOfMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+
+	var added, count *redis.IntCmd
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		added = pipe.SAdd(ctx, key, member)
+		count = pipe.SCard(ctx, key)
+		pipe.ExpireAt(ctx, key, endOfMonth.Add(24*time.Hour))
+		return nil
+	})
+	if err != nil {
+		if added != nil && added.Val() == 1 {
+			// the member isn't told to be added, so it doesn't consume the limit
+			if remErr := rdb.SRem(ctx, key, member).Err(); remErr != nil {
+				return false, false, 0, errors.Wrapf(err, "cannot add %s to set(%s) nor roll it back: %v", member, key, remErr)
+			}
+		}
+		return false, false, 0, errors.Wrapf(err, "cannot add %s to set(%s)", member, key)
+	}
+
+	if added.Val() == 0 {
+		// the member has been added in this month
+		return true, false, maxInt(limit-int(count.Val()), 0), nil
+	}
+
+	if int(count.Val()) > limit {
+		// Roll back. Concurrent requests may both roll back, which favours the limit over the reader.
+		if err = rdb.SRem(ctx, key, member).Err(); err != nil {
+			return false, false, 0, errors.Wrapf(err, "cannot roll back %s from set(%s)", member, key)
+		}
+		return false, false, 0, nil
+	}
+	return true, true, limit - int(count.Val()), nil
+}
@@ -0,0 +1,205 @@
+package paywall
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+)
+
+// setRediser implements SAdd, SCard, SRem, ExpireAt and TxPipelined of cache.Rediser in memory. Transactions on the keys containing failing fail before they run.
+type setRediser struct {
+	cache.Rediser
+	sets      map[string]map[string]bool
+	expiresAt map[string]time.Time
+	failing   string
+}
+
+func (r *setRediser) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if r.sets[key] == nil {
+		r.sets[key] = map[string]bool{}
+	}
+	var n int64
+	for _, m := range members {
+		if !r.sets[key][m.(string)] {
+			r.sets[key][m.(string)] = true
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (r *setRediser) SCard(ctx context.Context, key string) *redis.IntCmd {
+	return redis.NewIntResult(int64(len(r.sets[key])), nil)
+}
+
+func (r *setRediser) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	var n int64
+	for _, m := range members {
+		if r.sets[key][m.(string)] {
+			delete(r.sets[key], m.(string))
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (r *setRediser) ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd {
+	if r.expiresAt != nil {
+		r.expiresAt[key] = tm
+	}
+	return redis.NewBoolResult(true, nil)
+}
+
+func (r *setRediser) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	pipe := &setPipeliner{r: r}
+	if err := fn(pipe); err != nil {
+		return nil, err
+	}
+	return nil, pipe.err
+}
+
+// setPipeliner runs the commands of setRediser right away
+type setPipeliner struct {
+	redis.Pipeliner
+	r   *setRediser
+	err error
+}
+
+func (p *setPipeliner) fail(key string) bool {
+	if p.r.failing != "" && strings.Contains(key, p.r.failing) {
+		p.err = errors.New("transaction failed")
+	}
+	return p.err != nil
+}
+
+func (p *setPipeliner) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if p.fail(key) {
+		return redis.NewIntResult(0, p.err)
+	}
+	return p.r.SAdd(ctx, key, members...)
+}
+
+func (p *setPipeliner) SCard(ctx context.Context, key string) *redis.IntCmd {
+	if p.fail(key) {
+		return redis.NewIntResult(0, p.err)
+	}
+	return p.r.SCard(ctx, key)
+}
+
+func (p *setPipeliner) ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd {
+	if p.fail(key) {
+		return redis.NewBoolResult(false, p.err)
+	}
+	return p.r.ExpireAt(ctx, key, tm)
+}
+
+func TestMeter_Unlock(t *testing.T) {
+	m, err := NewMeter(&setRediser{sets: map[string]map[string]bool{}}, config.Metering{MemberLimit: 2, AnonymousLimit: 1, AnonymousIPLimit: 2, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("NewMeter() error = %v", err)
+	}
+	november := time.Date(2021, 11, 30, 23, 0, 0, 0, m.location)
+	december := time.Date(2021, 12, 1, 0, 0, 0, 0, m.location)
+
+	// the cases run in order against the same sets
+	tests := []struct {
+		name          string
+		now           time.Time
+		readerType    string
+		readerID      string
+		clientIP      string
+		postID        string
+		wantUnlocked  bool
+		wantRemaining int
+	}{
+		{name: "member opens the first post", now: november, readerType: ReaderMember, readerID: "f1", postID: "p1", wantUnlocked: true, wantRemaining: 1},
+		{name: "member opens the same post again", now: november, readerType: ReaderMember, readerID: "f1", postID: "p1", wantUnlocked: true, wantRemaining: 1},
+		{name: "member opens the last post", now: november, readerType: ReaderMember, readerID: "f1", postID: "p2", wantUnlocked: true, wantRemaining: 0},
+		{name: "member runs out of the quota", now: november, readerType: ReaderMember, readerID: "f1", postID: "p3"},
+		{name: "another member has a quota", now: november, readerType: ReaderMember, readerID: "f2", postID: "p3", wantUnlocked: true, wantRemaining: 1},
+		{name: "quota is renewed in the next month", now: december, readerType: ReaderMember, readerID: "f1", postID: "p3", wantUnlocked: true, wantRemaining: 1},
+		{name: "anonymous reader without an id is metered by IP", now: november, readerType: ReaderAnonymous, clientIP: "1.1.1.1", postID: "p1", wantUnlocked: true},
+		{name: "IP runs out of the quota of an anonymous reader", now: november, readerType: ReaderAnonymous, clientIP: "1.1.1.1", postID: "p2"},
+		{name: "anonymous reader with an id", now: november, readerType: ReaderAnonymous, readerID: "a1", clientIP: "1.1.1.1", postID: "p2", wantUnlocked: true, wantRemaining: 0},
+		{name: "new id behind the same IP runs out of the IP quota", now: november, readerType: ReaderAnonymous, readerID: "a2", clientIP: "1.1.1.1", postID: "p3"},
+		{name: "new id keeps its quota after being rejected by IP", now: november, readerType: ReaderAnonymous, readerID: "a2", clientIP: "2.2.2.2", postID: "p3", wantUnlocked: true},
+		{name: "anonymous reader without an id nor IP", now: november, readerType: ReaderAnonymous, postID: "p1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.now = func() time.Time { return tt.now }
+			gotUnlocked, gotRemaining, err := m.Unlock(context.Background(), tt.readerType, tt.readerID, tt.clientIP, tt.postID)
+			if err != nil {
+				t.Fatalf("Meter.Unlock() error = %v", err)
+			}
+			if gotUnlocked != tt.wantUnlocked || gotRemaining != tt.wantRemaining {
+				t.Errorf("Meter.Unlock() = %v, %d, want %v, %d", gotUnlocked, gotRemaining, tt.wantUnlocked, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestMeter_Unlock_transaction(t *testing.T) {
+	rdb := &setRediser{sets: map[string]map[string]bool{}, expiresAt: map[string]time.Time{}}
+	m, err := NewMeter(rdb, config.Metering{MemberLimit: 2, AnonymousLimit: 2, AnonymousIPLimit: 2, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("NewMeter() error = %v", err)
+	}
+	m.now = func() time.Time { return time.Date(2021, 11, 30, 23, 0, 0, 0, m.location) }
+	ctx := context.Background()
+
+	// the expiration is set by every add, so a set doesn't live forever even if setting it fails once
+	key := "apigateway.meter.member.f1.2021-11"
+	_, _, _ = m.Unlock(ctx, ReaderMember, "f1", "", "p1")
+	delete(rdb.expiresAt, key)
+	_, _, _ = m.Unlock(ctx, ReaderMember, "f1", "", "p1")
+	if want := time.Date(2021, 12, 2, 0, 0, 0, 0, m.location); !rdb.expiresAt[key].Equal(want) {
+		t.Errorf("set(%s) expires at %v, want %v", key, rdb.expiresAt[key], want)
+	}
+
+	// the post of the reader is rolled back if the IP quota fails to be counted
+	rdb.failing = "anonymous-ip"
+	if isUnlocked, _, err := m.Unlock(ctx, ReaderAnonymous, "a1", "1.1.1.1", "p1"); err == nil || isUnlocked {
+		t.Errorf("Meter.Unlock() = %v, %v, want an error", isUnlocked, err)
+	}
+	if n := len(rdb.sets["apigateway.meter.anonymous.a1.2021-11"]); n != 0 {
+		t.Errorf("reader has %d posts opened, want 0", n)
+	}
+}
+
+func TestMeter_VerifyAnonymousID(t *testing.T) {
+	m, _ := NewMeter(&setRediser{}, config.Metering{AnonymousLimit: 1, Secret: "secret"})
+	other, _ := NewMeter(&setRediser{}, config.Metering{AnonymousLimit: 1, Secret: "another secret"})
+	valid := m.IssueAnonymousID()
+
+	tests := []struct {
+		name   string
+		signed string
+		wantOK bool
+	}{
+		{name: "issued", signed: valid, wantOK: true},
+		{name: "signed by another secret", signed: other.IssueAnonymousID()},
+		{name: "made up", signed: "c6k5d1t0kq3u0f1c2j7g"},
+		{name: "empty", signed: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.VerifyAnonymousID(tt.signed)
+			if ok != tt.wantOK || (ok && got == "") {
+				t.Errorf("Meter.VerifyAnonymousID() = %q, %v, want ok %v", got, ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewMeter(t *testing.T) {
+	if _, err := NewMeter(&setRediser{}, config.Metering{AnonymousLimit: 1}); err == nil {
+		t.Errorf("NewMeter() without a secret error = nil")
+	}
+}
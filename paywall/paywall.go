@@ -0,0 +1,242 @@
+// Package paywall decides whether a post is premium and how it is truncated for readers without access
+package paywall
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mirror-media/apigateway/config"
+)
+
+const (
+	StrategyNone       = "none"
+	StrategyBlocks     = "blocks"
+	StrategyWords      = "words"
+	StrategyPercentage = "percentage"
+)
+
+// Post contains the attributes of a post which policies can match against
+type Post struct {
+	ID                   string
+	Categories           []string
+	IsMemberOnlyCategory bool
+	Tags                 []string
+	Writers              []string
+	Flags                map[string]bool
+	PublishedDate        time.Time
+	WordCount            int
+}
+
+// Decision is the result of evaluating the policies against a post
+type Decision struct {
+	// Policy is the name of the matched policy. It's empty if no policy matches.
+	Policy string
+	// IsPremium tells if the post should be truncated for readers without access
+	IsPremium  bool
+	truncation truncation
+}
+
+type truncation struct {
+	strategy   string
+	blocks     int
+	words      int
+	percentage int
+}
+
+type match struct {
+	categories           map[string]bool
+	isMemberOnlyCategory *bool
+	tags                 map[string]bool
+	writers              map[string]bool
+	flags                []string
+	publishedAfter       time.Time
+	publishedBefore      time.Time
+	minWordCount         int
+}
+
+type policy struct {
+	name       string
+	match      match
+	truncation truncation
+}
+
+// Engine evaluates the content gating policies in order
+type Engine struct {
+	policies []policy
+	flags    []string
+}
+
+// DefaultPolicies mirrors the original hardcoded behaviour: posts with a member only category keep 3 apiData blocks, or 5 if the post has no less than 1000 words
+func DefaultPolicies() config.ContentGating {
+	isMemberOnly := true
+	return config.ContentGating{
+		Policies: []config.ContentGatingPolicy{
+			{
+				Name: "member-only-long",
+				Match: config.ContentGatingMatch{
+					IsMemberOnlyCategory: &isMemberOnly,
+					MinWordCount:         1000,
+				},
+				Truncation: config.ContentGatingTruncation{
+					Strategy: StrategyBlocks,
+					Blocks:   5,
+				},
+			},
+			{
+				Name: "member-only",
+				Match: config.ContentGatingMatch{
+					IsMemberOnlyCategory: &isMemberOnly,
+				},
+				Truncation: config.ContentGatingTruncation{
+					Strategy: StrategyBlocks,
+					Blocks:   3,
+				},
+			},
+		},
+	}
+}
+
+// NewEngine validates the config and builds an Engine. DefaultPolicies is used if no policy is provided.
+func NewEngine(c config.ContentGating) (*Engine, error) {
+	if len(c.Policies) == 0 {
+		c = DefaultPolicies()
+	}
+
+	e := &Engine{
+		policies: make([]policy, 0, len(c.Policies)),
+	}
+	flagSet := make(map[string]bool)
+	for i, p := range c.Policies {
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("policy-%d", i)
+		}
+
+		t, err := newTruncation(p.Truncation)
+		if err != nil {
+			return nil, fmt.Errorf("policy(%s) has invalid truncation: %v", name, err)
+		}
+
+		m := match{
+			categories:           toSet(p.Match.Categories),
+			isMemberOnlyCategory: p.Match.IsMemberOnlyCategory,
+			tags:                 toSet(p.Match.Tags),
+			writers:              toSet(p.Match.Writers),
+			flags:                p.Match.Flags,
+			minWordCount:         p.Match.MinWordCount,
+		}
+		if p.Match.PublishedAfter != "" {
+			if m.publishedAfter, err = time.Parse(time.RFC3339, p.Match.PublishedAfter); err != nil {
+				return nil, fmt.Errorf("policy(%s) has invalid PublishedAfter: %v", name, err)
+			}
+		}
+		if p.Match.PublishedBefore != "" {
+			if m.publishedBefore, err = time.Parse(time.RFC3339, p.Match.PublishedBefore); err != nil {
+				return nil, fmt.Errorf("policy(%s) has invalid PublishedBefore: %v", name, err)
+			}
+		}
+		for _, f := range p.Match.Flags {
+			if !flagSet[f] {
+				flagSet[f] = true
+				e.flags = append(e.flags, f)
+			}
+		}
+
+		e.policies = append(e.policies, policy{
+			name:       name,
+			match:      m,
+			truncation: t,
+		})
+	}
+	return e, nil
+}
+
+func newTruncation(c config.ContentGatingTruncation) (truncation, error) {
+	t := truncation{
+		strategy:   c.Strategy,
+		blocks:     c.Blocks,
+		words:      c.Words,
+		percentage: c.Percentage,
+	}
+	switch c.Strategy {
+	case StrategyNone:
+	case StrategyBlocks:
+		if c.Blocks < 0 {
+			return t, fmt.Errorf("blocks(%d) cannot be negative", c.Blocks)
+		}
+	case StrategyWords:
+		if c.Words < 0 {
+			return t, fmt.Errorf("words(%d) cannot be negative", c.Words)
+		}
+	case StrategyPercentage:
+		if c.Percentage < 0 || c.Percentage > 100 {
+			return t, fmt.Errorf("percentage(%d) is not between 0 and 100", c.Percentage)
+		}
+	default:
+		return t, fmt.Errorf("unsupported strategy(%s)", c.Strategy)
+	}
+	return t, nil
+}
+
+// Flags returns the post-level boolean fields the policies depend on
+func (e *Engine) Flags() []string {
+	return e.flags
+}
+
+// Decide returns the decision of the first policy matching the post
+func (e *Engine) Decide(post Post) Decision {
+	for _, p := range e.policies {
+		if p.match.isMatched(post) {
+			return Decision{
+				Policy:     p.name,
+				IsPremium:  p.truncation.strategy != StrategyNone,
+				truncation: p.truncation,
+			}
+		}
+	}
+	return Decision{}
+}
+
+func (m match) isMatched(post Post) bool {
+	if m.isMemberOnlyCategory != nil && *m.isMemberOnlyCategory != post.IsMemberOnlyCategory {
+		return false
+	}
+	if len(m.categories) > 0 && !containsAny(m.categories, post.Categories) {
+		return false
+	}
+	if len(m.tags) > 0 && !containsAny(m.tags, post.Tags) {
+		return false
+	}
+	if len(m.writers) > 0 && !containsAny(m.writers, post.Writers) {
+		return false
+	}
+	for _, f := range m.flags {
+		if !post.Flags[f] {
+			return false
+		}
+	}
+	if !m.publishedAfter.IsZero() && !post.PublishedDate.After(m.publishedAfter) {
+		return false
+	}
+	if !m.publishedBefore.IsZero() && !post.PublishedDate.Before(m.publishedBefore) {
+		return false
+	}
+	return post.WordCount >= m.minWordCount
+}
+
+func toSet(ss []string) map[string]bool {
+	set := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		set[s] = true
+	}
+	return set
+}
+
+func containsAny(set map[string]bool, ss []string) bool {
+	for _, s := range ss {
+		if set[s] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package paywall
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mirror-media/apigateway/config"
+)
+
+func block(text string) interface{} {
+	return map[string]interface{}{
+		"type":    "unstyled",
+		"content": []interface{}{text},
+	}
+}
+
+func TestEngine_Decide(t *testing.T) {
+	isMemberOnly := true
+	published, _ := time.Parse(time.RFC3339, "2021-11-07T10:00:00+08:00")
+	gating := config.ContentGating{
+		Policies: []config.ContentGatingPolicy{
+			{
+				Name: "free-writer",
+				Match: config.ContentGatingMatch{
+					Writers: []string{"free writer"},
+				},
+				Truncation: config.ContentGatingTruncation{Strategy: StrategyNone},
+			},
+			{
+				Name: "advertised",
+				Match: config.ContentGatingMatch{
+					Flags: []string{"isAdvertised"},
+				},
+				Truncation: config.ContentGatingTruncation{Strategy: StrategyWords, Words: 10},
+			},
+			{
+				Name: "archive",
+				Match: config.ContentGatingMatch{
+					Tags:            []string{"archive"},
+					PublishedBefore: "2021-01-01T00:00:00+08:00",
+				},
+				Truncation: config.ContentGatingTruncation{Strategy: StrategyPercentage, Percentage: 50},
+			},
+			{
+				Name: "member-only",
+				Match: config.ContentGatingMatch{
+					IsMemberOnlyCategory: &isMemberOnly,
+				},
+				Truncation: config.ContentGatingTruncation{Strategy: StrategyBlocks, Blocks: 3},
+			},
+		},
+	}
+	e, err := NewEngine(gating)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		post          Post
+		wantPolicy    string
+		wantIsPremium bool
+	}{
+		{
+			name: "free writer wins over member only category",
+			post: Post{
+				IsMemberOnlyCategory: true,
+				Writers:              []string{"free writer"},
+			},
+			wantPolicy:    "free-writer",
+			wantIsPremium: false,
+		},
+		{
+			name: "flag",
+			post: Post{
+				Flags: map[string]bool{"isAdvertised": true},
+			},
+			wantPolicy:    "advertised",
+			wantIsPremium: true,
+		},
+		{
+			name: "tag published after the date",
+			post: Post{
+				Tags:          []string{"archive"},
+				PublishedDate: published,
+			},
+			wantPolicy:    "",
+			wantIsPremium: false,
+		},
+		{
+			name: "member only category",
+			post: Post{
+				IsMemberOnlyCategory: true,
+				PublishedDate:        published,
+			},
+			wantPolicy:    "member-only",
+			wantIsPremium: true,
+		},
+		{
+			name:          "no policy matches",
+			post:          Post{},
+			wantPolicy:    "",
+			wantIsPremium: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.Decide(tt.post)
+			if got.Policy != tt.wantPolicy || got.IsPremium != tt.wantIsPremium {
+				t.Errorf("Engine.Decide() = (%s, %v), want (%s, %v)", got.Policy, got.IsPremium, tt.wantPolicy, tt.wantIsPremium)
+			}
+		})
+	}
+}
+
+func TestNewEngine_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy config.ContentGatingPolicy
+	}{
+		{
+			name: "unsupported strategy",
+			policy: config.ContentGatingPolicy{
+				Truncation: config.ContentGatingTruncation{Strategy: "lines"},
+			},
+		},
+		{
+			name: "percentage over 100",
+			policy: config.ContentGatingPolicy{
+				Truncation: config.ContentGatingTruncation{Strategy: StrategyPercentage, Percentage: 101},
+			},
+		},
+		{
+			name: "invalid date",
+			policy: config.ContentGatingPolicy{
+				Match:      config.ContentGatingMatch{PublishedAfter: "2021-01-01"},
+				Truncation: config.ContentGatingTruncation{Strategy: StrategyNone},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEngine(config.ContentGating{Policies: []config.ContentGatingPolicy{tt.policy}}); err == nil {
+				t.Errorf("NewEngine() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestDecision_Truncate(t *testing.T) {
+	apiData := []interface{}{
+		block("<b>一二三</b>四五"),
+		block("六七八九十"),
+		block("一二三四五"),
+		block("六七八九十"),
+		block("一二三四五"),
+		block("六七八九十"),
+	}
+	tests := []struct {
+		name       string
+		truncation truncation
+		want       int
+	}{
+		{
+			name:       "blocks",
+			truncation: truncation{strategy: StrategyBlocks, blocks: 3},
+			want:       3,
+		},
+		{
+			name:       "blocks more than the post has",
+			truncation: truncation{strategy: StrategyBlocks, blocks: 10},
+			want:       6,
+		},
+		{
+			name:       "words",
+			truncation: truncation{strategy: StrategyWords, words: 12},
+			want:       2,
+		},
+		{
+			name:       "words keeps at least one block",
+			truncation: truncation{strategy: StrategyWords, words: 1},
+			want:       1,
+		},
+		{
+			name:       "percentage",
+			truncation: truncation{strategy: StrategyPercentage, percentage: 50},
+			want:       3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Decision{IsPremium: true, truncation: tt.truncation}
+			got := d.Truncate(apiData)
+			if !reflect.DeepEqual(got, apiData[0:tt.want]) {
+				t.Errorf("Decision.Truncate() kept %d blocks, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package paywall
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+const (
+	// ShareTokenQueryKey is the query parameter carrying a share token
+	ShareTokenQueryKey = "shareToken"
+	// ShareTokenHeader is the header carrying a share token
+	ShareTokenHeader = "X-Share-Token"
+)
+
+// ShareClaims are the claims of a share token. Subject is the firebase ID of the member who shares the post.
+type ShareClaims struct {
+	PostID string `json:"postId"`
+	jwt.RegisteredClaims
+}
+
+// ShareLinker mints and verifies the signed tokens which unlock a premium post for anyone holding them
+type ShareLinker struct {
+	rdb          cache.Rediser
+	secret       []byte
+	ttl          time.Duration
+	monthlyQuota int
+	location     *time.Location
+	now          func() time.Time
+}
+
+// NewShareLinker returns nil if no secret is configured
+func NewShareLinker(rdb cache.Rediser, c config.ShareLink) (*ShareLinker, error) {
+	if c.Secret == "" {
+		return nil, nil
+	}
+	if c.TTL <= 0 {
+		return nil, fmt.Errorf("share link has invalid TTL(%d)", c.TTL)
+	}
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load the location for share link")
+	}
+	return &ShareLinker{
+		rdb:          rdb,
+		secret:       []byte(c.Secret),
+		ttl:          time.Duration(c.TTL) * time.Second,
+		monthlyQuota: c.MonthlyQuota,
+		location:     tz,
+		now:          time.Now,
+	}, nil
+}
+
+// Mint signs a share token of postID for the member. Each member can share MonthlyQuota posts every calendar month, and sharing the same post again doesn't consume the quota.
+func (s *ShareLinker) Mint(ctx context.Context, firebaseID, postID string) (token string, expiresAt time.Time, remaining int, err error) {
+	if firebaseID == "" || postID == "" {
+		return "", time.Time{}, 0, errors.New("firebaseID and postID are required to mint a share token")
+	}
+
+	now := s.now()
+	if s.monthlyQuota > 0 {
+		if s.rdb == nil {
+			return "", time.Time{}, 0, errors.New("share link quota requires redis")
+		}
+		key := fmt.Sprintf("%s.%s.%s", "apigateway", "share", firebaseID)
+		var isAdded bool
+		if isAdded, _, remaining, err = addToMonthlySet(ctx, s.rdb, key, postID, s.monthlyQuota, now.In(s.location)); err != nil {
+			return "", time.Time{}, 0, err
+		} else if !isAdded {
+			return "", time.Time{}, 0, fmt.Errorf("member(%s) has run out of the quota(%d) of share links in this month", firebaseID, s.monthlyQuota)
+		}
+	}
+
+	expiresAt = now.Add(s.ttl)
+	claims := ShareClaims{
+		PostID: postID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        xid.New().String(),
+			Subject:   firebaseID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", time.Time{}, 0, errors.Wrapf(err, "cannot sign share token of post(%s)", postID)
+	}
+	return token, expiresAt, remaining, nil
+}
+
+// Verify checks the signature and the expiration of the share token and returns the claims
+func (s *ShareLinker) Verify(token string) (*ShareClaims, error) {
+	claims := &ShareClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method(%v)", t.Header["alg"])
+		}
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid share token")
+	} else if claims.PostID == "" {
+		return nil, errors.New("share token has no postId")
+	}
+	return claims, nil
+}
@@ -0,0 +1,67 @@
+package paywall
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mirror-media/apigateway/config"
+)
+
+func TestShareLinker_Verify(t *testing.T) {
+	s, err := NewShareLinker(nil, config.ShareLink{Secret: "secret", TTL: 60})
+	if err != nil {
+		t.Fatalf("NewShareLinker() error = %v", err)
+	}
+	other, _ := NewShareLinker(nil, config.ShareLink{Secret: "another secret", TTL: 60})
+
+	valid, _, _, err := s.Mint(context.Background(), "firebaseID", "postID")
+	if err != nil {
+		t.Fatalf("ShareLinker.Mint() error = %v", err)
+	}
+	forged, _, _, _ := other.Mint(context.Background(), "firebaseID", "postID")
+
+	s.now = func() time.Time { return time.Now().Add(-2 * time.Minute) }
+	expired, _, _, _ := s.Mint(context.Background(), "firebaseID", "postID")
+	s.now = time.Now
+
+	tests := []struct {
+		name       string
+		token      string
+		wantPostID string
+		wantErr    bool
+	}{
+		{
+			name:       "valid",
+			token:      valid,
+			wantPostID: "postID",
+		},
+		{
+			name:    "signed by another secret",
+			token:   forged,
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   expired,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			token:   "abc",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ShareLinker.Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.PostID != tt.wantPostID {
+				t.Errorf("ShareLinker.Verify() postID = %v, want %v", got.PostID, tt.wantPostID)
+			}
+		})
+	}
+}
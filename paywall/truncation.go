@@ -0,0 +1,91 @@
+package paywall
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Truncate returns the apiData blocks to keep according to the strategy of the decision
+func (d Decision) Truncate(apiData []interface{}) []interface{} {
+	if !d.IsPremium {
+		return apiData
+	}
+
+	switch t := d.truncation; t.strategy {
+	case StrategyBlocks:
+		return apiData[0:minInt(t.blocks, len(apiData))]
+	case StrategyWords:
+		return truncateByWords(apiData, t.words)
+	case StrategyPercentage:
+		var total int
+		for _, block := range apiData {
+			total += countWords(block)
+		}
+		return truncateByWords(apiData, total*t.percentage/100)
+	default:
+		return apiData
+	}
+}
+
+// truncateByWords keeps the leading blocks within the budget. At least one block is kept so the reader has something to read.
+func truncateByWords(apiData []interface{}, budget int) []interface{} {
+	var words int
+	for i, block := range apiData {
+		words += countWords(block)
+		if words > budget {
+			return apiData[0:maxInt(i, minInt(1, len(apiData)))]
+		}
+	}
+	return apiData
+}
+
+// countWords counts the characters of the text in the content of an apiData block with html tags stripped. Spaces are ignored because most posts are in Chinese.
+func countWords(block interface{}) int {
+	b, ok := block.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	return countText(b["content"])
+}
+
+func countText(v interface{}) (count int) {
+	switch t := v.(type) {
+	case string:
+		var inTag bool
+		for len(t) > 0 {
+			r, size := utf8.DecodeRuneInString(t)
+			t = t[size:]
+			switch {
+			case r == '<':
+				inTag = true
+			case r == '>':
+				inTag = false
+			case !inTag && !unicode.IsSpace(r):
+				count++
+			}
+		}
+	case []interface{}:
+		for _, e := range t {
+			count += countText(e)
+		}
+	case map[string]interface{}:
+		for _, e := range t {
+			count += countText(e)
+		}
+	}
+	return count
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,198 @@
+// Package promotion validates promotion codes and reserves their redemption
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// counterRetention is how long the redemption counters are kept after the promotion ends
+const counterRetention = 90 * 24 * time.Hour
+
+// Promotion is a valid promotion to apply to a subscription
+type Promotion struct {
+	ID       int
+	Code     string
+	Discount float64
+	EndAt    time.Time // zero if the promotion never ends
+}
+
+// Apply returns the amount after the discount. The discount is the amount off the price, and the amount has to stay positive.
+func (p Promotion) Apply(price float64) (float64, error) {
+	amount := price - p.Discount
+	if amount <= 0 {
+		return 0, fmt.Errorf("promotion(%s) cannot be applied to the price(%v)", p.Code, price)
+	}
+	return amount, nil
+}
+
+// Redeemer retrieves promotions from the member service and reserves their redemption in Redis
+type Redeemer struct {
+	rdb               cache.Rediser
+	client            *graphql.Client
+	defaultUsageLimit int
+	usageLimits       map[string]int
+	perMemberLimit    int
+	now               func() time.Time
+}
+
+// NewRedeemer retrieves promotions with client and reserves redemption in the counters of rdb, bypassing its local cache
+func NewRedeemer(rdb cache.Rediser, client *graphql.Client, c config.Promotion) *Redeemer {
+	usageLimits := make(map[string]int, len(c.UsageLimits))
+	for code, limit := range c.UsageLimits {
+		usageLimits[strings.ToLower(code)] = limit
+	}
+	return &Redeemer{
+		rdb:               cache.Unwrap(rdb),
+		client:            client,
+		defaultUsageLimit: c.DefaultUsageLimit,
+		usageLimits:       usageLimits,
+		perMemberLimit:    c.PerMemberLimit,
+		now:               time.Now,
+	}
+}
+
+// UsageLimit returns the number of times the code can be redeemed. 0 means unlimited.
+func (r *Redeemer) UsageLimit(code string) int {
+	if limit, ok := r.usageLimits[strings.ToLower(code)]; ok {
+		return limit
+	}
+	return r.defaultUsageLimit
+}
+
+// Retrieve returns the promotion of the code if it's active, in its date range and for the frequency. A promotion without a plan applies to any frequency.
+func (r *Redeemer) Retrieve(ctx context.Context, code string, frequency model.SubscriptionFrequencyType) (p Promotion, err error) {
+	gql := `query ($code: String!) {
+  allPromotions(where: {code: $code}) {
+    id
+    code
+    plan
+    state
+    startAt
+    endAt
+    discount
+  }
+}`
+	req := graphql.NewRequest(gql)
+	req.Var("code", code)
+
+	var resp struct {
+		Promotions []*model.Promotion `json:"allPromotions"`
+	}
+
+	if err = r.client.Run(ctx, req, &resp); err != nil {
+		return p, errors.Wrapf(err, "retrieving promotion(%s) encountered error", code)
+	} else if len(resp.Promotions) == 0 {
+		return p, fmt.Errorf("promotion(%s) is not found", code)
+	}
+
+	promotion := resp.Promotions[0]
+	if promotion.State == nil || *promotion.State != model.PromotionStateTypeActive {
+		return p, fmt.Errorf("promotion(%s) is not %s", code, model.PromotionStateTypeActive)
+	}
+	if promotion.Plan != nil && promotion.Plan.String() != frequency.String() {
+		return p, fmt.Errorf("promotion(%s) is only for %s subscriptions", code, *promotion.Plan)
+	}
+	if promotion.Discount == nil || *promotion.Discount <= 0 {
+		return p, fmt.Errorf("promotion(%s) has no discount", code)
+	}
+
+	p.Code = code
+	p.Discount = *promotion.Discount
+	now := r.now()
+	if promotion.StartAt != nil && *promotion.StartAt != "" {
+		startAt, err := time.Parse(time.RFC3339, *promotion.StartAt)
+		if err != nil {
+			return p, errors.Wrapf(err, "invalid startAt of promotion(%s)", code)
+		} else if now.Before(startAt) {
+			return p, fmt.Errorf("promotion(%s) has not started", code)
+		}
+	}
+	if promotion.EndAt != nil && *promotion.EndAt != "" {
+		if p.EndAt, err = time.Parse(time.RFC3339, *promotion.EndAt); err != nil {
+			return p, errors.Wrapf(err, "invalid endAt of promotion(%s)", code)
+		} else if now.After(p.EndAt) {
+			return p, fmt.Errorf("promotion(%s) has ended", code)
+		}
+	}
+
+	if p.ID, err = strconv.Atoi(promotion.ID); err != nil {
+		return p, errors.Wrapf(err, "invalid id of promotion(%s)", code)
+	}
+	return p, nil
+}
+
+func usageKey(promotionID int) string {
+	return fmt.Sprintf("%s.%s.%d", "apigateway", "promotion", promotionID)
+}
+
+func memberKey(promotionID int, firebaseID string) string {
+	return fmt.Sprintf("%s.%s", usageKey(promotionID), firebaseID)
+}
+
+// increase increases the counter and sets its expiration by the end of the promotion. It returns an error if the counter exceeds the limit after the increase, and the increase is undone. A limit of 0 means unlimited.
+func (r *Redeemer) increase(ctx context.Context, key string, limit int, endAt time.Time) (isExceeded bool, err error) {
+	n, err := r.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot increase counter(%s)", key)
+	}
+	if !endAt.IsZero() {
+		// the counter is still valid without the expiration
+		if err = r.rdb.ExpireAt(ctx, key, endAt.Add(counterRetention)).Err(); err != nil {
+			logrus.Warnf("cannot set expiration of counter(%s): %v", key, err)
+		}
+	}
+	if limit > 0 && n > int64(limit) {
+		r.decrease(ctx, key)
+		return true, nil
+	}
+	return false, nil
+}
+
+// decrease decreases the counter, and deletes it if nothing is counted
+func (r *Redeemer) decrease(ctx context.Context, key string) {
+	n, err := r.rdb.Decr(ctx, key).Result()
+	if err != nil {
+		logrus.Errorf("cannot decrease counter(%s): %v", key, err)
+	} else if n <= 0 {
+		r.rdb.Del(ctx, key)
+	}
+}
+
+// Reserve reserves a redemption of the promotion by the member. The counters are increased and compared with the limits at once, so concurrent checkouts can never exceed the limits together. It returns an error if the promotion has reached its usage limit or the member has reached the per-member limit. release gives back the reservation if the subscription isn't created.
+func (r *Redeemer) Reserve(ctx context.Context, p Promotion, firebaseID string) (release func(), err error) {
+	isExceeded, err := r.increase(ctx, usageKey(p.ID), r.UsageLimit(p.Code), p.EndAt)
+	if err != nil {
+		return nil, err
+	} else if isExceeded {
+		return nil, fmt.Errorf("promotion(%s) has reached its usage limit", p.Code)
+	}
+	isExceeded, err = r.increase(ctx, memberKey(p.ID, firebaseID), r.perMemberLimit, p.EndAt)
+	if err != nil || isExceeded {
+		r.decrease(ctx, usageKey(p.ID))
+	}
+	if err != nil {
+		return nil, err
+	} else if isExceeded {
+		return nil, fmt.Errorf("member(%s) has reached the limit of promotion(%s)", firebaseID, p.Code)
+	}
+	return func() {
+		r.Release(context.Background(), p.ID, firebaseID)
+	}, nil
+}
+
+// Release gives back the redemption of the promotion reserved by the member, when the subscription with the promoteId fails or is invalidated
+func (r *Redeemer) Release(ctx context.Context, promotionID int, firebaseID string) {
+	r.decrease(ctx, usageKey(promotionID))
+	r.decrease(ctx, memberKey(promotionID, firebaseID))
+}
@@ -0,0 +1,193 @@
+package promotion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/graph/member/model"
+)
+
+// counterRediser implements Get, Incr, Decr, Del and ExpireAt of cache.Rediser in memory. Expiration is ignored.
+type counterRediser struct {
+	cache.Rediser
+	counters map[string]int64
+}
+
+func (c *counterRediser) Get(ctx context.Context, key string) *redis.StringCmd {
+	n, ok := c.counters[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(strconv.FormatInt(n, 10), nil)
+}
+
+func (c *counterRediser) Incr(ctx context.Context, key string) *redis.IntCmd {
+	c.counters[key]++
+	return redis.NewIntResult(c.counters[key], nil)
+}
+
+func (c *counterRediser) Decr(ctx context.Context, key string) *redis.IntCmd {
+	c.counters[key]--
+	return redis.NewIntResult(c.counters[key], nil)
+}
+
+func (c *counterRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, key := range keys {
+		delete(c.counters, key)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+func (c *counterRediser) ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func TestPromotion_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "discounted", price: 1000, want: 900},
+		{name: "free", price: 100, wantErr: true},
+	}
+	p := Promotion{Code: "SPRING", Discount: 100}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Apply(tt.price)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedeemer_Retrieve(t *testing.T) {
+	promotions := map[string]string{
+		"SPRING":   `{"id": "1", "code": "SPRING", "state": "active", "startAt": "2021-11-01T00:00:00+08:00", "endAt": "2021-12-31T23:59:59+08:00", "discount": 100}`,
+		"YEARLY":   `{"id": "2", "code": "YEARLY", "plan": "yearly", "state": "active", "discount": 300}`,
+		"INACTIVE": `{"id": "3", "code": "INACTIVE", "state": "inactive", "discount": 100}`,
+		"ENDED":    `{"id": "4", "code": "ENDED", "state": "active", "endAt": "2021-11-30T23:59:59+08:00", "discount": 100}`,
+		"FUTURE":   `{"id": "5", "code": "FUTURE", "state": "active", "startAt": "2022-01-01T00:00:00+08:00", "discount": 100}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables struct {
+				Code string `json:"code"`
+			} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		promotion, ok := promotions[req.Variables.Code]
+		if !ok {
+			_, _ = w.Write([]byte(`{"data": {"allPromotions": []}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"allPromotions": [` + promotion + `]}}`))
+	}))
+	defer server.Close()
+
+	r := NewRedeemer(nil, graphql.NewClient(server.URL), config.Promotion{})
+	r.now = func() time.Time { return time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		code      string
+		frequency model.SubscriptionFrequencyType
+		wantID    int
+		wantErr   bool
+	}{
+		{name: "valid", code: "SPRING", frequency: model.SubscriptionFrequencyTypeOneTime, wantID: 1},
+		{name: "valid for the plan", code: "YEARLY", frequency: model.SubscriptionFrequencyTypeYearly, wantID: 2},
+		{name: "not for the plan", code: "YEARLY", frequency: model.SubscriptionFrequencyTypeMonthly, wantErr: true},
+		{name: "inactive", code: "INACTIVE", frequency: model.SubscriptionFrequencyTypeYearly, wantErr: true},
+		{name: "ended", code: "ENDED", frequency: model.SubscriptionFrequencyTypeYearly, wantErr: true},
+		{name: "not started", code: "FUTURE", frequency: model.SubscriptionFrequencyTypeYearly, wantErr: true},
+		{name: "not found", code: "UNKNOWN", frequency: model.SubscriptionFrequencyTypeYearly, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Retrieve(context.Background(), tt.code, tt.frequency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Retrieve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("Retrieve() = %v, want id %v", got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestRedeemer_Reserve(t *testing.T) {
+	rdb := &counterRediser{counters: map[string]int64{
+		usageKey(1):       3,
+		memberKey(2, "a"): 2,
+		usageKey(3):       100,
+		memberKey(3, "a"): 1,
+	}}
+	r := NewRedeemer(rdb, nil, config.Promotion{
+		DefaultUsageLimit: 3,
+		UsageLimits:       map[string]int{"unlimited": 0},
+		PerMemberLimit:    2,
+	})
+
+	tests := []struct {
+		name       string
+		promotion  Promotion
+		firebaseID string
+		wantUsage  map[string]int64
+		wantErr    bool
+	}{
+		{name: "never redeemed", promotion: Promotion{ID: 4, Code: "AUTUMN"}, firebaseID: "a", wantUsage: map[string]int64{usageKey(4): 1, memberKey(4, "a"): 1}},
+		{name: "usage limit is reached", promotion: Promotion{ID: 1, Code: "SPRING"}, firebaseID: "a", wantUsage: map[string]int64{usageKey(1): 3, memberKey(1, "a"): 0}, wantErr: true},
+		{name: "limit of the member is reached", promotion: Promotion{ID: 2, Code: "SUMMER"}, firebaseID: "a", wantUsage: map[string]int64{usageKey(2): 0, memberKey(2, "a"): 2}, wantErr: true},
+		{name: "another member", promotion: Promotion{ID: 2, Code: "SUMMER"}, firebaseID: "b", wantUsage: map[string]int64{usageKey(2): 1, memberKey(2, "b"): 1}},
+		{name: "unlimited usage and code is case insensitive", promotion: Promotion{ID: 3, Code: "Unlimited"}, firebaseID: "a", wantUsage: map[string]int64{usageKey(3): 101, memberKey(3, "a"): 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Reserve(context.Background(), tt.promotion, tt.firebaseID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Reserve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for key, want := range tt.wantUsage {
+				if got := rdb.counters[key]; got != want {
+					t.Errorf("counter(%s) = %d, want %d", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedeemer_Reserve_release(t *testing.T) {
+	rdb := &counterRediser{counters: map[string]int64{}}
+	r := NewRedeemer(rdb, nil, config.Promotion{DefaultUsageLimit: 1})
+	p := Promotion{ID: 1, Code: "SPRING", EndAt: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)}
+
+	release, err := r.Reserve(context.Background(), p, "a")
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if _, err = r.Reserve(context.Background(), p, "b"); err == nil {
+		t.Fatal("Reserve() over the usage limit is not rejected")
+	}
+	release()
+	if len(rdb.counters) != 0 {
+		t.Errorf("counters %v are left after the release", rdb.counters)
+	}
+	if _, err = r.Reserve(context.Background(), p, "b"); err != nil {
+		t.Errorf("Reserve() after the release error = %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/institution"
+	"github.com/mirror-media/apigateway/trial"
+	"github.com/sirupsen/logrus"
+)
+
+// PurgeRequest is the body of the cache purge endpoint. Tags are post IDs.
+type PurgeRequest struct {
+	URIs     []string `json:"uris"`
+	Prefixes []string `json:"prefixes"`
+	Tags     []string `json:"tags"`
+}
+
+type PurgeReply struct {
+	Purged int64 `json:"purged"`
+}
+
+// Purge deletes the cache entries by the request. Purged is the number of deleted keys so far even if an error occurs.
+func (pc *ProxyCache) Purge(ctx context.Context, req PurgeRequest) (purged int64, err error) {
+	n, err := pc.PurgeURIs(ctx, req.URIs...)
+	purged += n
+	if err != nil {
+		return purged, err
+	}
+	n, err = pc.PurgePrefixes(ctx, req.Prefixes...)
+	purged += n
+	if err != nil {
+		return purged, err
+	}
+	n, err = pc.PurgeTags(ctx, req.Tags...)
+	purged += n
+	return purged, err
+}
+
+func newPurgeHandler(pc *ProxyCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logrus.WithFields(logrus.Fields{
+			"path": c.FullPath(),
+		})
+
+		var req PurgeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+
+		purged, err := pc.Purge(c.Request.Context(), req)
+		if err != nil {
+			logger.Errorf("purging cache encountered error: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+				Data:   PurgeReply{Purged: purged},
+			})
+			return
+		}
+		logger.Infof("%d cache keys are purged by %+v", purged, req)
+		c.JSON(http.StatusOK, PurgeReply{Purged: purged})
+	}
+}
+
+// InvalidateEntitlementsRequest is the body of the entitlement invalidation endpoint
+type InvalidateEntitlementsRequest struct {
+	FirebaseIDs []string `json:"firebaseIds" binding:"required"`
+}
+
+func newInvalidateEntitlementsHandler(entitlements *entitlement.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logrus.WithFields(logrus.Fields{
+			"path": c.FullPath(),
+		})
+
+		var req InvalidateEntitlementsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+
+		if err := entitlements.Invalidate(c.Request.Context(), req.FirebaseIDs...); err != nil {
+			logger.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// parseMonthQuery parses the "month" query, e.g., 2006-01, or returns now. The request is aborted if the query is invalid.
+func parseMonthQuery(c *gin.Context) (month time.Time, ok bool) {
+	q := c.Query("month")
+	if q == "" {
+		return time.Now(), true
+	}
+	month, err := time.Parse("2006-01", q)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, ErrorReply{
+			Errors: []Error{{Message: "month should be in the format of 2006-01"}},
+		})
+		return month, false
+	}
+	// the middle of the month is in the same month in any timezone
+	return month.AddDate(0, 0, 14), true
+}
+
+// InstitutionUsageReply has the numbers of requests granted by each institution in the month
+type InstitutionUsageReply struct {
+	Month string           `json:"month"`
+	Usage map[string]int64 `json:"usage"`
+}
+
+// newInstitutionUsageHandler replies the usage in the month of the "month" query, e.g., 2006-01, or the current month
+func newInstitutionUsageHandler(institutions *institution.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logrus.WithFields(logrus.Fields{
+			"path": c.FullPath(),
+		})
+
+		if institutions == nil {
+			c.JSON(http.StatusOK, InstitutionUsageReply{Usage: map[string]int64{}})
+			return
+		}
+
+		month, ok := parseMonthQuery(c)
+		if !ok {
+			return
+		}
+
+		usage, err := institutions.Usage(c.Request.Context(), month)
+		if err != nil {
+			logger.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, InstitutionUsageReply{
+			Month: month.Format("2006-01"),
+			Usage: usage,
+		})
+	}
+}
+
+// TrialStatsReply has the numbers of trials started in the month, and trials converted or expired when they ended in the month
+type TrialStatsReply struct {
+	Month string           `json:"month"`
+	Stats map[string]int64 `json:"stats"`
+}
+
+// newTrialStatsHandler replies the stats of trials in the month of the "month" query, e.g., 2006-01, or the current month
+func newTrialStatsHandler(trials *trial.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logrus.WithFields(logrus.Fields{
+			"path": c.FullPath(),
+		})
+
+		month, ok := parseMonthQuery(c)
+		if !ok {
+			return
+		}
+
+		stats, err := trials.Stats(c.Request.Context(), month)
+		if err != nil {
+			logger.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, TrialStatsReply{
+			Month: month.Format("2006-01"),
+			Stats: stats,
+		})
+	}
+}
+
+// newAdminMutationRelay relays the GraphQL mutations of administrators to membermutation, which verifies the admin token again
+func newAdminMutationRelay(upstream string) (gin.HandlerFunc, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = target.Path
+			req.Host = target.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logrus.WithField("upstream", upstream).Error(err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	return func(c *gin.Context) {
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}, nil
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	cacheEntryPrefix = "gwcache:1:"
+
+	defaultCacheLockTTL  = 10 * time.Second
+	defaultCacheLockWait = 3 * time.Second
+	cacheLockPollPeriod  = 50 * time.Millisecond
+	upstreamFetchTimeout = 30 * time.Second
+)
+
+// upstreamFetcher fetches the body of a request from the upstream
+type upstreamFetcher func(ctx context.Context) (body []byte, status int, err error)
+
+// cacheEntry is a cached upstream response
+type cacheEntry struct {
+	body     []byte
+	status   int
+	storedAt time.Time
+}
+
+// ProxyCache caches upstream responses in redis by the rule of the path. Concurrent misses of the same key are coalesced within the process by singleflight and across replicas by a lock. Expired entries are still served within the stale-while-revalidate window while one replica refreshes them.
+type ProxyCache struct {
+	rdb         cache.Rediser
+	direct      cache.Rediser // for the tags and the locks, which bypass the local cache of rdb
+	rules       []cacheRule
+	defaultRule cacheRule
+	lockTTL     time.Duration
+	lockWait    time.Duration
+	compression string
+	group       singleflight.Group
+}
+
+// NewProxyCache rejects a compression it can't store responses with
+func NewProxyCache(rdb cache.Rediser, c config.RedisCache) (*ProxyCache, error) {
+	rules, defaultRule := newCacheRules(c)
+	pc := &ProxyCache{
+		rdb:         rdb,
+		direct:      cache.Unwrap(rdb),
+		rules:       rules,
+		defaultRule: defaultRule,
+		lockTTL:     time.Duration(c.LockTTL) * time.Second,
+		lockWait:    time.Duration(c.LockWait) * time.Millisecond,
+		compression: c.Compression,
+	}
+	switch pc.compression {
+	case "":
+		pc.compression = encodingGzip
+	case encodingGzip, encodingBrotli, encodingIdentity:
+	default:
+		return nil, fmt.Errorf("unsupported cache compression(%s)", pc.compression)
+	}
+	if pc.lockTTL <= 0 {
+		pc.lockTTL = defaultCacheLockTTL
+	}
+	if pc.lockWait <= 0 {
+		pc.lockWait = defaultCacheLockWait
+	}
+	return pc, nil
+}
+
+// rule returns the rule with the longest path prefix matching the path
+func (pc *ProxyCache) rule(path string) cacheRule {
+	for _, r := range pc.rules {
+		if strings.HasPrefix(path, r.pathPrefix) {
+			return r
+		}
+	}
+	return pc.defaultRule
+}
+
+// key returns the cache key of the URL with the query normalized by the rule
+func (pc *ProxyCache) key(u *url.URL, rule cacheRule) string {
+	return fmt.Sprintf("%s.%s.%s.%s", "apigateway", "proxy", "uri", rule.requestURI(u))
+}
+
+// keyOfURI returns the cache key of the request URI by the rule of its path
+func (pc *ProxyCache) keyOfURI(requestURI string) (string, error) {
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid request uri(%s)", requestURI)
+	}
+	return pc.key(u, pc.rule(u.Path)), nil
+}
+
+// tagKey is the key of the set of the cache keys of responses containing the post
+func (pc *ProxyCache) tagKey(postID string) string {
+	return fmt.Sprintf("%s.%s.%s.%s", "apigateway", "proxy", "tag", postID)
+}
+
+// load returns the cached entry of the key. isStale is true if the entry is older than the TTL of the rule but still in the stale-while-revalidate window. Entries kept only for errors are not returned.
+func (pc *ProxyCache) load(ctx context.Context, key string, rule cacheRule) (entry cacheEntry, isStale bool, ok bool) {
+	entry, ok = pc.loadLastKnown(ctx, key)
+	if !ok || entry.storedAt.IsZero() {
+		return entry, false, ok
+	}
+	age := time.Since(entry.storedAt)
+	if age > rule.ttl+rule.staleWhileRevalidate {
+		return cacheEntry{}, false, false
+	}
+	return entry, age > rule.ttl, true
+}
+
+// loadLastKnown returns the cached entry of the key regardless of its age
+func (pc *ProxyCache) loadLastKnown(ctx context.Context, key string) (entry cacheEntry, ok bool) {
+	cmd := pc.rdb.Get(ctx, key)
+	if cmd == nil {
+		return cacheEntry{}, false
+	}
+	b, err := cmd.Bytes()
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	if entry, err = decodeCacheEntry(b); err != nil {
+		logrus.Warnf("decoding redis cache(%s) encountered error: %v", key, err)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store saves the response if its status is cacheable by the rule, and keeps it for the TTL plus the stale-while-revalidate and stale-if-error windows. The key is tagged by the IDs of the posts in the body.
+func (pc *ProxyCache) store(ctx context.Context, key string, rule cacheRule, body []byte, status int) error {
+	if !rule.isCacheableStatus(status) {
+		return nil
+	}
+
+	ttl := rule.ttl + rule.staleWhileRevalidate + rule.staleIfError
+	b, err := encodeCacheEntry(cacheEntry{body: body, status: status, storedAt: time.Now()}, pc.compression)
+	if err != nil {
+		return err
+	}
+	if err = pc.rdb.Set(ctx, key, b, ttl).Err(); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Items []struct {
+			ID string `json:"_id"`
+		} `json:"_items"`
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		// not a list of posts
+		return nil
+	}
+	for _, item := range resp.Items {
+		if item.ID == "" {
+			continue
+		}
+		tagKey := pc.tagKey(item.ID)
+		if err := pc.direct.SAdd(ctx, tagKey, key).Err(); err != nil {
+			return errors.Wrapf(err, "tagging cache(%s) with post(%s) encountered error", key, item.ID)
+		}
+		// the tag lives as long as the longest living entry tagged by it, so its expiration is only extended. A tag without expiration has a negative TTL.
+		current, err := pc.direct.TTL(ctx, tagKey).Result()
+		if err != nil {
+			return errors.Wrapf(err, "getting expiration of tag(%s) encountered error", tagKey)
+		} else if current >= ttl {
+			continue
+		}
+		if err := pc.direct.Expire(ctx, tagKey, ttl).Err(); err != nil {
+			return errors.Wrapf(err, "setting expiration of tag(%s) encountered error", tagKey)
+		}
+	}
+	return nil
+}
+
+// PurgeURIs deletes the entries of the request URIs
+func (pc *ProxyCache) PurgeURIs(ctx context.Context, uris ...string) (purged int64, err error) {
+	keys := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		key, err := pc.keyOfURI(uri)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+	return pc.del(ctx, keys)
+}
+
+// PurgePrefixes deletes the entries of which the request URIs start with any of the prefixes
+func (pc *ProxyCache) PurgePrefixes(ctx context.Context, prefixes ...string) (purged int64, err error) {
+	for _, prefix := range prefixes {
+		keys, err := cache.Keys(ctx, pc.rdb, cache.EscapePattern(fmt.Sprintf("%s.%s.%s.%s", "apigateway", "proxy", "uri", prefix))+"*")
+		if err != nil {
+			return purged, err
+		}
+		n, err := pc.del(ctx, keys)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// PurgeTags deletes the entries containing any of the posts
+func (pc *ProxyCache) PurgeTags(ctx context.Context, postIDs ...string) (purged int64, err error) {
+	for _, postID := range postIDs {
+		tagKey := pc.tagKey(postID)
+		keys, err := pc.direct.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return purged, errors.Wrapf(err, "reading tag(%s) encountered error", tagKey)
+		}
+		n, err := pc.del(ctx, keys)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		if err = pc.direct.Del(ctx, tagKey).Err(); err != nil {
+			return purged, errors.Wrapf(err, "deleting tag(%s) encountered error", tagKey)
+		}
+	}
+	return purged, nil
+}
+
+// del deletes the keys one by one because keys of a cluster may be in different slots
+func (pc *ProxyCache) del(ctx context.Context, keys []string) (deleted int64, err error) {
+	for _, key := range keys {
+		n, err := pc.rdb.Del(ctx, key).Result()
+		if err != nil {
+			return deleted, errors.Wrapf(err, "deleting cache(%s) encountered error", key)
+		}
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// fetch gets the response from the upstream for a cache miss. Only one request per key is sent to the upstream by the process, and only the replica holding the lock of the key sends it unless the lock isn't released in time.
+func (pc *ProxyCache) fetch(key string, rule cacheRule, fetchUpstream upstreamFetcher) (body []byte, status int, err error) {
+	v, err, _ := pc.group.Do(key, func() (interface{}, error) {
+		// The fetch is shared by requests so it shouldn't be canceled by any of them
+		ctx, cancel := context.WithTimeout(context.Background(), upstreamFetchTimeout)
+		defer cancel()
+
+		release, isLocked := pc.lock(ctx, key)
+		if isLocked {
+			defer release()
+		} else if entry, ok := pc.waitFor(ctx, key, rule); ok {
+			return entry, nil
+		}
+
+		body, status, err := fetchUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err = pc.store(ctx, key, rule, body, status); err != nil {
+			logrus.Warnf("setting redis cache(%s) encountered error: %v", key, err)
+		}
+		return cacheEntry{body: body, status: status}, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	entry := v.(cacheEntry)
+	return entry.body, entry.status, nil
+}
+
+// revalidate refreshes the entry in the background if no other replica is doing it
+func (pc *ProxyCache) revalidate(key string, rule cacheRule, fetchUpstream upstreamFetcher) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), upstreamFetchTimeout)
+		defer cancel()
+
+		release, isLocked := pc.lock(ctx, key)
+		if !isLocked {
+			return
+		}
+		defer release()
+
+		body, status, err := fetchUpstream(ctx)
+		if err != nil {
+			logrus.Warnf("revalidating redis cache(%s) encountered error: %v", key, err)
+			return
+		}
+		if err = pc.store(ctx, key, rule, body, status); err != nil {
+			logrus.Warnf("setting redis cache(%s) encountered error: %v", key, err)
+		}
+	}()
+}
+
+// lock acquires the lock of the key across replicas. The lock expires by itself after lockTTL in case the holder dies.
+func (pc *ProxyCache) lock(ctx context.Context, key string) (release func(), isLocked bool) {
+	lockKey := key + ".lock"
+	owner := xid.New().String()
+	isLocked, err := pc.direct.SetNX(ctx, lockKey, owner, pc.lockTTL).Result()
+	if err != nil {
+		logrus.Warnf("acquiring lock(%s) encountered error: %v", lockKey, err)
+		return nil, false
+	} else if !isLocked {
+		return nil, false
+	}
+
+	return func() {
+		// Don't release the lock taken over by others after it expires
+		if v, err := pc.direct.Get(context.Background(), lockKey).Result(); err == nil && v == owner {
+			pc.direct.Del(context.Background(), lockKey)
+		}
+	}, true
+}
+
+// waitFor polls the entry of the key filled by the replica holding the lock
+func (pc *ProxyCache) waitFor(ctx context.Context, key string, rule cacheRule) (entry cacheEntry, ok bool) {
+	timer := time.NewTimer(pc.lockWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(cacheLockPollPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return cacheEntry{}, false
+		case <-timer.C:
+			return cacheEntry{}, false
+		case <-ticker.C:
+			if entry, isStale, ok := pc.load(ctx, key, rule); ok && !isStale {
+				return entry, true
+			}
+		}
+	}
+}
+
+// encodeCacheEntry compresses the body with the encoding and prepends the header of the format "gwcache:1:<stored at in unix time>:<status>:<encoding>\n" to it
+func encodeCacheEntry(entry cacheEntry, encoding string) ([]byte, error) {
+	body, err := compress(encoding, entry.body)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == "" {
+		encoding = encodingIdentity
+	}
+	header := cacheEntryPrefix + strconv.FormatInt(entry.storedAt.Unix(), 10) + ":" + strconv.Itoa(entry.status) + ":" + encoding + "\n"
+	b := make([]byte, 0, len(header)+len(body))
+	b = append(b, header...)
+	return append(b, body...), nil
+}
+
+// decodeCacheEntry returns an entry with a zero storedAt and the status 200 for entries without the header, which are saved by former versions
+func decodeCacheEntry(b []byte) (cacheEntry, error) {
+	if !bytes.HasPrefix(b, []byte(cacheEntryPrefix)) {
+		return cacheEntry{body: b, status: http.StatusOK}, nil
+	}
+	i := bytes.IndexByte(b, '\n')
+	if i == -1 {
+		return cacheEntry{}, errors.New("cache entry has no end of header")
+	}
+
+	fields := strings.Split(string(b[len(cacheEntryPrefix):i]), ":")
+	if len(fields) != 3 {
+		return cacheEntry{}, fmt.Errorf("cache entry has invalid header(%s)", b[:i])
+	}
+	unix, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return cacheEntry{}, errors.Wrap(err, "cache entry has invalid stored time")
+	}
+	status, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return cacheEntry{}, errors.Wrap(err, "cache entry has invalid status")
+	}
+	body, err := decompress(fields[2], b[i+1:])
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	return cacheEntry{body: body, status: status, storedAt: time.Unix(unix, 0)}, nil
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/config"
+)
+
+// memoryRediser is an in-memory cache.Rediser for tests. Expiration is only recorded by Expire.
+type memoryRediser struct {
+	mu   sync.Mutex
+	kv   map[string]string
+	sets map[string]map[string]bool
+	ttls map[string]time.Duration
+}
+
+func newMemoryRediser() *memoryRediser {
+	return &memoryRediser{
+		kv:   make(map[string]string),
+		sets: make(map[string]map[string]bool),
+		ttls: make(map[string]time.Duration),
+	}
+}
+
+func (m *memoryRediser) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch v := value.(type) {
+	case []byte:
+		m.kv[key] = string(v)
+	case string:
+		m.kv[key] = v
+	}
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (m *memoryRediser) SetXX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd {
+	m.mu.Lock()
+	_, ok := m.kv[key]
+	m.mu.Unlock()
+	if ok {
+		m.Set(ctx, key, value, ttl)
+	}
+	return redis.NewBoolResult(ok, nil)
+}
+
+func (m *memoryRediser) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.BoolCmd {
+	m.mu.Lock()
+	_, ok := m.kv[key]
+	m.mu.Unlock()
+	if !ok {
+		m.Set(ctx, key, value, ttl)
+	}
+	return redis.NewBoolResult(!ok, nil)
+}
+
+func (m *memoryRediser) Get(ctx context.Context, key string) *redis.StringCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.kv[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (m *memoryRediser) Incr(ctx context.Context, key string) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.ParseInt(m.kv[key], 10, 64)
+	n++
+	m.kv[key] = strconv.FormatInt(n, 10)
+	return redis.NewIntResult(n, nil)
+}
+
+func (m *memoryRediser) Decr(ctx context.Context, key string) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.ParseInt(m.kv[key], 10, 64)
+	n--
+	m.kv[key] = strconv.FormatInt(n, 10)
+	return redis.NewIntResult(n, nil)
+}
+
+func (m *memoryRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, k := range keys {
+		if _, ok := m.kv[k]; ok {
+			delete(m.kv, k)
+			n++
+		}
+		if _, ok := m.sets[k]; ok {
+			delete(m.sets, k)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (m *memoryRediser) Expire(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ttls[key] = ttl
+	return redis.NewBoolResult(true, nil)
+}
+
+func (m *memoryRediser) TTL(ctx context.Context, key string) *redis.DurationCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ttl, ok := m.ttls[key]
+	if !ok {
+		// the key has no expiration
+		ttl = -1
+	}
+	return redis.NewDurationResult(ttl, nil)
+}
+
+func (m *memoryRediser) ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func (m *memoryRediser) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]bool)
+	}
+	var n int64
+	for _, e := range members {
+		if s := e.(string); !m.sets[key][s] {
+			m.sets[key][s] = true
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (m *memoryRediser) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, e := range members {
+		if s := e.(string); m.sets[key][s] {
+			delete(m.sets[key], s)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (m *memoryRediser) SCard(ctx context.Context, key string) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return redis.NewIntResult(int64(len(m.sets[key])), nil)
+}
+
+func (m *memoryRediser) SIsMember(ctx context.Context, key string, member interface{}) *redis.BoolCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return redis.NewBoolResult(m.sets[key][member.(string)], nil)
+}
+
+func (m *memoryRediser) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	members := make([]string, 0, len(m.sets[key]))
+	for s := range m.sets[key] {
+		members = append(members, s)
+	}
+	return redis.NewStringSliceResult(members, nil)
+}
+
+// TxPipelined isn't used by ProxyCache
+func (m *memoryRediser) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func TestNewProxyCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression string
+		want        string
+		wantErr     bool
+	}{
+		{name: "default", want: encodingGzip},
+		{name: "brotli", compression: encodingBrotli, want: encodingBrotli},
+		{name: "identity", compression: encodingIdentity, want: encodingIdentity},
+		{name: "unsupported", compression: "zstd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewProxyCache(newMemoryRediser(), config.RedisCache{Compression: tt.compression})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProxyCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.compression != tt.want {
+				t.Errorf("NewProxyCache() compression = %v, want %v", got.compression, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyCache_fetch(t *testing.T) {
+	pc, _ := NewProxyCache(newMemoryRediser(), config.RedisCache{TTL: 60})
+	key, _ := pc.keyOfURI("/api/v0/posts")
+	rule := pc.rule("/api/v0/posts")
+
+	var calls int32
+	release := make(chan struct{})
+	fetchUpstream := func(ctx context.Context) ([]byte, int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte(`{"_items":[]}`), http.StatusOK, nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	bodies := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i], _, _ = pc.fetch(key, rule, fetchUpstream)
+		}(i)
+	}
+	// let the requests pile up on the in-flight fetch
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("upstream is called %d times, want 1", calls)
+	}
+	for i, b := range bodies {
+		if string(b) != `{"_items":[]}` {
+			t.Errorf("body of request %d = %s", i, b)
+		}
+	}
+	if entry, isStale, ok := pc.load(context.Background(), key, rule); !ok || isStale || string(entry.body) != `{"_items":[]}` {
+		t.Errorf("ProxyCache.load() = (%s, %v, %v), want the fresh body", entry.body, isStale, ok)
+	}
+}
+
+func TestProxyCache_load(t *testing.T) {
+	rdb := newMemoryRediser()
+	pc, _ := NewProxyCache(rdb, config.RedisCache{TTL: 60, StaleWhileRevalidate: 60})
+	ctx := context.Background()
+
+	fresh, _ := encodeCacheEntry(cacheEntry{body: []byte("fresh"), status: http.StatusNotFound, storedAt: time.Now()}, encodingGzip)
+	stale, _ := encodeCacheEntry(cacheEntry{body: []byte("stale"), status: http.StatusOK, storedAt: time.Now().Add(-90 * time.Second)}, encodingBrotli)
+	rdb.Set(ctx, "fresh", fresh, 0)
+	rdb.Set(ctx, "stale", stale, 0)
+	expired, _ := encodeCacheEntry(cacheEntry{body: []byte("expired"), status: http.StatusOK, storedAt: time.Now().Add(-150 * time.Second)}, encodingGzip)
+	rdb.Set(ctx, "expired", expired, 0)
+	rdb.Set(ctx, "corrupted", []byte(cacheEntryPrefix+strconv.FormatInt(time.Now().Unix(), 10)+":200:gzip\ncorrupted"), 0)
+	rdb.Set(ctx, "legacy", []byte("legacy"), 0)
+
+	tests := []struct {
+		key        string
+		wantBody   []byte
+		wantStatus int
+		wantStale  bool
+		wantOK     bool
+	}{
+		{key: "fresh", wantBody: []byte("fresh"), wantStatus: http.StatusNotFound, wantOK: true},
+		{key: "stale", wantBody: []byte("stale"), wantStatus: http.StatusOK, wantStale: true, wantOK: true},
+		{key: "expired"},
+		{key: "corrupted"},
+		{key: "legacy", wantBody: []byte("legacy"), wantStatus: http.StatusOK, wantOK: true},
+		{key: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			entry, isStale, ok := pc.load(ctx, tt.key, pc.defaultRule)
+			if !bytes.Equal(entry.body, tt.wantBody) || entry.status != tt.wantStatus || isStale != tt.wantStale || ok != tt.wantOK {
+				t.Errorf("ProxyCache.load() = (%s, %d, %v, %v), want (%s, %d, %v, %v)", entry.body, entry.status, isStale, ok, tt.wantBody, tt.wantStatus, tt.wantStale, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProxyCache_PurgeTags(t *testing.T) {
+	rdb := newMemoryRediser()
+	pc, _ := NewProxyCache(rdb, config.RedisCache{TTL: 60})
+	ctx := context.Background()
+
+	list, _ := pc.keyOfURI("/api/v0/posts")
+	detail, _ := pc.keyOfURI("/api/v0/posts?where=%7B%22slug%22%3A%22b%22%7D")
+	other, _ := pc.keyOfURI("/api/v0/sections")
+	pc.store(ctx, list, pc.defaultRule, []byte(`{"_items":[{"_id":"a"},{"_id":"b"}]}`), http.StatusOK)
+	pc.store(ctx, detail, pc.defaultRule, []byte(`{"_items":[{"_id":"b"}]}`), http.StatusOK)
+	pc.store(ctx, other, pc.defaultRule, []byte(`{"_items":[{"name":"news"}]}`), http.StatusOK)
+
+	purged, err := pc.PurgeTags(ctx, "b")
+	if err != nil {
+		t.Fatalf("ProxyCache.PurgeTags() error = %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("ProxyCache.PurgeTags() = %d, want 2", purged)
+	}
+	for key, wantOK := range map[string]bool{list: false, detail: false, other: true} {
+		if _, _, ok := pc.load(ctx, key, pc.defaultRule); ok != wantOK {
+			t.Errorf("cache(%s) exists = %v, want %v", key, ok, wantOK)
+		}
+	}
+}
+
+func TestProxyCache_store_tagExpiration(t *testing.T) {
+	rdb := newMemoryRediser()
+	pc, _ := NewProxyCache(rdb, config.RedisCache{TTL: 60})
+	long := cacheRule{ttl: 10 * time.Minute, statusCodes: map[int]bool{http.StatusOK: true}}
+	short := cacheRule{ttl: time.Minute, statusCodes: map[int]bool{http.StatusOK: true}}
+	body := []byte(`{"_items":[{"_id":"p1"}]}`)
+
+	if err := pc.store(context.Background(), "long", long, body, http.StatusOK); err != nil {
+		t.Fatalf("ProxyCache.store() error = %v", err)
+	}
+	if err := pc.store(context.Background(), "short", short, body, http.StatusOK); err != nil {
+		t.Fatalf("ProxyCache.store() error = %v", err)
+	}
+	if got := rdb.ttls[pc.tagKey("p1")]; got != 10*time.Minute {
+		t.Errorf("tag expires in %v, want %v", got, 10*time.Minute)
+	}
+}
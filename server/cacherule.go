@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mirror-media/apigateway/config"
+)
+
+// cacheRule decides if and how the responses of the requests with the path prefix are cached
+type cacheRule struct {
+	pathPrefix           string
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	methods              map[string]bool
+	statusCodes          map[int]bool
+	ignoredQueryParams   []string
+	sortQueryParams      bool
+	bypassHeader         string
+}
+
+func newCacheRule(c config.CacheRule) cacheRule {
+	r := cacheRule{
+		pathPrefix:           c.PathPrefix,
+		ttl:                  time.Duration(c.TTL) * time.Second,
+		staleWhileRevalidate: time.Duration(c.StaleWhileRevalidate) * time.Second,
+		staleIfError:         time.Duration(c.StaleIfError) * time.Second,
+		methods:              make(map[string]bool),
+		statusCodes:          make(map[int]bool),
+		ignoredQueryParams:   c.IgnoredQueryParams,
+		sortQueryParams:      c.SortQueryParams,
+		bypassHeader:         c.BypassHeader,
+	}
+	if len(c.Methods) == 0 {
+		r.methods[http.MethodGet] = true
+	}
+	for _, m := range c.Methods {
+		r.methods[strings.ToUpper(m)] = true
+	}
+	if len(c.StatusCodes) == 0 {
+		r.statusCodes[http.StatusOK] = true
+	}
+	for _, code := range c.StatusCodes {
+		r.statusCodes[code] = true
+	}
+	return r
+}
+
+// newCacheRules returns the configured rules sorted by the length of the path prefix in descending order and the default rule
+func newCacheRules(c config.RedisCache) (rules []cacheRule, defaultRule cacheRule) {
+	defaultRule = newCacheRule(config.CacheRule{
+		TTL:                  c.TTL,
+		StaleWhileRevalidate: c.StaleWhileRevalidate,
+		StaleIfError:         c.StaleIfError,
+	})
+	rules = make([]cacheRule, 0, len(c.Rules))
+	for _, r := range c.Rules {
+		rules = append(rules, newCacheRule(r))
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].pathPrefix) > len(rules[j].pathPrefix)
+	})
+	return rules, defaultRule
+}
+
+// isCacheable tells if the response of the request can be read from or written to the cache
+func (r cacheRule) isCacheable(req *http.Request) bool {
+	if r.ttl <= 0 || !r.methods[req.Method] {
+		return false
+	}
+	if r.bypassHeader != "" && req.Header.Get(r.bypassHeader) != "" {
+		return false
+	}
+	return true
+}
+
+func (r cacheRule) isCacheableStatus(status int) bool {
+	return r.statusCodes[status]
+}
+
+// requestURI normalizes the query of the URL by the rule
+func (r cacheRule) requestURI(u *url.URL) string {
+	if len(r.ignoredQueryParams) == 0 && !r.sortQueryParams {
+		return u.RequestURI()
+	}
+
+	var query string
+	if r.sortQueryParams {
+		q := u.Query()
+		for _, p := range r.ignoredQueryParams {
+			q.Del(p)
+		}
+		// Encode sorts the query by key, and values of the same key are sorted here
+		for _, vs := range q {
+			sort.Strings(vs)
+		}
+		query = q.Encode()
+	} else {
+		// keep the original order
+		ignored := make(map[string]bool, len(r.ignoredQueryParams))
+		for _, p := range r.ignoredQueryParams {
+			ignored[p] = true
+		}
+		params := strings.Split(u.RawQuery, "&")
+		kept := params[:0]
+		for _, param := range params {
+			k := param
+			if i := strings.Index(param, "="); i != -1 {
+				k = param[:i]
+			}
+			if k, err := url.QueryUnescape(k); err == nil && ignored[k] {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		query = strings.Join(kept, "&")
+	}
+
+	uri := u.EscapedPath()
+	if query != "" {
+		uri += "?" + query
+	}
+	return uri
+}
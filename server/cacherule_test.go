@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/mirror-media/apigateway/config"
+)
+
+func Test_cacheRule_requestURI(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.CacheRule
+		uri  string
+		want string
+	}{
+		{
+			name: "untouched",
+			rule: config.CacheRule{},
+			uri:  "/api/v0/posts?b=2&a=1",
+			want: "/api/v0/posts?b=2&a=1",
+		},
+		{
+			name: "ignored params keep the order",
+			rule: config.CacheRule{IgnoredQueryParams: []string{"utm_source", "fbclid"}},
+			uri:  "/api/v0/posts?b=2&utm_source=fb&a=1&fbclid=x",
+			want: "/api/v0/posts?b=2&a=1",
+		},
+		{
+			name: "sorted params",
+			rule: config.CacheRule{SortQueryParams: true, IgnoredQueryParams: []string{"utm_source"}},
+			uri:  "/api/v0/posts?b=2&utm_source=fb&a=3&a=1",
+			want: "/api/v0/posts?a=1&a=3&b=2",
+		},
+		{
+			name: "all params ignored",
+			rule: config.CacheRule{IgnoredQueryParams: []string{"utm_source"}},
+			uri:  "/api/v0/posts?utm_source=fb",
+			want: "/api/v0/posts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.uri)
+			if got := newCacheRule(tt.rule).requestURI(u); got != tt.want {
+				t.Errorf("cacheRule.requestURI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyCache_rule(t *testing.T) {
+	pc, _ := NewProxyCache(newMemoryRediser(), config.RedisCache{
+		TTL: 60,
+		Rules: []config.CacheRule{
+			{PathPrefix: "/api/v0", TTL: 30},
+			{PathPrefix: "/api/v0/posts", TTL: 10, BypassHeader: "X-No-Cache"},
+			{PathPrefix: "/api/v0/sections", TTL: 0},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		want   bool
+	}{
+		{name: "longest prefix wins", method: http.MethodGet, path: "/api/v0/posts", want: true},
+		{name: "bypass header", method: http.MethodGet, path: "/api/v0/posts", header: http.Header{"X-No-Cache": []string{"1"}}, want: false},
+		{name: "zero ttl disables the cache", method: http.MethodGet, path: "/api/v0/sections", want: false},
+		{name: "method not allowed", method: http.MethodPost, path: "/api/v0/posts", want: false},
+		{name: "default rule", method: http.MethodGet, path: "/story/a", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			if tt.header != nil {
+				req.Header = tt.header
+			}
+			if got := pc.rule(tt.path).isCacheable(req); got != tt.want {
+				t.Errorf("cacheRule.isCacheable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if pc.rule("/api/v0/posts").isCacheableStatus(http.StatusNotFound) {
+		t.Errorf("cacheRule.isCacheableStatus(404) = true, want false by default")
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+const (
+	encodingIdentity = "identity"
+	encodingGzip     = "gzip"
+	encodingBrotli   = "br"
+
+	// acceptedUpstreamEncodings is the Accept-Encoding sent to the upstream, which are all decodable by the gateway
+	acceptedUpstreamEncodings = "br, gzip"
+	// bodies smaller than it are not worth compressing
+	minCompressSize = 1024
+)
+
+// compress encodes b with the content coding
+func compress(encoding string, b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case encodingIdentity, "":
+		return b, nil
+	case encodingGzip:
+		w = gzip.NewWriter(&buf)
+	case encodingBrotli:
+		w = brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding(%s)", encoding)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, errors.Wrapf(err, "compressing with %s encountered error", encoding)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrapf(err, "compressing with %s encountered error", encoding)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress decodes b of the content coding
+func decompress(encoding string, b []byte) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case encodingIdentity, "":
+		return b, nil
+	case encodingGzip, "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gzip body")
+		}
+		defer gr.Close()
+		r = gr
+	case encodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(b))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding(%s)", encoding)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompressing %s body encountered error", encoding)
+	}
+	return decoded, nil
+}
+
+// decodeContentEncoding decodes the body by the Content-Encoding header, in which the codings are listed in the order they are applied
+func decodeContentEncoding(h http.Header, body []byte) ([]byte, error) {
+	codings := strings.Split(h.Get("Content-Encoding"), ",")
+	var err error
+	for i := len(codings) - 1; i >= 0; i-- {
+		if body, err = decompress(codings[i], body); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// negotiateEncoding picks brotli or gzip by the Accept-Encoding of the client, or identity if neither is acceptable
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			if v := strings.TrimSpace(p); strings.HasPrefix(v, "q=") {
+				if parsed, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := encodingIdentity, 0.0
+	// brotli is preferred on a tie
+	for _, coding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := qualities[coding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func Test_negotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: encodingIdentity},
+		{acceptEncoding: "gzip", want: encodingGzip},
+		{acceptEncoding: "gzip, deflate, br", want: encodingBrotli},
+		{acceptEncoding: "br;q=0.5, gzip", want: encodingGzip},
+		{acceptEncoding: "br;q=0, gzip;q=0", want: encodingIdentity},
+		{acceptEncoding: "*", want: encodingBrotli},
+		{acceptEncoding: "deflate", want: encodingIdentity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+				t.Errorf("negotiateEncoding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decodeContentEncoding(t *testing.T) {
+	body := []byte(`{"_items":[]}`)
+	gzipped, _ := compress(encodingGzip, body)
+	brotlied, _ := compress(encodingBrotli, body)
+	both, _ := compress(encodingBrotli, gzipped)
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		wantErr         bool
+	}{
+		{name: "plaintext", body: body},
+		{name: "gzip", contentEncoding: "gzip", body: gzipped},
+		{name: "brotli", contentEncoding: "br", body: brotlied},
+		{name: "gzip then brotli", contentEncoding: "gzip, br", body: both},
+		{name: "unsupported", contentEncoding: "deflate", body: body, wantErr: true},
+		{name: "mislabeled", contentEncoding: "gzip", body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.contentEncoding != "" {
+				h.Set("Content-Encoding", tt.contentEncoding)
+			}
+			got, err := decodeContentEncoding(h, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeContentEncoding() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !bytes.Equal(got, body) {
+				t.Errorf("decodeContentEncoding() = %s, want %s", got, body)
+			}
+		})
+	}
+}
+
+func Test_prepareReply(t *testing.T) {
+	small := []byte(`{"tokenState":"OK"}`)
+	large := bytes.Repeat([]byte(`{"_id":"a"},`), minCompressSize)
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		body             []byte
+		wantEncoding     string
+		wantETagEncoding string
+	}{
+		{name: "small body is not compressed", acceptEncoding: "br", body: small, wantETagEncoding: encodingIdentity},
+		{name: "brotli", acceptEncoding: "gzip, br", body: large, wantEncoding: encodingBrotli, wantETagEncoding: encodingBrotli},
+		{name: "gzip", acceptEncoding: "gzip", body: large, wantEncoding: encodingGzip, wantETagEncoding: encodingGzip},
+		{name: "identity", body: large, wantETagEncoding: encodingIdentity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/v0/posts", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			h := http.Header{}
+
+			encoded, _, err := prepareReply(h, req, http.StatusOK, tt.body, false)
+			if err != nil {
+				t.Fatalf("prepareReply() error = %v", err)
+			}
+			if got := h.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got, want := h.Get("ETag"), etagOf(tt.body, tt.wantETagEncoding); got != want {
+				t.Errorf("ETag = %s, want %s", got, want)
+			}
+			if decoded, err := decodeContentEncoding(h, encoded); err != nil || !bytes.Equal(decoded, tt.body) {
+				t.Errorf("decoded body doesn't match the original, error = %v", err)
+			}
+		})
+	}
+}
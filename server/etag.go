@@ -0,0 +1,95 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// etagOf returns a strong ETag of the final body replied to the client. Each content encoding is a different representation, so it's suffixed to the ETag.
+func etagOf(body []byte, encoding string) string {
+	sum := sha256.Sum256(body)
+	tag := base64.RawURLEncoding.EncodeToString(sum[:])
+	if encoding != encodingIdentity && encoding != "" {
+		tag += "-" + encoding
+	}
+	return `"` + tag + `"`
+}
+
+// isETagMatched tells if the If-None-Match header matches the etag with the weak comparison
+func isETagMatched(ifNoneMatch, etag string) bool {
+	if ifNoneMatch = strings.TrimSpace(ifNoneMatch); ifNoneMatch == "" {
+		return false
+	} else if ifNoneMatch == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setValidators sets the ETag and the caching headers of the body. Bodies personalized by the token, the metering quota or a share token must not be stored by shared caches.
+func setValidators(h http.Header, body []byte, encoding string, isPersonalized bool) (etag string) {
+	etag = etagOf(body, encoding)
+	h.Set("ETag", etag)
+	if isPersonalized {
+		h.Set("Cache-Control", "private, no-cache")
+	} else {
+		h.Set("Cache-Control", "public, no-cache")
+	}
+	h.Add("Vary", "Authorization")
+	return etag
+}
+
+// isConditional tells if the validators apply to the request and its response status
+func isConditional(method string, status int) bool {
+	return (method == http.MethodGet || method == http.MethodHead) && status == http.StatusOK
+}
+
+// prepareReply sets the validators and compresses the body by the Accept-Encoding of the request. isNotModified is true if the client already has the body.
+func prepareReply(h http.Header, req *http.Request, status int, body []byte, isPersonalized bool) (encoded []byte, isNotModified bool, err error) {
+	h.Add("Vary", "Accept-Encoding")
+	encoding := encodingIdentity
+	if len(body) >= minCompressSize {
+		encoding = negotiateEncoding(req.Header.Get("Accept-Encoding"))
+	}
+
+	if isConditional(req.Method, status) {
+		etag := setValidators(h, body, encoding, isPersonalized)
+		if isETagMatched(req.Header.Get("If-None-Match"), etag) {
+			return nil, true, nil
+		}
+	}
+
+	if encoding == encodingIdentity {
+		return body, false, nil
+	}
+	if encoded, err = compress(encoding, body); err != nil {
+		return nil, false, err
+	}
+	h.Set("Content-Encoding", encoding)
+	return encoded, false, nil
+}
+
+// replyConditionally replies the JSON body compressed if the client accepts it, or 304 if the client already has it
+func replyConditionally(c *gin.Context, status int, body []byte, isPersonalized bool) {
+	encoded, isNotModified, err := prepareReply(c.Writer.Header(), c.Request, status, body, isPersonalized)
+	if err != nil {
+		logrus.WithField("path", c.FullPath()).Error(err)
+		c.AbortWithStatus(http.StatusBadGateway)
+		return
+	} else if isNotModified {
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+	c.Abort()
+	c.Data(status, gin.MIMEJSON+"; charset=utf-8", encoded)
+}
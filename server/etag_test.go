@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Test_isETagMatched(t *testing.T) {
+	etag := etagOf([]byte(`{"tokenState":"OK"}`), encodingIdentity)
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{name: "empty", ifNoneMatch: "", want: false},
+		{name: "any", ifNoneMatch: "*", want: true},
+		{name: "exact", ifNoneMatch: etag, want: true},
+		{name: "weak", ifNoneMatch: "W/" + etag, want: true},
+		{name: "in a list", ifNoneMatch: `"a", ` + etag, want: true},
+		{name: "mismatch", ifNoneMatch: `"a", "b"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isETagMatched(tt.ifNoneMatch, etag); got != tt.want {
+				t.Errorf("isETagMatched() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_replyConditionally(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := []byte(`{"tokenState":"OK"}`)
+
+	tests := []struct {
+		name             string
+		method           string
+		status           int
+		ifNoneMatch      string
+		isPersonalized   bool
+		wantStatus       int
+		wantCacheControl string
+		wantBody         string
+	}{
+		{name: "full body", method: http.MethodGet, status: http.StatusOK, wantStatus: http.StatusOK, wantCacheControl: "public, no-cache", wantBody: string(body)},
+		{name: "not modified", method: http.MethodGet, status: http.StatusOK, ifNoneMatch: etagOf(body, encodingIdentity), isPersonalized: true, wantStatus: http.StatusNotModified, wantCacheControl: "private, no-cache"},
+		{name: "changed", method: http.MethodGet, status: http.StatusOK, ifNoneMatch: `"old"`, isPersonalized: true, wantStatus: http.StatusOK, wantCacheControl: "private, no-cache", wantBody: string(body)},
+		{name: "error status has no validators", method: http.MethodGet, status: http.StatusNotFound, ifNoneMatch: etagOf(body, encodingIdentity), wantStatus: http.StatusNotFound, wantBody: string(body)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(tt.method, "/api/v0/posts", nil)
+			if tt.ifNoneMatch != "" {
+				c.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			replyConditionally(c, tt.status, body, tt.isPersonalized)
+			c.Writer.WriteHeaderNow()
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirror-media/apigateway/billing"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/payment"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func newNewebpayStore(c config.NewebPayStore) payment.NewebPayStore {
+	return payment.NewebPayStore{
+		CallbackHost:        c.CallbackHost,
+		CallbackProtocol:    c.CallbackProtocol,
+		ClientBackPath:      c.ClientBackPath,
+		Endpoint:            c.Endpoint,
+		ID:                  c.ID,
+		HashKey:             c.HashKey,
+		HashIV:              c.HashIV,
+		IsAbleToModifyEmail: payment.Boolean(c.IsAbleToModifyEmail),
+		LoginType:           payment.NewebpayLoginType(c.LoginType),
+		NotifyProtocol:      c.NotifyProtocol,
+		NotifyHost:          c.NotifyHost,
+		NotifyPath:          c.NotifyPath,
+		Is3DSecure:          payment.Boolean(c.Is3DSecure),
+		RespondType:         payment.NewebpayRespondType(c.RespondType),
+		ReturnPath:          c.ReturnPath,
+		Version:             c.Version,
+	}
+}
+
+func newECPayStore(c config.ECPayStore) payment.ECPayStore {
+	return payment.ECPayStore{
+		ID:               c.ID,
+		HashKey:          c.HashKey,
+		HashIV:           c.HashIV,
+		Endpoint:         c.Endpoint,
+		CallbackProtocol: c.CallbackProtocol,
+		CallbackHost:     c.CallbackHost,
+		ClientBackPath:   c.ClientBackPath,
+		ReturnPath:       c.ReturnPath,
+		NotifyProtocol:   c.NotifyProtocol,
+		NotifyHost:       c.NotifyHost,
+		NotifyPath:       c.NotifyPath,
+	}
+}
+
+// NewPaymentProviders registers NewebPay as the default provider, and ECPay if it's configured
+func NewPaymentProviders(c config.Conf) *payment.Providers {
+	if c.ECPayStore.ID == "" {
+		return payment.NewProviders(newNewebpayStore(c.NewebPayStore))
+	}
+	return payment.NewProviders(newNewebpayStore(c.NewebPayStore), newECPayStore(c.ECPayStore))
+}
+
+// newPaymentCallbackHandler settles the trades POSTed by the provider. Providers notify again unless they're replied with 200 and CallbackReply, so only a settled or duplicate trade is replied so.
+func newPaymentCallbackHandler(provider payment.Provider, settler *billing.Settler, entitlements *entitlement.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logrus.WithFields(logrus.Fields{
+			"path":     c.FullPath(),
+			"provider": provider.Name(),
+		})
+
+		if err := c.Request.ParseForm(); err != nil {
+			logger.Warnf("invalid callback: %v", err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+		trade, err := provider.VerifyCallback(c.Request.PostForm)
+		if err != nil {
+			logger.Warnf("invalid callback: %v", err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+		logger = logger.WithFields(logrus.Fields{
+			"order": trade.OrderNumber,
+			"trade": trade.TradeNumber,
+		})
+
+		ctx := c.Request.Context()
+		settlement, err := settler.Settle(ctx, provider, trade)
+		if errors.Is(err, billing.ErrAmountMismatch) {
+			logger.Error(err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		} else if err != nil {
+			logger.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorReply{
+				Errors: []Error{{Message: err.Error()}},
+			})
+			return
+		}
+
+		if !settlement.IsDuplicate && settlement.FirebaseID != "" {
+			// the cache expires shortly anyway
+			if err = entitlements.Invalidate(ctx, settlement.FirebaseID); err != nil {
+				logger.Warn(err)
+			}
+		}
+		logger.WithFields(logrus.Fields{
+			"subscription": settlement.SubscriptionID,
+			"isDuplicate":  settlement.IsDuplicate,
+		}).Info("trade is settled")
+		c.String(http.StatusOK, provider.CallbackReply())
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -16,38 +18,103 @@ import (
 	"firebase.google.com/go/v4/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/httpclient"
-	"github.com/machinebox/graphql"
 	graphqlclient "github.com/machinebox/graphql"
-	"github.com/mirror-media/apigateway/cache"
-	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/institution"
 	"github.com/mirror-media/apigateway/middleware"
+	"github.com/mirror-media/apigateway/paywall"
 	"github.com/mirror-media/apigateway/token"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/tidwall/sjson"
 )
 
 // FIXME the file is way toooooooo long
 
+const (
+	// entitlementStateStale means the last known entitlement is used
+	entitlementStateStale = "stale"
+	// entitlementStateFailOpen means the reader is treated as a premium member by the degraded mode
+	entitlementStateFailOpen = "fail-open"
+
+	entitlementStateHeader = "GW-Entitlement"
+	institutionHeader      = "GW-Institution"
+)
+
 type premiumAccess struct {
-	isPrivileged bool
-	postIDs      map[string]interface{}
+	isPrivileged     bool
+	postIDs          map[string]interface{}
+	entitlementState string
+	// institutionID is the institution granting the premium access by the IP of the reader
+	institutionID string
+}
+
+// meterSession unlocks premium posts with the metering quota of the reader of a request. A nil meterSession unlocks nothing.
+type meterSession struct {
+	ctx        context.Context
+	logger     *logrus.Entry
+	meter      *paywall.Meter
+	readerType string
+	readerID   string
+	clientIP   string
+	remaining  *int
+}
+
+func (s *meterSession) unlock(postID string) bool {
+	if s == nil {
+		return false
+	}
+	isUnlocked, remaining, err := s.meter.Unlock(s.ctx, s.readerType, s.readerID, s.clientIP, postID)
+	if err != nil {
+		s.logger.Warnf("metering post(%s) for %s(%s) encountered error: %v", postID, s.readerType, s.readerID, err)
+	}
+	s.remaining = &remaining
+	return isUnlocked
+}
+
+// anonymousReaderID returns the id signed by the meter in the cookie or the header. Otherwise a new id is issued in both of the reply, and the reader is metered by the client IP until the id comes back.
+func anonymousReaderID(c *gin.Context, meter *paywall.Meter, anonymousIDHeader string) string {
+	signed := c.GetHeader(anonymousIDHeader)
+	if cookie, err := c.Cookie(paywall.AnonymousIDCookie); err == nil && cookie != "" {
+		signed = cookie
+	}
+	if id, ok := meter.VerifyAnonymousID(signed); ok {
+		return id
+	}
+	signed = meter.IssueAnonymousID()
+	c.SetCookie(paywall.AnonymousIDCookie, signed, int((400 * 24 * time.Hour).Seconds()), "/", "", true, true)
+	if anonymousIDHeader != "" {
+		c.Header(anonymousIDHeader, signed)
+	}
+	return ""
+}
+
+func (s *meterSession) remainingQuota() *int {
+	if s == nil {
+		return nil
+	}
+	return s.remaining
 }
 
-func NewSingleHostReverseProxy(target *url.URL, pathBaseToStrip string, rdb cache.Rediser, cacheTTL int, memberGraphqlEndpoint string, privilegedEmailDomains map[string]bool, firebaseClient *auth.Client) func(c *gin.Context) {
-	targetQuery := target.RawQuery
+func NewSingleHostReverseProxy(routes UpstreamRoutes, pathBaseToStrip string, proxyCache *ProxyCache, memberGraphqlEndpoint string, privilegedEmailDomains map[string]bool, firebaseClient *auth.Client, gate *paywall.Engine, meter *paywall.Meter, anonymousIDHeader string, shareLinker *paywall.ShareLinker, entitlements *entitlement.Cache, privileges *entitlement.Privileges, degradedMode config.DegradedMode, institutions *institution.Registry, trustedProxies institution.TrustedProxies) func(c *gin.Context) {
+	fetchEntitlement := newEntitlementFetcher(graphqlclient.NewClient(memberGraphqlEndpoint, graphqlclient.WithHTTPClient(httpclient.DefaultNetHttpClient)), privileges)
 	director := func(req *http.Request) {
 		if strings.HasSuffix(pathBaseToStrip, "/") {
 			pathBaseToStrip = pathBaseToStrip + "/"
 		}
 		trimmedPath := strings.TrimPrefix(req.URL.Path, pathBaseToStrip)
-		if trimmedPath == "/story" {
-			req.URL.Path = "/getposts"
-			req.URL.RawPath = "/getposts"
+		route, upstreamPath := routes.match(trimmedPath)
+		if upstreamPath != trimmedPath {
+			req.URL.Path = upstreamPath
+			req.URL.RawPath = ""
 		} else {
 			req.URL.Path = trimmedPath
 			req.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, pathBaseToStrip)
 		}
 
+		target := route.target
+		targetQuery := target.RawQuery
 		req.URL.Scheme = target.Scheme
 		req.URL.Host = target.Host
 		req.URL.Path, req.URL.RawPath = joinURLPath(target, req.URL)
@@ -58,6 +125,12 @@ func NewSingleHostReverseProxy(target *url.URL, pathBaseToStrip string, rdb cach
 			req.URL.RawQuery = targetQuery + "&" + req.URL.RawQuery
 		}
 
+		// validators are computed over the modified body by the gateway, so the upstream must always reply the full body
+		req.Header.Del("If-None-Match")
+		req.Header.Del("If-Modified-Since")
+		// the body is rewritten by the gateway, so only the encodings it can decode are accepted
+		req.Header.Set("Accept-Encoding", acceptedUpstreamEncodings)
+
 		if _, ok := req.Header["User-Agent"]; !ok {
 			// explicitly disable User-Agent so it's not set to default value
 			req.Header.Set("User-Agent", "")
@@ -71,6 +144,15 @@ func NewSingleHostReverseProxy(target *url.URL, pathBaseToStrip string, rdb cach
 		var err error
 		var tokenState string
 
+		var sharedPostID string
+		if shareToken := extractShareToken(c); shareToken != "" && shareLinker != nil {
+			if claims, err := shareLinker.Verify(shareToken); err != nil {
+				logger.Info(err)
+			} else {
+				sharedPostID = claims.PostID
+			}
+		}
+
 		tokenSaved, isTokenExist := c.Get(middleware.GCtxTokenKey)
 		var typedToken token.Token
 		if !isTokenExist {
@@ -88,19 +170,56 @@ func NewSingleHostReverseProxy(target *url.URL, pathBaseToStrip string, rdb cach
 		trimmedPath := strings.TrimPrefix(c.Request.URL.Path, pathBaseToStrip)
 		isOriginalPathStory := (trimmedPath == "/story")
 
-		premiumAccessChan := make(chan premiumAccess)
+		// posts are transformed by the paywall only if the route allows
+		route, upstreamPath := routes.match(trimmedPath)
+		isPaywalled := route.isPaywalled && isPostPath(upstreamPath)
+
+		var session *meterSession
+		if meter != nil && !isOriginalPathStory {
+			session = &meterSession{
+				ctx:        c.Request.Context(),
+				logger:     logger,
+				meter:      meter,
+				readerType: paywall.ReaderAnonymous,
+			}
+			if firebaseID := c.GetString(middleware.GCtxUserIDKey); tokenState == token.OK && firebaseID != "" {
+				session.readerType = paywall.ReaderMember
+				session.readerID = firebaseID
+			} else {
+				session.readerID = anonymousReaderID(c, meter, anonymousIDHeader)
+				if ip := trustedProxies.ClientIP(c.Request); ip != nil {
+					session.clientIP = ip.String()
+				}
+			}
+		}
+
+		grantingInstitution, isInstitutional := institutions.Match(c.Request)
+		if !isInstitutional && tokenState == token.OK {
+			grantingInstitution, isInstitutional = institutions.MatchSignInProvider(typedToken.GetSignInProvider())
+		}
+
+		// buffered so the goroutine doesn't leak if the request is aborted before receiving
+		premiumAccessChan := make(chan premiumAccess, 1)
 		// TODO refactor
 		go func(c *gin.Context) {
+			var err error
 			var hasPremiumPrivilege bool
 			var emailVerified bool
 			var email string
+			var entitlementState string
+			var institutionID string
 			subscribedPostIDs := map[string]interface{}{}
 
 			defer func(c *gin.Context) {
+				if sharedPostID != "" {
+					subscribedPostIDs[sharedPostID] = nil
+				}
 				c.Set(middleware.GCtxIsPremiumKey, hasPremiumPrivilege)
 				premiumAccessChan <- premiumAccess{
-					isPrivileged: hasPremiumPrivilege,
-					postIDs:      subscribedPostIDs,
+					isPrivileged:     hasPremiumPrivilege,
+					postIDs:          subscribedPostIDs,
+					entitlementState: entitlementState,
+					institutionID:    institutionID,
 				}
 			}(c)
 
@@ -113,67 +232,233 @@ func NewSingleHostReverseProxy(target *url.URL, pathBaseToStrip string, rdb cach
 				hasPremiumPrivilege = emailVerified && isDomainPrivileged
 			}
 
+			if !hasPremiumPrivilege && isInstitutional {
+				hasPremiumPrivilege = true
+				institutionID = grantingInstitution.ID
+			}
+
 			if tokenState == token.OK && !isOriginalPathStory {
 				skipMemberCheck := hasPremiumPrivilege
 
-				var hasMemberPremiumPrivilege bool
-				hasMemberPremiumPrivilege, subscribedPostIDs, err = getMemberSubscription(c, logger, memberGraphqlEndpoint, skipMemberCheck)
+				var hasMemberPremiumPrivilege, isStale bool
+				hasMemberPremiumPrivilege, subscribedPostIDs, isStale, err = getMemberSubscription(c, entitlements, fetchEntitlement, skipMemberCheck)
 
 				if err != nil {
 					logger.Error(err)
+					if degradedMode.FailOpen {
+						hasPremiumPrivilege = true
+						entitlementState = entitlementStateFailOpen
+					}
 					return
+				} else if isStale {
+					entitlementState = entitlementStateStale
 				}
 
 				hasPremiumPrivilege = hasPremiumPrivilege || hasMemberPremiumPrivilege
 			}
 		}(c)
 
-		var body []byte
-		redisKey := fmt.Sprintf("%s.%s.%s.%s", "apigateway", "proxy", "uri", c.Request.RequestURI)
-		if cmd := rdb.Get(context.TODO(), redisKey); cmd == nil {
-			// cache doesn't exist, do fetch reverse proxy
+		// the reply varies with the reader if any of them is involved
+		isPersonalized := isTokenExist || session != nil || sharedPostID != "" || isInstitutional
+
+		cacheRule := proxyCache.rule(c.Request.URL.Path)
+		isCacheable := cacheRule.isCacheable(c.Request)
+		redisKey := proxyCache.key(c.Request.URL, cacheRule)
+
+		if c.Request.Method != http.MethodGet || !isPaywalled {
+			modifyResponse := ModifyReverseProxyResponse(c, proxyCache, tokenState, gate, session, premiumAccessChan, isPersonalized, isPaywalled, institutions)
+			reverseProxy := httputil.ReverseProxy{
+				Director:       director,
+				Transport:      route.transport,
+				ModifyResponse: modifyResponse,
+				ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+					if errors.Is(err, errCircuitOpen) && isCacheable {
+						if entry, ok := proxyCache.loadLastKnown(r.Context(), redisKey); ok {
+							if err = replyStaleResponse(w, r, entry, modifyResponse); err == nil {
+								return
+							}
+						}
+					}
+					logger.Errorf("proxying uri(%s) encountered error: %v", c.Request.RequestURI, err)
+					w.WriteHeader(http.StatusBadGateway)
+				},
+			}
+			reverseProxy.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+
+		// Post requests are served from the cache and coalesced on misses
+		upstreamClient := &http.Client{
+			Transport: route.transport,
+			// Redirects are passed through to the client as the reverse proxy does
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		fetchUpstream := newUpstreamFetcher(c.Request, director, upstreamClient)
+		var entry cacheEntry
+		var isStale, isCached bool
+		if isCacheable {
+			entry, isStale, isCached = proxyCache.load(c.Request.Context(), redisKey, cacheRule)
+		}
+		body, status := entry.body, entry.status
+		switch {
+		case isCached:
+			if isStale {
+				proxyCache.revalidate(redisKey, cacheRule, fetchUpstream)
+				c.Header("GW-Stale", entry.storedAt.Format(time.RFC3339))
+			}
+			c.Header("GW-Cache", time.Now().Format(time.RFC3339))
+		case isCacheable:
 			logger.Infof("cache for uri(%s) cannot be fetched", c.Request.RequestURI)
-		} else if body, err = cmd.Bytes(); err != nil {
-			// cache can't be understood, do fetch reverse proxy
-			if cmd.Val() != "" {
-				logger.Warnf("cache for uri(%s) cannot be converted to bytes", c.Request.RequestURI)
+			body, status, err = proxyCache.fetch(redisKey, cacheRule, fetchUpstream)
+		default:
+			body, status, err = fetchUpstream(c.Request.Context())
+		}
+		var passThrough *passThroughResponse
+		if errors.As(err, &passThrough) {
+			passThrough.reply(c)
+			return
+		}
+		// the last known response is served while the upstream is considered down
+		if errors.Is(err, errCircuitOpen) && isCacheable {
+			if entry, ok := proxyCache.loadLastKnown(c.Request.Context(), redisKey); ok {
+				body, status, err = entry.body, entry.status, nil
+				c.Header("GW-Stale", entry.storedAt.Format(time.RFC3339))
 			}
-		} else {
-			switch path := c.Request.URL.Path; {
-			case strings.HasSuffix(path, "/getposts") || strings.HasSuffix(path, "/posts") || strings.HasSuffix(path, "/post"):
-				// block the workflow before the premium premiumAccess is computed
-				premiumAccess := <-premiumAccessChan
+		}
+		if err != nil {
+			logger.Errorf("fetching uri(%s) from upstream encountered error: %v", c.Request.RequestURI, err)
+			c.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
 
-				// break the switch to continue with response from proxied request
+		// block the workflow before the premium premiumAccess is computed
+		premiumAccess := <-premiumAccessChan
+		if premiumAccess.entitlementState != "" {
+			c.Header(entitlementStateHeader, premiumAccess.entitlementState)
+		}
+		if premiumAccess.institutionID != "" {
+			c.Header(institutionHeader, premiumAccess.institutionID)
+		}
 
-				var itemsLength int
-				if itemsLength, body, err = modifyPostItems(logger, body, premiumAccess.postIDs, premiumAccess.isPrivileged); err != nil {
-					logger.Warnf("modifyPostItems in cache encounter error: %s", err)
-					break
-				}
+		var itemsLength int
+		var isPremiumServed bool
+		if itemsLength, body, isPremiumServed, err = modifyPostItems(logger, body, gate, session, premiumAccess.postIDs, premiumAccess.isPrivileged); err != nil {
+			logger.Errorf("modifyPostItems encounter error: %s", err)
+			c.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
+		countInstitutionalUsage(c.Request.Context(), logger, institutions, premiumAccess.institutionID, isPremiumServed)
 
-				if body, err = removePostItemsHtml(body, itemsLength); err != nil {
-					logger.Warnf("encounter error when deleting html in cache:", err)
-					break
-				}
-				c.Header("GW-Cache", time.Now().Format(time.RFC3339))
-				c.AbortWithStatusJSON(http.StatusOK, Reply{
-					TokenState: tokenState,
-					Data:       json.RawMessage(body),
-				})
-				return
+		if body, err = removePostItemsHtml(body, itemsLength); err != nil {
+			logger.Errorf("encounter error when deleting html: %v", err)
+			c.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
+		b, err := json.Marshal(Reply{
+			TokenState:       tokenState,
+			RemainingQuota:   session.remainingQuota(),
+			EntitlementState: premiumAccess.entitlementState,
+			Institution:      premiumAccess.institutionID,
+			Data:             json.RawMessage(body),
+		})
+		if err != nil {
+			logger.Errorf("Marshalling reply encountered error: %v", err)
+			c.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
+		replyConditionally(c, status, b, isPersonalized)
+	}
+}
+
+// passThroughResponse is an upstream response other than a JSON reply of 200, e.g. a redirect. It's returned by the upstreamFetcher as an error so it isn't cached, and it's replied to the client with its headers as the reverse proxy does.
+type passThroughResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (r *passThroughResponse) Error() string {
+	return fmt.Sprintf("upstream replied %d of content type(%s)", r.status, r.header.Get("Content-Type"))
+}
+
+// reply writes the response to the client
+func (r *passThroughResponse) reply(c *gin.Context) {
+	for k, vv := range r.header {
+		for _, v := range vv {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	c.Data(r.status, r.header.Get("Content-Type"), r.body)
+	c.Abort()
+}
+
+// hopHeaders are removed from the responses passed through as the reverse proxy does
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// newUpstreamFetcher returns a upstreamFetcher sending a copy of the request to the upstream with the client IP appended to X-Forwarded-For. The response body is decoded by its Content-Encoding. Responses other than a JSON reply of 200 are returned as a *passThroughResponse error.
+func newUpstreamFetcher(r *http.Request, director func(*http.Request), client *http.Client) upstreamFetcher {
+	return func(ctx context.Context) ([]byte, int, error) {
+		req := r.Clone(ctx)
+		req.RequestURI = ""
+		req.Body = http.NoBody
+		req.ContentLength = 0
+		if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			if prior := req.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+				clientIP = strings.Join(prior, ", ") + ", " + clientIP
 			}
+			req.Header.Set("X-Forwarded-For", clientIP)
 		}
+		director(req)
 
-		reverseProxy := httputil.ReverseProxy{
-			Director:       director,
-			ModifyResponse: ModifyReverseProxyResponse(c, rdb, cacheTTL, tokenState, premiumAccessChan),
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, err
 		}
-		reverseProxy.ServeHTTP(c.Writer, c.Request)
+		if body, err = decodeContentEncoding(resp.Header, body); err != nil {
+			return nil, 0, err
+		}
+		if resp.StatusCode != http.StatusOK || !isJSONContentType(resp.Header.Get("Content-Type")) {
+			header := resp.Header.Clone()
+			for _, h := range hopHeaders {
+				header.Del(h)
+			}
+			// the body is decoded and its length is set by the reply
+			header.Del("Content-Encoding")
+			header.Del("Content-Length")
+			return nil, resp.StatusCode, &passThroughResponse{status: resp.StatusCode, header: header, body: body}
+		}
+		return body, resp.StatusCode, nil
 	}
 }
 
-func ModifyReverseProxyResponse(c *gin.Context, rdb cache.Rediser, cacheTTL int, tokenState string, premiumAccessChan chan premiumAccess) func(*http.Response) error {
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+func isPostPath(path string) bool {
+	return strings.HasSuffix(path, "/getposts") || strings.HasSuffix(path, "/posts") || strings.HasSuffix(path, "/post")
+}
+
+func ModifyReverseProxyResponse(c *gin.Context, proxyCache *ProxyCache, tokenState string, gate *paywall.Engine, session *meterSession, premiumAccessChan chan premiumAccess, isPersonalized bool, isPaywalled bool, institutions *institution.Registry) func(*http.Response) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"path": c.FullPath(),
 	})
@@ -181,41 +466,55 @@ func ModifyReverseProxyResponse(c *gin.Context, rdb cache.Rediser, cacheTTL int,
 		body, err := io.ReadAll(r.Body)
 		defer r.Body.Close()
 		if err != nil {
-			logger.Errorf("encounter error when reading proxy response:", err)
+			logger.Errorf("encounter error when reading proxy response: %v", err)
 			return err
 		}
+		if body, err = decodeContentEncoding(r.Header, body); err != nil {
+			logger.Errorf("encounter error when decoding proxy response: %v", err)
+			return err
+		}
+		r.Header.Del("Content-Encoding")
+
+		// Save the complete post as early as we can and run in in a goroutine. Stale responses served from the cache are not saved again.
+		if cacheRule := proxyCache.rule(c.Request.URL.Path); cacheRule.isCacheable(c.Request) && r.Header.Get("GW-Stale") == "" {
+			redisKey := proxyCache.key(c.Request.URL, cacheRule)
+			go func(body []byte, status int, redisKey string) {
+				if err := proxyCache.store(context.TODO(), redisKey, cacheRule, body, status); err != nil {
+					logger.Warnf("setting redis cache(%s) encountered error: %v", redisKey, err)
+				}
+			}(body, r.StatusCode, redisKey)
+		}
 
-		// Save the complete post as early as we can and run in in a goroutine
-		redisKey := fmt.Sprintf("%s.%s.%s.%s", "apigateway", "proxy", "uri", c.Request.RequestURI)
-		go func(rdb cache.Rediser, body []byte, redisKey string) {
-			if err = rdb.Set(context.TODO(), redisKey, body, time.Duration(cacheTTL)*time.Second).Err(); err != nil {
-				logger.Warnf("setting redis cache(%s) encountered error: %v", redisKey, err)
-			}
-		}(rdb, body, redisKey)
-
-		switch path := r.Request.URL.Path; {
-		// TODO refactor condition
-		case strings.HasSuffix(path, "/getposts") || strings.HasSuffix(path, "/posts") || strings.HasSuffix(path, "/post"):
-
+		var entitlementState, institutionID string
+		if isPaywalled {
 			premiumAccess := <-premiumAccessChan
+			if entitlementState = premiumAccess.entitlementState; entitlementState != "" {
+				r.Header.Set(entitlementStateHeader, entitlementState)
+			}
+			if institutionID = premiumAccess.institutionID; institutionID != "" {
+				r.Header.Set(institutionHeader, institutionID)
+			}
 
 			var itemsLength int
-			if itemsLength, body, err = modifyPostItems(logger, body, premiumAccess.postIDs, premiumAccess.isPrivileged); err != nil {
+			var isPremiumServed bool
+			if itemsLength, body, isPremiumServed, err = modifyPostItems(logger, body, gate, session, premiumAccess.postIDs, premiumAccess.isPrivileged); err != nil {
 				logger.Errorf("modifyPostItems encounter error: %s", err)
 				return err
 			}
+			countInstitutionalUsage(c.Request.Context(), logger, institutions, institutionID, isPremiumServed)
 
 			if body, err = removePostItemsHtml(body, itemsLength); err != nil {
-				logger.Errorf("encounter error when deleting html:", err)
+				logger.Errorf("encounter error when deleting html: %v", err)
 				return err
 			}
-
-		default:
 		}
 
 		b, err := json.Marshal(Reply{
-			TokenState: tokenState,
-			Data:       json.RawMessage(body),
+			TokenState:       tokenState,
+			RemainingQuota:   session.remainingQuota(),
+			EntitlementState: entitlementState,
+			Institution:      institutionID,
+			Data:             json.RawMessage(body),
 		})
 
 		if err != nil {
@@ -223,6 +522,18 @@ func ModifyReverseProxyResponse(c *gin.Context, rdb cache.Rediser, cacheTTL int,
 			return err
 		}
 
+		b, isNotModified, err := prepareReply(r.Header, c.Request, r.StatusCode, b, isPersonalized)
+		if err != nil {
+			logger.Errorf("encounter error when encoding reply: %v", err)
+			return err
+		} else if isNotModified {
+			r.StatusCode = http.StatusNotModified
+			r.Body = http.NoBody
+			r.ContentLength = 0
+			r.Header.Del("Content-Length")
+			return nil
+		}
+
 		r.Body = io.NopCloser(bytes.NewReader(b))
 		r.ContentLength = int64(len(b))
 		r.Header.Set("Content-Length", strconv.Itoa(len(b)))
@@ -230,124 +541,227 @@ func ModifyReverseProxyResponse(c *gin.Context, rdb cache.Rediser, cacheTTL int,
 	}
 }
 
-// getMemberSubscription will return hasMemberPremiumPrivilege as false and subscribedPostIDs as empty map if skipMemberCheck is true
-func getMemberSubscription(c *gin.Context, logger *logrus.Entry, memberGraphqlEndpoint string, skipMemberCheck bool) (hasMemberPremiumPrivilege bool, subscribedPostIDs map[string]interface{}, err error) {
+// replyStaleResponse replies the cached entry modified as an upstream response and marked by the GW-Stale header
+func replyStaleResponse(w http.ResponseWriter, r *http.Request, entry cacheEntry, modifyResponse func(*http.Response) error) error {
+	resp := &http.Response{
+		StatusCode: entry.status,
+		Header: http.Header{
+			"Content-Type": []string{"application/json; charset=utf-8"},
+			"GW-Stale":     []string{entry.storedAt.Format(time.RFC3339)},
+		},
+		Body:    io.NopCloser(bytes.NewReader(entry.body)),
+		Request: r,
+	}
+	if err := modifyResponse(resp); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, err := io.Copy(w, resp.Body)
+	return err
+}
+
+// extractShareToken returns the share token in the header or the query. The query parameter is removed so it affects neither the cache key nor the upstream request.
+func extractShareToken(c *gin.Context) string {
+	shareToken := c.GetHeader(paywall.ShareTokenHeader)
+
+	q := c.Request.URL.Query()
+	if _, ok := q[paywall.ShareTokenQueryKey]; ok {
+		if shareToken == "" {
+			shareToken = q.Get(paywall.ShareTokenQueryKey)
+		}
+		q.Del(paywall.ShareTokenQueryKey)
+		c.Request.URL.RawQuery = q.Encode()
+		c.Request.RequestURI = c.Request.URL.RequestURI()
+	}
+	return shareToken
+}
+
+// getMemberSubscription will return hasMemberPremiumPrivilege as false and subscribedPostIDs as empty map if skipMemberCheck is true. isStale is true if the member service is unavailable and the last known entitlement is used.
+func getMemberSubscription(c *gin.Context, entitlements *entitlement.Cache, fetch entitlement.Fetcher, skipMemberCheck bool) (hasMemberPremiumPrivilege bool, subscribedPostIDs map[string]interface{}, isStale bool, err error) {
 	// declare before we use it to make sure a instance is returned
 	subscribedPostIDs = make(map[string]interface{})
 
 	if skipMemberCheck {
-		return false, subscribedPostIDs, nil
+		return false, subscribedPostIDs, false, nil
 	}
 
 	firebaseID := c.GetString(middleware.GCtxUserIDKey)
 	if firebaseID == "" {
-		return false, subscribedPostIDs, nil
+		return false, subscribedPostIDs, false, nil
+	}
+
+	e, isStale, err := entitlements.Get(c.Request.Context(), firebaseID, fetch)
+	if err != nil {
+		return false, subscribedPostIDs, false, err
 	}
+	for _, postID := range e.SubscribedPostIDs {
+		subscribedPostIDs[postID] = nil
+	}
+	return e.HasPremiumPrivilege, subscribedPostIDs, isStale, nil
+}
+
+// newEntitlementFetcher queries the member, its group, its marketing membership and its active one time subscriptions from the member service
+func newEntitlementFetcher(client *graphqlclient.Client, privileges *entitlement.Privileges) entitlement.Fetcher {
 	gql := `
 query ($firebaseId: String!) {
   member(where:{firebaseId: $firebaseId}){
-		type
-		state
+    ` + privileges.MemberFields() + `
     subscription(where:{frequency: one_time, isActive: true}){
       postId
     }
   }
 }
 `
-	req := graphqlclient.NewRequest(gql)
-	req.Var("firebaseId", firebaseID)
-	// data := model.Member{}
-	resp := struct {
-		Member model.Member `json:"member"`
-	}{}
-
-	client := graphql.NewClient(memberGraphqlEndpoint, graphql.WithHTTPClient(httpclient.DefaultNetHttpClient))
-	err = client.Run(context.TODO(), req, &resp)
-	if err != nil {
-		err = fmt.Errorf("cannot fetch member and subscription state from member server:%v", err)
-		return false, subscribedPostIDs, err
-	}
-
-	member := resp.Member
-	if member.Subscription != nil {
-		for _, s := range member.Subscription {
-			subscribedPostIDs[*s.PostID] = nil
-		}
-	}
-	if member.State != nil && *member.State == model.MemberStateTypeActive && member.Type != nil {
-		nonPremiumType := map[model.MemberTypeType]interface{}{
-			model.MemberTypeTypeNone:             nil,
-			model.MemberTypeTypeSubscribeOneTime: nil,
+	return func(ctx context.Context, firebaseID string) (e entitlement.Entitlement, err error) {
+		req := graphqlclient.NewRequest(gql)
+		req.Var("firebaseId", firebaseID)
+		resp := struct {
+			Member entitlement.Member `json:"member"`
+		}{}
+
+		err = client.Run(ctx, req, &resp)
+		if err != nil {
+			err = fmt.Errorf("cannot fetch member and subscription state from member server:%v", err)
+			return e, err
 		}
 
-		if _, isNotPremium := nonPremiumType[*member.Type]; !isNotPremium {
-			hasMemberPremiumPrivilege = true
+		member := resp.Member
+		e.SubscribedPostIDs = make([]string, 0, len(member.Subscription))
+		for _, s := range member.Subscription {
+			if s.PostID != nil {
+				e.SubscribedPostIDs = append(e.SubscribedPostIDs, *s.PostID)
+			}
 		}
+		e.HasPremiumPrivilege, err = privileges.HasPremiumPrivilege(ctx, &member)
+		return e, err
 	}
-	return hasMemberPremiumPrivilege, subscribedPostIDs, err
 }
 
-func modifyPostItems(logger *logrus.Entry, body []byte, subscribedPostIDs map[string]interface{}, hasPremiumPrivilege bool) (postItemsLength int, modifiedBody []byte, err error) {
+// modifyPostItems truncates the premium posts the reader isn't entitled to. isPremiumServed is true if the body is a single premium post served in full.
+func modifyPostItems(logger *logrus.Entry, body []byte, gate *paywall.Engine, session *meterSession, subscribedPostIDs map[string]interface{}, hasPremiumPrivilege bool) (postItemsLength int, modifiedBody []byte, isPremiumServed bool, err error) {
 	type Category struct {
-		IsMemberOnly *bool `json:"isMemberOnly,omitempty"`
+		Name         string `json:"name"`
+		IsMemberOnly *bool  `json:"isMemberOnly,omitempty"`
+	}
+	type Tag struct {
+		Name string `json:"name"`
+	}
+	type Writer struct {
+		Name string `json:"name"`
 	}
 
 	type ItemContent struct {
 		APIData []interface{} `json:"apiData"`
 	}
 	type Item struct {
-		ID         string      `json:"_id"`
-		Content    ItemContent `json:"content"`
-		Categories []Category  `json:"categories"`
-		WordCount  int         `json:"word_count"`
+		ID            string      `json:"_id"`
+		Content       ItemContent `json:"content"`
+		Categories    []Category  `json:"categories"`
+		Tags          []Tag       `json:"tags"`
+		Writers       []Writer    `json:"writers"`
+		PublishedDate string      `json:"publishedDate"`
+		WordCount     int         `json:"word_count"`
 	}
 	type Resp struct {
-		Items []Item `json:"_items"`
+		Items []json.RawMessage `json:"_items"`
 	}
 
 	var items Resp
 	err = json.Unmarshal(body, &items)
 	if err != nil {
 		err = fmt.Errorf("unmarshal post body encountered error: %v", err)
-		return 0, nil, err
+		return 0, nil, false, err
 	}
 
 	// modify body at the end and truncate the post depending on the post and member state
-	for i, item := range items.Items {
-		var isPostPremium bool
+	for i, rawItem := range items.Items {
+		var item Item
+		if err = json.Unmarshal(rawItem, &item); err != nil {
+			err = fmt.Errorf("unmarshal _items.%d encountered error: %v", i, err)
+			return 0, nil, false, err
+		}
+
+		post := paywall.Post{
+			ID:        item.ID,
+			WordCount: item.WordCount,
+		}
 		for _, category := range item.Categories {
+			post.Categories = append(post.Categories, category.Name)
 			if category.IsMemberOnly != nil && *category.IsMemberOnly {
-				isPostPremium = true
-				break
+				post.IsMemberOnlyCategory = true
 			}
 		}
-
-		if isPostPremium && isPostToBeTruncate(isPostPremium, item.ID, hasPremiumPrivilege, subscribedPostIDs) {
-			APIDataLength := len(item.Content.APIData)
-			truncatedEnd := minInt(3, APIDataLength)
-			if item.WordCount >= 1000 {
-				truncatedEnd = minInt(5, APIDataLength)
+		for _, tag := range item.Tags {
+			post.Tags = append(post.Tags, tag.Name)
+		}
+		for _, writer := range item.Writers {
+			post.Writers = append(post.Writers, writer.Name)
+		}
+		if item.PublishedDate != "" {
+			if post.PublishedDate, err = time.Parse(time.RFC3339, item.PublishedDate); err != nil {
+				logger.Warnf("publishedDate(%s) of post(%s) cannot be parsed: %v", item.PublishedDate, item.ID, err)
+			}
+		}
+		if flags := gate.Flags(); len(flags) > 0 {
+			fields := make(map[string]json.RawMessage)
+			if err = json.Unmarshal(rawItem, &fields); err != nil {
+				err = fmt.Errorf("unmarshal fields of _items.%d encountered error: %v", i, err)
+				return 0, nil, false, err
+			}
+			post.Flags = make(map[string]bool, len(flags))
+			for _, f := range flags {
+				post.Flags[f] = string(fields[f]) == "true"
 			}
-			truncatedAPIData := item.Content.APIData[0:truncatedEnd]
+		}
+
+		decision := gate.Decide(post)
+
+		isTruncated := isPostToBeTruncate(decision.IsPremium, item.ID, hasPremiumPrivilege, subscribedPostIDs)
+		// Only a single post consumes the metering quota, lists of posts don't
+		if isTruncated && len(items.Items) == 1 && session.unlock(item.ID) {
+			isTruncated = false
+		}
+
+		isPremiumServed = decision.IsPremium && !isTruncated && len(items.Items) == 1
+
+		if isTruncated {
+			truncatedAPIData := decision.Truncate(item.Content.APIData)
 			body, err = sjson.SetBytes(body, fmt.Sprintf("_items.%d.content.apiData", i), truncatedAPIData)
 			if err != nil {
 				err = fmt.Errorf("encounter error when truncating apiData: %v", err)
-				return 0, nil, err
+				return 0, nil, false, err
 			}
 			body, err = sjson.SetBytes(body, fmt.Sprintf("_items.%d.isTruncated", i), true)
 			if err != nil {
 				err = fmt.Errorf("encounter error setting isTruncated to true for _items.%d: %v", i, err)
-				return 0, nil, err
+				return 0, nil, false, err
 			}
 		} else {
 			body, err = sjson.SetBytes(body, fmt.Sprintf("_items.%d.isTruncated", i), false)
 			if err != nil {
 				err = fmt.Errorf("encounter error setting isTruncated to false for _items.%d: %v", i, err)
-				return 0, nil, err
+				return 0, nil, false, err
 			}
 		}
 	}
-	return len(items.Items), body, err
+	return len(items.Items), body, isPremiumServed, err
+}
+
+// countInstitutionalUsage counts the premium post served to a reader granted by the institution. Lists of posts aren't counted.
+func countInstitutionalUsage(ctx context.Context, logger *logrus.Entry, institutions *institution.Registry, institutionID string, isPremiumServed bool) {
+	if institutionID == "" || !isPremiumServed {
+		return
+	}
+	if err := institutions.CountUsage(ctx, institutionID); err != nil {
+		logger.Warn(err)
+	}
 }
 
 func removePostItemsHtml(body []byte, itemsLength int) (modifiedBody []byte, err error) {
@@ -400,10 +814,3 @@ func singleJoiningSlash(a, b string) string {
 	}
 	return a + b
 }
-
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
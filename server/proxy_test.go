@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirror-media/apigateway/config"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/middleware"
+)
+
+func Test_getMemberSubscription(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fetcher := func(isDown bool) entitlement.Fetcher {
+		return func(ctx context.Context, firebaseID string) (entitlement.Entitlement, error) {
+			if isDown {
+				return entitlement.Entitlement{}, errors.New("member service is down")
+			}
+			return entitlement.Entitlement{HasPremiumPrivilege: true, SubscribedPostIDs: []string{"post"}}, nil
+		}
+	}
+
+	tests := []struct {
+		name        string
+		cache       config.EntitlementCache
+		isKnown     bool
+		isDown      bool
+		wantPremium bool
+		wantStale   bool
+		wantErr     bool
+	}{
+		{name: "fetched", cache: config.EntitlementCache{TTL: 60}, wantPremium: true},
+		{name: "last known entitlement", cache: config.EntitlementCache{TTL: 60}, isKnown: true, isDown: true, wantPremium: true, wantStale: true},
+		{name: "unknown member", cache: config.EntitlementCache{TTL: 60}, isDown: true, wantErr: true},
+		{name: "no cache to fall back to", isKnown: true, isDown: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entitlements := entitlement.NewCache(newMemoryRediser(), tt.cache)
+			if tt.isKnown {
+				_, _, _ = entitlements.Get(context.Background(), "a", fetcher(false))
+				_ = entitlements.Invalidate(context.Background(), "a")
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/v0/posts", nil)
+			c.Set(middleware.GCtxUserIDKey, "a")
+
+			gotPremium, _, gotStale, err := getMemberSubscription(c, entitlements, fetcher(tt.isDown), false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMemberSubscription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotPremium != tt.wantPremium || gotStale != tt.wantStale {
+				t.Errorf("getMemberSubscription() = (%v, %v), want (%v, %v)", gotPremium, gotStale, tt.wantPremium, tt.wantStale)
+			}
+		})
+	}
+}
+
+func Test_newUpstreamFetcher(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("GW-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/posts", http.StatusMovedPermanently)
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"_items":[]}`))
+		}
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	director := func(req *http.Request) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		wantPassThrough bool
+		wantStatus      int
+		wantLocation    string
+	}{
+		{name: "posts", path: "/posts", wantStatus: http.StatusOK},
+		{name: "redirect", path: "/moved", wantPassThrough: true, wantStatus: http.StatusMovedPermanently, wantLocation: "/posts"},
+		{name: "not json", path: "/html", wantPassThrough: true, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.RemoteAddr = "203.0.113.2:1234"
+			r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+			_, status, err := newUpstreamFetcher(r, director, client)(context.Background())
+			var passThrough *passThroughResponse
+			if isPassThrough := errors.As(err, &passThrough); isPassThrough != tt.wantPassThrough {
+				t.Fatalf("newUpstreamFetcher() error = %v, want pass through %v", err, tt.wantPassThrough)
+			} else if !isPassThrough && err != nil {
+				t.Fatalf("newUpstreamFetcher() error = %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("newUpstreamFetcher() status = %d, want %d", status, tt.wantStatus)
+			}
+			if passThrough == nil {
+				return
+			}
+			if got := passThrough.header.Get("Location"); got != tt.wantLocation {
+				t.Errorf("passed through Location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := passThrough.header.Get("GW-Forwarded-For"); got != "198.51.100.1, 203.0.113.2" {
+				t.Errorf("upstream X-Forwarded-For = %q, want %q", got, "198.51.100.1, 203.0.113.2")
+			}
+		})
+	}
+}
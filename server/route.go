@@ -8,19 +8,33 @@ import (
 	gqlgenhendler "github.com/99designs/gqlgen/graphql/handler"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/httpclient"
 	"github.com/machinebox/graphql"
+	"github.com/mirror-media/apigateway/billing"
+	"github.com/mirror-media/apigateway/entitlement"
+	"github.com/mirror-media/apigateway/gift"
 	"github.com/mirror-media/apigateway/graph/member/mutationgraph"
 	"github.com/mirror-media/apigateway/graph/member/mutationgraph/generated"
 	"github.com/mirror-media/apigateway/handler"
+	"github.com/mirror-media/apigateway/institution"
 	"github.com/mirror-media/apigateway/middleware"
 	"github.com/mirror-media/apigateway/payment"
+	"github.com/mirror-media/apigateway/paywall"
+	"github.com/mirror-media/apigateway/promotion"
 	"github.com/mirror-media/apigateway/token"
+	"github.com/mirror-media/apigateway/trial"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type Reply struct {
 	TokenState interface{} `json:"tokenState"`
-	Data       interface{} `json:"data,omitempty"`
+	// RemainingQuota is the number of premium posts the reader can still open for free in this month
+	RemainingQuota *int `json:"remainingQuota,omitempty"`
+	// EntitlementState is "stale" or "fail-open" if the entitlement of the reader cannot be fetched from the member service
+	EntitlementState string `json:"entitlementState,omitempty"`
+	// Institution is the ID of the institution granting the premium access by the IP of the reader
+	Institution string      `json:"institution,omitempty"`
+	Data        interface{} `json:"data,omitempty"`
 }
 
 type Error struct {
@@ -48,10 +62,15 @@ func SetHealthRoute(server *Server) error {
 // SetRoute sets the routing for the gin engine
 func SetRoute(server *Server) error {
 	apiRouter := server.Engine.Group("/api")
+	trustedProxies, err := institution.NewTrustedProxies(server.Conf.Institutions.TrustedProxies)
+	if err != nil {
+		return err
+	}
 
 	// v2 api
 	v2Router := apiRouter.Group("/v2")
-	v2tokenStateRouter := v2Router.Use(middleware.SetIDTokenOnly(server.firebaseClient))
+	// the limit is taken before authentication, so requests with invalid tokens are throttled by client IP as well
+	v2tokenStateRouter := v2Router.Use(middleware.SetIDTokenOnly(server.firebaseClient), middleware.RateLimit(server.Rdb, "v2", server.Conf.RateLimits.V2, trustedProxies))
 
 	v2TokenAuthenticatedWithFirebaseRouter := v2tokenStateRouter.Use(middleware.AuthenticateIDToken(server.firebaseClient), middleware.FirebaseClientToContextMiddleware(server.firebaseClient), middleware.FirebaseDBClientToContextMiddleware(server.firebaseDatabaseClient))
 
@@ -65,7 +84,7 @@ func SetRoute(server *Server) error {
 
 	// v1 api
 	v1Router := apiRouter.Group("/v1")
-	v1tokenStateRouter := v1Router.Use(middleware.SetIDTokenOnly(server.firebaseClient))
+	v1tokenStateRouter := v1Router.Use(middleware.SetIDTokenOnly(server.firebaseClient), middleware.RateLimit(server.Rdb, "v1", server.Conf.RateLimits.V1, trustedProxies))
 	v1tokenStateRouter.GET("/tokenState", func(c *gin.Context) {
 		t := c.Value(middleware.GCtxTokenKey).(token.Token)
 		if t == nil {
@@ -79,15 +98,73 @@ func SetRoute(server *Server) error {
 		})
 	})
 
+	proxyCache, err := NewProxyCache(server.Rdb, server.Conf.RedisService.Cache)
+	if err != nil {
+		return err
+	}
+	entitlements := entitlement.NewCache(server.Rdb, server.Conf.EntitlementCache)
+	if entitlements == nil {
+		// there's no last known entitlement to fall back to without the cache
+		if server.Conf.DegradedMode.FailOpen {
+			logrus.Warn("EntitlementCache::TTL is not set, so every reader with a valid token is treated as a premium member while the member service is down")
+		} else {
+			logrus.Warn("EntitlementCache::TTL is not set, so every member is treated as a non-premium reader while the member service is down")
+		}
+	}
+	institutions, err := institution.NewRegistry(server.Rdb, server.Conf.Institutions)
+	if err != nil {
+		return err
+	}
+	trials, err := trial.NewTracker(server.Rdb)
+	if err != nil {
+		return err
+	}
+	privileges, err := entitlement.NewPrivileges(graphql.NewClient(server.Conf.ServiceEndpoints.UserGraphQL, graphql.WithHTTPClient(httpclient.DefaultNetHttpClient)), server.Conf.GroupSubscription, server.Conf.MemberSchema)
+	if err != nil {
+		return err
+	}
+
+	// admin api
+	adminRouter := apiRouter.Group("/admin")
+	adminAuthenticatedRouter := adminRouter.Use(middleware.AuthenticateAdminToken(server.Conf.Admin.Token))
+	adminAuthenticatedRouter.POST("/cache/purge", newPurgeHandler(proxyCache))
+	adminAuthenticatedRouter.POST("/entitlements/invalidate", newInvalidateEntitlementsHandler(entitlements))
+	adminAuthenticatedRouter.GET("/institutions/usage", newInstitutionUsageHandler(institutions))
+	adminAuthenticatedRouter.GET("/trials/stats", newTrialStatsHandler(trials))
+	adminMutationRelay, err := newAdminMutationRelay("http://localhost:8888/api/admin/graphql/member")
+	if err != nil {
+		return err
+	}
+	adminAuthenticatedRouter.POST("/graphql/member", adminMutationRelay)
+
 	// v0 api proxy every request to the restful serverce
 	v0Router := apiRouter.Group("/v0")
-	v0tokenStateRouter := v0Router.Use(middleware.SetIDTokenOnly(server.firebaseClient), middleware.SetUserID(server.firebaseClient))
+	v0tokenStateRouter := v0Router.Use(middleware.SetIDTokenOnly(server.firebaseClient), middleware.SetUserID(server.firebaseClient), middleware.RateLimit(server.Rdb, "v0", server.Conf.RateLimits.V0, trustedProxies))
 	proxyURL, err := url.Parse(server.Conf.V0RESTfulSvrTargetURL)
 	if err != nil {
 		return err
 	}
+	upstreamRoutes, err := NewUpstreamRoutes(server.Conf.UpstreamRoutes, proxyURL, server.Conf.UpstreamPolicy)
+	if err != nil {
+		return err
+	}
+
+	gate, err := paywall.NewEngine(server.Conf.ContentGating)
+	if err != nil {
+		return err
+	}
+
+	meter, err := paywall.NewMeter(server.Rdb, server.Conf.Metering)
+	if err != nil {
+		return err
+	}
 
-	v0tokenStateRouter.Any("/*wildcard", NewSingleHostReverseProxy(proxyURL, v0Router.BasePath(), server.Rdb, server.Conf.RedisService.Cache.TTL, server.Conf.ServiceEndpoints.UserGraphQL, server.Conf.PrivilegedEmailDomains, server.firebaseClient))
+	shareLinker, err := paywall.NewShareLinker(server.Rdb, server.Conf.ShareLink)
+	if err != nil {
+		return err
+	}
+
+	v0tokenStateRouter.Any("/*wildcard", NewSingleHostReverseProxy(upstreamRoutes, v0Router.BasePath(), proxyCache, server.Conf.ServiceEndpoints.UserGraphQL, server.Conf.PrivilegedEmailDomains, server.firebaseClient, gate, meter, server.Conf.Metering.AnonymousIDHeader, shareLinker, entitlements, privileges, server.Conf.DegradedMode, institutions, trustedProxies))
 
 	return nil
 }
@@ -101,34 +178,61 @@ func SetMemberMutationRoute(server *Server) error {
 
 	v2tokenStateRouter := v2Router.Use(middleware.SetIDTokenOnly(server.firebaseClient))
 
-	v2TokenAuthenticatedWithFirebaseRouter := v2tokenStateRouter.Use(middleware.AuthenticateIDToken(server.firebaseClient), middleware.AuthenticateMemberQueryAndFirebaseIDInArguments, middleware.FirebaseClientToContextMiddleware(server.firebaseClient), middleware.FirebaseDBClientToContextMiddleware(server.firebaseDatabaseClient))
+	// apigateway relays the mutations from localhost and has throttled invalid tokens by client IP already, so the mutations are counted by member after authentication, apart from the other v2 requests
+	v2TokenAuthenticatedWithFirebaseRouter := v2tokenStateRouter.Use(middleware.AuthenticateIDToken(server.firebaseClient), middleware.RateLimit(server.Rdb, "v2.mutation", server.Conf.RateLimits.V2, nil), middleware.AuthenticateMemberQueryAndFirebaseIDInArguments, middleware.FirebaseClientToContextMiddleware(server.firebaseClient), middleware.FirebaseDBClientToContextMiddleware(server.firebaseDatabaseClient))
 
 	c := server.Conf
 
+	shareLinker, err := paywall.NewShareLinker(server.Rdb, c.ShareLink)
+	if err != nil {
+		return err
+	}
+
+	client := graphql.NewClient(server.Services.UserGraphQL, graphql.WithHTTPClient(httpclient.DefaultNetHttpClient))
+	trials, err := trial.NewTracker(server.Rdb)
+	if err != nil {
+		return err
+	}
+	privileges, err := entitlement.NewPrivileges(client, c.GroupSubscription, c.MemberSchema)
+	if err != nil {
+		return err
+	}
+	entitlements := entitlement.NewCache(server.Rdb, c.EntitlementCache)
+	payments := NewPaymentProviders(*c)
+	promotions := promotion.NewRedeemer(server.Rdb, client, c.Promotion)
+	settler := billing.NewSettler(client, server.Rdb, promotions)
+
 	svr := gqlgenhendler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &mutationgraph.Resolver{
-		Conf:       *server.Conf,
-		UserSvrURL: server.Conf.ServiceEndpoints.UserGraphQL,
-		Client: func() *graphql.Client {
-			httpClient := httpclient.DefaultNetHttpClient
-			return graphql.NewClient(server.Services.UserGraphQL, graphql.WithHTTPClient(httpClient))
-		}(),
-		NewebpayStore: payment.NewebPayStore{
-			CallbackHost:        c.NewebPayStore.CallbackHost,
-			CallbackProtocol:    c.NewebPayStore.CallbackProtocol,
-			ClientBackPath:      c.NewebPayStore.ClientBackPath,
-			ID:                  c.NewebPayStore.ID,
-			IsAbleToModifyEmail: payment.Boolean(c.NewebPayStore.IsAbleToModifyEmail),
-			LoginType:           payment.NewebpayLoginType(c.NewebPayStore.LoginType),
-			NotifyProtocol:      c.NewebPayStore.NotifyProtocol,
-			NotifyHost:          c.NewebPayStore.NotifyHost,
-			NotifyPath:          c.NewebPayStore.NotifyPath,
-			Is3DSecure:          payment.Boolean(c.NewebPayStore.Is3DSecure),
-			RespondType:         payment.NewebpayRespondType(c.NewebPayStore.RespondType),
-			ReturnPath:          c.NewebPayStore.ReturnPath,
-			Version:             c.NewebPayStore.Version,
-		},
+		Conf:         *server.Conf,
+		UserSvrURL:   server.Conf.ServiceEndpoints.UserGraphQL,
+		Client:       client,
+		Payments:     payments,
+		Settler:      settler,
+		ShareLinker:  shareLinker,
+		Entitlements: entitlements,
+		Privileges:   privileges,
+		Promotions:   promotions,
+		Trials:       trials,
+		Gifts:        gift.NewClaims(server.Rdb),
 	}}))
 	v2TokenAuthenticatedWithFirebaseRouter.POST("/graphql/member", gin.WrapH(svr))
 
+	// administrators call the same schema with the admin token, e.g. to refund payments
+	adminAuthenticatedRouter := apiRouter.Group("/admin").Use(middleware.AuthenticateAdminToken(c.Admin.Token))
+	adminAuthenticatedRouter.POST("/graphql/member", gin.WrapH(svr))
+
+	// providers notify payments without a token, and the callbacks are verified by their signatures, i.e., TradeSha of NewebPay and CheckMacValue of ECPay
+	callbackPaths := map[string]string{
+		payment.NewebpayProviderName: c.NewebPayStore.NotifyPath,
+		payment.ECPayProviderName:    c.ECPayStore.NotifyPath,
+	}
+	for name, path := range callbackPaths {
+		provider, err := payments.Get(name)
+		if path == "" || err != nil {
+			continue
+		}
+		server.Engine.POST(path, newPaymentCallbackHandler(provider, settler, entitlements))
+	}
+
 	return nil
 }
@@ -9,11 +9,9 @@ import (
 	"firebase.google.com/go/v4/auth"
 	"firebase.google.com/go/v4/db"
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	"github.com/mirror-media/apigateway/cache"
 	"github.com/mirror-media/apigateway/config"
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
 	"google.golang.org/api/option"
 )
 
@@ -59,54 +57,9 @@ func NewServer(c config.Conf) (*Server, error) {
 		return nil, errors.Wrap(err, "fail to initialize the Firebase Database Client")
 	}
 
-	var rdb cache.Rediser
-
-	switch c.RedisService.Type {
-	case "cluster":
-		if len(c.RedisService.Addresses) == 0 {
-			return nil, errors.New("there's no redis address provided")
-		}
-		// TODO refactor
-		addrs := make([]string, 0, len(c.RedisService.Addresses))
-		for _, a := range c.RedisService.Addresses {
-			addrs = append(addrs, fmt.Sprintf("%s:%d", a.Addr, a.Port))
-		}
-		rdb = redis.NewClusterClient(&redis.ClusterOptions{
-			Addrs:    addrs,
-			Password: c.RedisService.Password,
-		})
-	case "single":
-		if len(c.RedisService.Addresses) == 0 {
-			return nil, errors.New("there's no redis address provided")
-		} else if len(c.RedisService.Addresses) > 1 {
-			logrus.Warnf("single type Redis accepts only the first address, but %d addresses are provided", len(c.RedisService.Addresses))
-		}
-
-		// TODO refactor
-		// Only the first address is used because it's a single instance
-		addrs := make([]string, 0, len(c.RedisService.Addresses))
-		for _, a := range c.RedisService.Addresses {
-			addrs = append(addrs, fmt.Sprintf("%s:%d", a.Addr, a.Port))
-		}
-		rdb = redis.NewClient(&redis.Options{
-			Addr:     addrs[0],
-			Password: c.RedisService.Password,
-		})
-	case "sentinel":
-		if len(c.RedisService.Addresses) == 0 {
-			return nil, errors.New("there's no redis address provided")
-		}
-		// TODO refactor
-		addrs := make([]string, 0, len(c.RedisService.Addresses))
-		for _, a := range c.RedisService.Addresses {
-			addrs = append(addrs, fmt.Sprintf("%s:%d", a.Addr, a.Port))
-		}
-		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
-			SentinelAddrs: addrs,
-			Password:      c.RedisService.Password,
-		})
-	default:
-		return nil, errors.New(fmt.Sprintf("unsupported redis type(%s)", c.RedisService.Type))
+	rdb, err := cache.NewRediser(c.RedisService)
+	if err != nil {
+		return nil, err
 	}
 
 	s := &Server{
@@ -115,7 +68,7 @@ func NewServer(c config.Conf) (*Server, error) {
 		FirebaseApp:            app,
 		firebaseClient:         firebaseClient,
 		firebaseDatabaseClient: dbClient,
-		Rdb:                    rdb,
+		Rdb:                    cache.NewLocalCache(rdb, c.RedisService.Local),
 		Services: &ServiceEndpoints{
 			UserGraphQL: c.ServiceEndpoints.UserGraphQL,
 		},
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultUpstreamDialTimeout     = 3000  // milliseconds
+	defaultUpstreamResponseTimeout = 10000 // milliseconds
+	defaultUpstreamRetryBackoff    = 100   // milliseconds
+	defaultBreakerThreshold        = 5
+	defaultBreakerCooldown         = 30 // seconds
+)
+
+// errCircuitOpen is returned without calling the upstream while its circuit is open
+var errCircuitOpen = errors.New("circuit of the upstream is open")
+
+// mergeUpstreamPolicy fills the zero fields of p by fallback and then the defaults. MaxRetries is filled only if it's nil.
+func mergeUpstreamPolicy(p, fallback config.UpstreamPolicy) config.UpstreamPolicy {
+	pick := func(v, fallback, defaultValue int) int {
+		if v != 0 {
+			return v
+		} else if fallback != 0 {
+			return fallback
+		}
+		return defaultValue
+	}
+	maxRetries := 0
+	if p.MaxRetries != nil {
+		maxRetries = *p.MaxRetries
+	} else if fallback.MaxRetries != nil {
+		maxRetries = *fallback.MaxRetries
+	}
+	return config.UpstreamPolicy{
+		DialTimeout:      pick(p.DialTimeout, fallback.DialTimeout, defaultUpstreamDialTimeout),
+		ResponseTimeout:  pick(p.ResponseTimeout, fallback.ResponseTimeout, defaultUpstreamResponseTimeout),
+		MaxRetries:       &maxRetries,
+		RetryBackoff:     pick(p.RetryBackoff, fallback.RetryBackoff, defaultUpstreamRetryBackoff),
+		BreakerThreshold: pick(p.BreakerThreshold, fallback.BreakerThreshold, defaultBreakerThreshold),
+		BreakerCooldown:  pick(p.BreakerCooldown, fallback.BreakerCooldown, defaultBreakerCooldown),
+	}
+}
+
+// resilientTransport calls an upstream with timeouts, retries idempotent requests and stops calling it while its circuit is open
+type resilientTransport struct {
+	base         http.RoundTripper
+	maxRetries   int
+	retryBackoff time.Duration
+	breaker      *circuitBreaker
+}
+
+// newResilientTransport creates a transport with the timeouts and retries of the merged policy p. The breaker may be shared with the other transports to the same upstream.
+func newResilientTransport(p config.UpstreamPolicy, breaker *circuitBreaker) *resilientTransport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{
+		Timeout:   time.Duration(p.DialTimeout) * time.Millisecond,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	base.ResponseHeaderTimeout = time.Duration(p.ResponseTimeout) * time.Millisecond
+
+	return &resilientTransport{
+		base:         base,
+		maxRetries:   *p.MaxRetries,
+		retryBackoff: time.Duration(p.RetryBackoff) * time.Millisecond,
+		breaker:      breaker,
+	}
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if !t.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+	// The outcome is recorded on every return, so a trial request cancelled during the backoff still ends the trial
+	defer func() {
+		t.breaker.record(!isUpstreamFailure(resp, err))
+	}()
+
+	attempts := 1
+	if isRetryable(req) && t.maxRetries > 0 {
+		attempts += t.maxRetries
+	}
+	for i := 0; ; i++ {
+		r := req
+		if i > 0 {
+			if err = sleep(req.Context(), t.retryBackoff*time.Duration(i)); err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			if req.GetBody != nil {
+				if r.Body, err = req.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		resp, err = t.base.RoundTrip(r)
+		if !isUpstreamFailure(resp, err) || i == attempts-1 || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+}
+
+// isRetryable tells if the request is idempotent and its body can be sent again
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// isUpstreamFailure tells if the upstream is unavailable rather than the request is bad
+func isUpstreamFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// circuitBreaker opens after threshold consecutive failures. After the cooldown, one trial request is let through, which closes the circuit if it succeeds or opens it again if it fails. A non-positive threshold disables the breaker.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu         sync.Mutex
+	failures   int
+	openUntil  time.Time
+	isTrialing bool
+}
+
+// newPolicyCircuitBreaker creates a breaker with the threshold and cooldown of the merged policy p
+func newPolicyCircuitBreaker(p config.UpstreamPolicy) *circuitBreaker {
+	return newCircuitBreaker(p.BreakerThreshold, time.Duration(p.BreakerCooldown)*time.Second)
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.isTrialing || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.isTrialing = true
+	return true
+}
+
+func (b *circuitBreaker) record(isSuccessful bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.isTrialing = false
+	if isSuccessful {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
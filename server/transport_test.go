@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+)
+
+func Test_circuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	steps := []struct {
+		name      string
+		do        func()
+		wantAllow bool
+	}{
+		{name: "closed", wantAllow: true},
+		{name: "one failure", do: func() { b.record(false) }, wantAllow: true},
+		{name: "opened by the threshold", do: func() { b.record(false) }, wantAllow: false},
+		{name: "trial after the cooldown", do: func() { now = now.Add(time.Minute) }, wantAllow: true},
+		{name: "only one trial", wantAllow: false},
+		{name: "reopened by the failed trial", do: func() { b.record(false) }, wantAllow: false},
+		{name: "another trial", do: func() { now = now.Add(time.Minute) }, wantAllow: true},
+		{name: "closed by the successful trial", do: func() { b.record(true) }, wantAllow: true},
+	}
+	for _, step := range steps {
+		if step.do != nil {
+			step.do()
+		}
+		if got := b.allow(); got != step.wantAllow {
+			t.Fatalf("%s: circuitBreaker.allow() = %v, want %v", step.name, got, step.wantAllow)
+		}
+	}
+}
+
+func Test_resilientTransport_RoundTrip(t *testing.T) {
+	var calls int32
+	var failures int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		failures   int32
+		wantStatus int
+		wantCalls  int32
+		wantErr    error
+	}{
+		{name: "retried until success", method: http.MethodGet, failures: 2, wantStatus: http.StatusOK, wantCalls: 3},
+		{name: "non idempotent request is not retried", method: http.MethodPost, failures: 1, wantStatus: http.StatusServiceUnavailable, wantCalls: 1},
+		{name: "retries are bounded", method: http.MethodGet, failures: 5, wantStatus: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "circuit is open", method: http.MethodGet, wantCalls: 0, wantErr: errCircuitOpen},
+	}
+	maxRetries := 2
+	policy := mergeUpstreamPolicy(config.UpstreamPolicy{MaxRetries: &maxRetries, RetryBackoff: 1, BreakerThreshold: 2}, config.UpstreamPolicy{})
+	transport := newResilientTransport(policy, newPolicyCircuitBreaker(policy))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			atomic.StoreInt32(&failures, tt.failures)
+			req, _ := http.NewRequest(tt.method, upstream.URL, nil)
+
+			resp, err := transport.RoundTrip(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resilientTransport.RoundTrip() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("resilientTransport.RoundTrip() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("upstream is called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_resilientTransport_RoundTrip_cancelledTrial(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	maxRetries := 1
+	policy := mergeUpstreamPolicy(config.UpstreamPolicy{MaxRetries: &maxRetries, RetryBackoff: 200}, config.UpstreamPolicy{})
+	transport := newResilientTransport(policy, newCircuitBreaker(1, 10*time.Millisecond))
+	roundTrip := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := roundTrip(context.Background()); err != nil {
+		t.Fatalf("resilientTransport.RoundTrip() error = %v", err)
+	}
+	if err := roundTrip(context.Background()); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("resilientTransport.RoundTrip() error = %v, want %v", err, errCircuitOpen)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := roundTrip(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("trial: resilientTransport.RoundTrip() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	atomic.StoreInt32(&calls, 0)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := roundTrip(ctx); errors.Is(err, errCircuitOpen) {
+		t.Fatal("the circuit stays open after the cancelled trial")
+	}
+	if calls != 1 {
+		t.Errorf("upstream is called %d times after the cancelled trial, want 1", calls)
+	}
+}
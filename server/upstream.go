@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mirror-media/apigateway/config"
+	"github.com/pkg/errors"
+)
+
+// UpstreamRoute proxies the requests matching its path prefix or regex to its upstream with the path rewritten
+type UpstreamRoute struct {
+	pathPrefix  string
+	pathRegex   *regexp.Regexp
+	rewrite     string
+	target      *url.URL
+	isPaywalled bool
+	transport   *resilientTransport
+}
+
+// UpstreamRoutes are matched in order. The last one is the fallback proxying everything else to the default upstream.
+type UpstreamRoutes []UpstreamRoute
+
+// storyUpstreamRoute is always appended after the configured routes, so "/story" is treated as a "/getposts" request unless a configured route matches it first
+var storyUpstreamRoute = config.UpstreamRoute{
+	PathPrefix: "/story",
+	Rewrite:    "/getposts",
+}
+
+// NewUpstreamRoutes creates the routes of the config followed by the built-in "/story" route. Routes without an upstream use the default target, and the zero fields of their policies fall back to the default policy. Routes to the same upstream host share a circuit breaker, of which the threshold and cooldown are decided by the first of these routes, while timeouts and retries are kept per route.
+func NewUpstreamRoutes(routes []config.UpstreamRoute, defaultTarget *url.URL, defaultPolicy config.UpstreamPolicy) (UpstreamRoutes, error) {
+	routes = append(routes[:len(routes):len(routes)], storyUpstreamRoute)
+
+	breakers := make(map[string]*circuitBreaker)
+	newTransport := func(target *url.URL, p config.UpstreamPolicy) *resilientTransport {
+		breaker, ok := breakers[target.Host]
+		if !ok {
+			breaker = newPolicyCircuitBreaker(p)
+			breakers[target.Host] = breaker
+		}
+		return newResilientTransport(p, breaker)
+	}
+
+	upstreamRoutes := make(UpstreamRoutes, 0, len(routes)+1)
+	for i, r := range routes {
+		route := UpstreamRoute{
+			pathPrefix:  r.PathPrefix,
+			rewrite:     r.Rewrite,
+			target:      defaultTarget,
+			isPaywalled: !r.SkipPaywall,
+		}
+		switch {
+		case r.PathPrefix != "" && r.PathRegex != "":
+			return nil, fmt.Errorf("upstream route(%d) has both PathPrefix and PathRegex", i)
+		case r.PathRegex != "":
+			re, err := regexp.Compile(r.PathRegex)
+			if err != nil {
+				return nil, errors.Wrapf(err, "upstream route(%d) has invalid PathRegex", i)
+			}
+			route.pathRegex = re
+		case r.PathPrefix == "":
+			return nil, fmt.Errorf("upstream route(%d) has neither PathPrefix nor PathRegex", i)
+		}
+		if r.Upstream != "" {
+			target, err := url.Parse(r.Upstream)
+			if err != nil {
+				return nil, errors.Wrapf(err, "upstream route(%d) has invalid Upstream", i)
+			}
+			route.target = target
+		}
+		route.transport = newTransport(route.target, mergeUpstreamPolicy(r.Policy, defaultPolicy))
+		upstreamRoutes = append(upstreamRoutes, route)
+	}
+
+	return append(upstreamRoutes, UpstreamRoute{
+		pathPrefix:  "/",
+		target:      defaultTarget,
+		isPaywalled: true,
+		transport:   newTransport(defaultTarget, mergeUpstreamPolicy(defaultPolicy, config.UpstreamPolicy{})),
+	}), nil
+}
+
+// match returns the first route matching the path and the path rewritten by it
+func (routes UpstreamRoutes) match(path string) (route UpstreamRoute, upstreamPath string) {
+	for _, r := range routes {
+		if upstreamPath, ok := r.rewritePath(path); ok {
+			return r, upstreamPath
+		}
+	}
+	// unreachable because the fallback matches every path
+	return routes[len(routes)-1], path
+}
+
+// rewritePath rewrites the path if it matches the route. The matched prefix is replaced by the rewrite, or the rewrite is expanded with the submatches of the regex, e.g., "$1" or "${name}".
+func (r UpstreamRoute) rewritePath(path string) (upstreamPath string, ok bool) {
+	if r.pathRegex != nil {
+		submatches := r.pathRegex.FindStringSubmatchIndex(path)
+		if submatches == nil {
+			return "", false
+		} else if r.rewrite == "" {
+			return path, true
+		}
+		return string(r.pathRegex.ExpandString(nil, r.rewrite, path, submatches)), true
+	}
+
+	// prefixes match whole path segments, so "/story" doesn't match "/storyline"
+	if path != r.pathPrefix && !strings.HasPrefix(path, strings.TrimSuffix(r.pathPrefix, "/")+"/") {
+		return "", false
+	} else if r.rewrite == "" {
+		return path, true
+	}
+	return r.rewrite + strings.TrimPrefix(path, r.pathPrefix), true
+}
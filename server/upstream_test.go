@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/mirror-media/apigateway/config"
+)
+
+func TestUpstreamRoutes_match(t *testing.T) {
+	defaultTarget, _ := url.Parse("http://restful")
+	routes, err := NewUpstreamRoutes([]config.UpstreamRoute{
+		{PathPrefix: "/video", Upstream: "http://video/api", Rewrite: "/v1", SkipPaywall: true},
+		{PathRegex: `^/podcasts/(?P<id>[a-z0-9]+)$`, Upstream: "http://podcast", Rewrite: "/episodes/${id}"},
+	}, defaultTarget, config.UpstreamPolicy{})
+	if err != nil {
+		t.Fatalf("NewUpstreamRoutes() error = %v", err)
+	}
+
+	tests := []struct {
+		path            string
+		wantUpstream    string
+		wantPath        string
+		wantIsPaywalled bool
+	}{
+		{path: "/story", wantUpstream: "http://restful", wantPath: "/getposts", wantIsPaywalled: true},
+		{path: "/story/abc", wantUpstream: "http://restful", wantPath: "/getposts/abc", wantIsPaywalled: true},
+		{path: "/storyline", wantUpstream: "http://restful", wantPath: "/storyline", wantIsPaywalled: true},
+		{path: "/video/posts", wantUpstream: "http://video/api", wantPath: "/v1/posts"},
+		{path: "/podcasts/abc", wantUpstream: "http://podcast", wantPath: "/episodes/abc", wantIsPaywalled: true},
+		{path: "/podcasts/abc/def", wantUpstream: "http://restful", wantPath: "/podcasts/abc/def", wantIsPaywalled: true},
+		{path: "/posts", wantUpstream: "http://restful", wantPath: "/posts", wantIsPaywalled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			route, path := routes.match(tt.path)
+			if route.target.String() != tt.wantUpstream || path != tt.wantPath || route.isPaywalled != tt.wantIsPaywalled {
+				t.Errorf("UpstreamRoutes.match() = (%s, %s, %v), want (%s, %s, %v)", route.target, path, route.isPaywalled, tt.wantUpstream, tt.wantPath, tt.wantIsPaywalled)
+			}
+		})
+	}
+}
+
+func TestNewUpstreamRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  []config.UpstreamRoute
+		wantErr bool
+	}{
+		{name: "default", routes: nil},
+		{name: "both prefix and regex", routes: []config.UpstreamRoute{{PathPrefix: "/a", PathRegex: "^/a"}}, wantErr: true},
+		{name: "neither prefix nor regex", routes: []config.UpstreamRoute{{Rewrite: "/a"}}, wantErr: true},
+		{name: "invalid regex", routes: []config.UpstreamRoute{{PathRegex: "("}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewUpstreamRoutes(tt.routes, &url.URL{}, config.UpstreamPolicy{}); (err != nil) != tt.wantErr {
+				t.Errorf("NewUpstreamRoutes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewUpstreamRoutes_policies(t *testing.T) {
+	defaultTarget, _ := url.Parse("http://restful")
+	zero, three := 0, 3
+	routes, err := NewUpstreamRoutes([]config.UpstreamRoute{
+		{PathPrefix: "/video", Upstream: "http://video/api", Policy: config.UpstreamPolicy{MaxRetries: &zero}},
+		{PathPrefix: "/videos", Upstream: "http://video/v2"},
+		{PathPrefix: "/posts"},
+	}, defaultTarget, config.UpstreamPolicy{MaxRetries: &three})
+	if err != nil {
+		t.Fatalf("NewUpstreamRoutes() error = %v", err)
+	}
+
+	tests := []struct {
+		path           string
+		wantMaxRetries int
+		sharesBreaker  string
+	}{
+		{path: "/video", wantMaxRetries: 0, sharesBreaker: "/videos"},
+		{path: "/videos", wantMaxRetries: 3, sharesBreaker: "/video"},
+		{path: "/posts", wantMaxRetries: 3, sharesBreaker: "/story"},
+		{path: "/story", wantMaxRetries: 3, sharesBreaker: "/others"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			route, _ := routes.match(tt.path)
+			other, _ := routes.match(tt.sharesBreaker)
+			if route.transport.maxRetries != tt.wantMaxRetries {
+				t.Errorf("maxRetries = %d, want %d", route.transport.maxRetries, tt.wantMaxRetries)
+			}
+			if route.transport.breaker != other.transport.breaker {
+				t.Errorf("breaker of %s is not shared with %s", tt.path, tt.sharesBreaker)
+			}
+		})
+	}
+	if video, _ := routes.match("/video"); video.transport.breaker == routes[len(routes)-1].transport.breaker {
+		t.Errorf("breaker of another upstream is shared")
+	}
+}
@@ -18,10 +18,11 @@ type FirebaseToken struct {
 
 type firebaseTokenState struct {
 	sync.Mutex
-	state         *string
-	email         string
-	emailVerified bool
-	subject       string
+	state          *string
+	email          string
+	emailVerified  bool
+	subject        string
+	signInProvider string
 }
 
 func (ftt *firebaseTokenState) setState(state string) {
@@ -50,6 +51,7 @@ func (ft *FirebaseToken) ExecuteTokenStateUpdate() error {
 			return
 		}
 		ft.tokenState.subject = t.Subject
+		ft.tokenState.signInProvider = t.Firebase.SignInProvider
 		email, ok := t.Claims["email"]
 		if !ok {
 			email = ""
@@ -102,6 +104,17 @@ func (ft *FirebaseToken) GetSubject() string {
 	return ft.tokenState.subject
 }
 
+// GetSignInProvider will automatically update state if cached state is nil
+func (ft *FirebaseToken) GetSignInProvider() string {
+	if ft.tokenState.state == nil {
+		ft.ExecuteTokenStateUpdate()
+	}
+
+	ft.tokenState.Lock()
+	defer ft.tokenState.Unlock()
+	return ft.tokenState.signInProvider
+}
+
 // NewFirebaseToken creates a token and excute the token state update procedure
 func NewFirebaseToken(authHeader string, client *auth.Client) (Token, error) {
 	if client == nil {
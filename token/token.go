@@ -13,4 +13,5 @@ type Token interface {
 	GetTokenState() string
 	GetSubject() string
 	GetEmail() (string, bool)
+	GetSignInProvider() string
 }
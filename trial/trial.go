@@ -0,0 +1,157 @@
+// Package trial tracks the free trials of recurring subscriptions
+package trial
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/graph/member/model"
+	"github.com/pkg/errors"
+)
+
+// State is the state of a trial subscription
+type State string
+
+const (
+	// StatePending means the card is not authorised yet
+	StatePending State = "pending"
+	// StateTrialing means the card is authorised and the trial hasn't ended
+	StateTrialing State = "trialing"
+	// StateConverted means the subscription is charged after the trial ends
+	StateConverted State = "converted"
+	// StateExpired means the trial ended without a charge, or the authorisation failed
+	StateExpired State = "expired"
+)
+
+// Days are the valid lengths of trials
+var Days = map[int]bool{7: true, 14: true, 30: true}
+
+// usageRetention is how long the monthly sets of trials are kept
+const usageRetention = 400 * 24 * time.Hour
+
+// started is the name of the monthly set of trials started
+const started = "started"
+
+// claimTTL is how long a claim guards the free trial of a member while its subscription is being created. The subscription with trialEndDatetime tells the trial is used afterwards.
+const claimTTL = 10 * time.Minute
+
+// StateOf returns the trial state of the subscription. isTrial is false if the subscription has no trial.
+func StateOf(s *model.Subscription, now time.Time) (state State, isTrial bool, err error) {
+	if s == nil || s.TrialEndDatetime == nil || *s.TrialEndDatetime == "" {
+		return "", false, nil
+	}
+	end, err := time.Parse(time.RFC3339, *s.TrialEndDatetime)
+	if err != nil {
+		return "", true, errors.Wrapf(err, "invalid trialEndDatetime of subscription(%s)", s.ID)
+	}
+
+	if s.Status == nil || *s.Status == model.SubscriptionStatusTypePaying || *s.Status == model.SubscriptionStatusTypeToPay {
+		return StatePending, true, nil
+	}
+	if now.Before(end) {
+		if *s.Status == model.SubscriptionStatusTypePaid {
+			return StateTrialing, true, nil
+		}
+		return StateExpired, true, nil
+	}
+	if s.PeriodLastSuccessDatetime != nil && *s.PeriodLastSuccessDatetime != "" {
+		charged, err := time.Parse(time.RFC3339, *s.PeriodLastSuccessDatetime)
+		if err != nil {
+			return "", true, errors.Wrapf(err, "invalid periodLastSuccessDatetime of subscription(%s)", s.ID)
+		}
+		if !charged.Before(end) {
+			return StateConverted, true, nil
+		}
+	}
+	return StateExpired, true, nil
+}
+
+// Tracker guards the free trials being started and counts trials by state every month
+type Tracker struct {
+	rdb      cache.Rediser
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewTracker keeps the records in rdb, bypassing its local cache
+func NewTracker(rdb cache.Rediser) (*Tracker, error) {
+	tz, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load the location for trials")
+	}
+	return &Tracker{
+		rdb:      cache.Unwrap(rdb),
+		location: tz,
+		now:      time.Now,
+	}, nil
+}
+
+func claimKey(firebaseID string) string {
+	return fmt.Sprintf("%s.%s.%s.%s", "apigateway", "trial", "claim", firebaseID)
+}
+
+func (t *Tracker) monthlyKey(state string, month time.Time) string {
+	return fmt.Sprintf("%s.%s.%s.%s", "apigateway", "trial", state, month.In(t.location).Format("200601"))
+}
+
+// Claim guards the free trial of the member until its subscription is created. isClaimed is false if the member is starting another trial. Whether the member has used the trial is told by the subscriptions of the member.
+func (t *Tracker) Claim(ctx context.Context, firebaseID string) (isClaimed bool, err error) {
+	isClaimed, err = t.rdb.SetNX(ctx, claimKey(firebaseID), firebaseID, claimTTL).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot claim the trial of member(%s)", firebaseID)
+	}
+	return isClaimed, nil
+}
+
+// Release gives back the claim of the free trial of the member, e.g., when the subscription fails to be created
+func (t *Tracker) Release(ctx context.Context, firebaseID string) error {
+	if err := t.rdb.Del(ctx, claimKey(firebaseID)).Err(); err != nil {
+		return errors.Wrapf(err, "cannot release the trial of member(%s)", firebaseID)
+	}
+	return nil
+}
+
+// Start counts the trial of the subscription in the current month
+func (t *Tracker) Start(ctx context.Context, subscriptionID string) error {
+	return t.add(ctx, t.monthlyKey(started, t.now()), subscriptionID)
+}
+
+// Record counts the converted or expired trial of the subscription in the month the trial ends. Recording the same subscription again doesn't count it twice. A nil Tracker records nothing.
+func (t *Tracker) Record(ctx context.Context, subscription *model.Subscription, state State) error {
+	if t == nil || (state != StateConverted && state != StateExpired) {
+		return nil
+	}
+	end, err := time.Parse(time.RFC3339, *subscription.TrialEndDatetime)
+	if err != nil {
+		return errors.Wrapf(err, "invalid trialEndDatetime of subscription(%s)", subscription.ID)
+	}
+	return t.add(ctx, t.monthlyKey(string(state), end), subscription.ID)
+}
+
+func (t *Tracker) add(ctx context.Context, key, subscriptionID string) error {
+	added, err := t.rdb.SAdd(ctx, key, subscriptionID).Result()
+	if err != nil {
+		return errors.Wrapf(err, "cannot add subscription(%s) to set(%s)", subscriptionID, key)
+	}
+	if added == 1 {
+		if err = t.rdb.Expire(ctx, key, usageRetention).Err(); err != nil {
+			return errors.Wrapf(err, "cannot set expiration of set(%s)", key)
+		}
+	}
+	return nil
+}
+
+// Stats returns the numbers of trials started in the month, and the numbers of trials converted or expired when they ended in the month
+func (t *Tracker) Stats(ctx context.Context, month time.Time) (map[string]int64, error) {
+	stats := make(map[string]int64, 3)
+	for _, state := range []string{started, string(StateConverted), string(StateExpired)} {
+		n, err := t.rdb.SCard(ctx, t.monthlyKey(state, month)).Result()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot count %s trials", state)
+		}
+		stats[state] = n
+	}
+	return stats, nil
+}
@@ -0,0 +1,184 @@
+package trial
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mirror-media/apigateway/cache"
+	"github.com/mirror-media/apigateway/graph/member/model"
+)
+
+// setRediser implements the set commands, SetNX, Del and Expire of cache.Rediser in memory. Expiration is ignored.
+type setRediser struct {
+	cache.Rediser
+	sets   map[string]map[string]bool
+	values map[string]interface{}
+}
+
+func (s *setRediser) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if s.sets[key] == nil {
+		s.sets[key] = map[string]bool{}
+	}
+	var added int64
+	for _, m := range members {
+		if !s.sets[key][m.(string)] {
+			s.sets[key][m.(string)] = true
+			added++
+		}
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+func (s *setRediser) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if _, ok := s.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	s.values[key] = value
+	return redis.NewBoolResult(true, nil)
+}
+
+func (s *setRediser) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := s.values[key]; ok {
+			delete(s.values, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (s *setRediser) SCard(ctx context.Context, key string) *redis.IntCmd {
+	return redis.NewIntResult(int64(len(s.sets[key])), nil)
+}
+
+func (s *setRediser) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func subscription(status model.SubscriptionStatusType, trialEnd string, lastSuccess string) *model.Subscription {
+	s := &model.Subscription{ID: "1", Status: &status}
+	if trialEnd != "" {
+		s.TrialEndDatetime = &trialEnd
+	}
+	if lastSuccess != "" {
+		s.PeriodLastSuccessDatetime = &lastSuccess
+	}
+	return s
+}
+
+func TestStateOf(t *testing.T) {
+	now := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		subscription *model.Subscription
+		want         State
+		wantIsTrial  bool
+		wantErr      bool
+	}{
+		{name: "no subscription"},
+		{name: "no trial", subscription: subscription(model.SubscriptionStatusTypePaid, "", "")},
+		{name: "not authorised", subscription: subscription(model.SubscriptionStatusTypePaying, "2021-12-14T00:00:00Z", ""), want: StatePending, wantIsTrial: true},
+		{name: "trialing", subscription: subscription(model.SubscriptionStatusTypePaid, "2021-12-14T00:00:00Z", ""), want: StateTrialing, wantIsTrial: true},
+		{name: "authorisation failed", subscription: subscription(model.SubscriptionStatusTypeFail, "2021-12-14T00:00:00Z", ""), want: StateExpired, wantIsTrial: true},
+		{name: "converted", subscription: subscription(model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00Z", "2021-11-30T00:05:00Z"), want: StateConverted, wantIsTrial: true},
+		{name: "ended without a charge", subscription: subscription(model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00Z", ""), want: StateExpired, wantIsTrial: true},
+		{name: "charged before the trial", subscription: subscription(model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00Z", "2021-11-16T00:00:00Z"), want: StateExpired, wantIsTrial: true},
+		{name: "invalid end", subscription: subscription(model.SubscriptionStatusTypePaid, "soon", ""), wantIsTrial: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotIsTrial, err := StateOf(tt.subscription, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StateOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || gotIsTrial != tt.wantIsTrial {
+				t.Errorf("StateOf() = (%v, %v), want (%v, %v)", got, gotIsTrial, tt.want, tt.wantIsTrial)
+			}
+		})
+	}
+}
+
+func TestTracker_Claim(t *testing.T) {
+	tracker, err := NewTracker(&setRediser{sets: map[string]map[string]bool{}, values: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		firebaseID string
+		do         func()
+		want       bool
+	}{
+		{name: "first trial", firebaseID: "a", want: true},
+		{name: "trial being started", firebaseID: "a"},
+		{name: "another member", firebaseID: "b", want: true},
+		{
+			name:       "released trial",
+			firebaseID: "b",
+			do: func() {
+				_ = tracker.Release(ctx, "b")
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.do != nil {
+				tt.do()
+			}
+			got, err := tracker.Claim(ctx, tt.firebaseID)
+			if err != nil {
+				t.Fatalf("Claim() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Claim() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracker_Stats(t *testing.T) {
+	tracker, err := NewTracker(&setRediser{sets: map[string]map[string]bool{}, values: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	// 2021-12-01 01:00 in Taipei
+	tracker.now = func() time.Time { return time.Date(2021, 11, 30, 17, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	_ = tracker.Start(ctx, "1")
+	_ = tracker.Start(ctx, "2")
+	// the trial ends in December in Taipei and is recorded twice
+	converted := subscription(model.SubscriptionStatusTypePaid, "2021-11-30T16:30:00Z", "2021-11-30T16:35:00Z")
+	_ = tracker.Record(ctx, converted, StateConverted)
+	_ = tracker.Record(ctx, converted, StateConverted)
+	_ = tracker.Record(ctx, subscription(model.SubscriptionStatusTypePaid, "2021-11-30T00:00:00Z", ""), StateExpired)
+	_ = tracker.Record(ctx, subscription(model.SubscriptionStatusTypePaid, "2021-12-14T00:00:00Z", ""), StateTrialing)
+
+	tests := []struct {
+		name  string
+		month time.Time
+		want  map[string]int64
+	}{
+		{name: "December", month: time.Date(2021, 12, 15, 0, 0, 0, 0, time.UTC), want: map[string]int64{"started": 2, "converted": 1, "expired": 0}},
+		{name: "November", month: time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC), want: map[string]int64{"started": 0, "converted": 0, "expired": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tracker.Stats(ctx, tt.month)
+			if err != nil {
+				t.Fatalf("Stats() error = %v", err)
+			}
+			for state, want := range tt.want {
+				if got[state] != want {
+					t.Errorf("Stats()[%s] = %v, want %v", state, got[state], want)
+				}
+			}
+		})
+	}
+}